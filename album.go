@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+// AlbumDownloadOptions overrides the settings for one album download. Unset
+// fields, including the *bool ones, take their value from the settings.
+type AlbumDownloadOptions struct {
+	Service              string `json:"service,omitempty"`
+	OutputDir            string `json:"output_dir,omitempty"`
+	AudioFormat          string `json:"audio_format,omitempty"`
+	FilenameFormat       string `json:"filename_format,omitempty"`
+	FolderTemplate       string `json:"folder_template,omitempty"`
+	TrackNumber          *bool  `json:"track_number,omitempty"`
+	EmbedLyrics          *bool  `json:"embed_lyrics,omitempty"`
+	EmbedMaxQualityCover *bool  `json:"embed_max_quality_cover,omitempty"`
+	AllowFallback        *bool  `json:"allow_fallback,omitempty"`
+	UseFirstArtistOnly   *bool  `json:"use_first_artist_only,omitempty"`
+	UseSingleGenre       *bool  `json:"use_single_genre,omitempty"`
+	EmbedGenre           *bool  `json:"embed_genre,omitempty"`
+	SkipCover            bool   `json:"skip_cover,omitempty"`
+}
+
+func (a *App) DownloadAlbum(url string, options AlbumDownloadOptions) (backend.AlbumJob, error) {
+	if url == "" {
+		return backend.AlbumJob{}, fmt.Errorf("album URL is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	data, err := backend.GetFilteredSpotifyData(ctx, url, false, 0)
+	cancel()
+	if err != nil {
+		return backend.AlbumJob{}, fmt.Errorf("failed to fetch album metadata: %w", err)
+	}
+
+	album, ok := data.(*backend.AlbumResponsePayload)
+	if !ok {
+		return backend.AlbumJob{}, fmt.Errorf("URL is not a Spotify album: %s", url)
+	}
+	if len(album.TrackList) == 0 {
+		return backend.AlbumJob{}, fmt.Errorf("album has no tracks")
+	}
+
+	applyAlbumDefaults(&options, a.settings())
+
+	tracks := numberAlbumTracks(album.TrackList)
+
+	albumArtist := album.AlbumInfo.Artists
+	if albumArtist == "" && len(tracks) > 0 {
+		albumArtist = tracks[0].AlbumArtist
+	}
+	if *options.UseFirstArtistOnly {
+		albumArtist = backend.GetFirstArtist(albumArtist)
+	}
+
+	albumDir := filepath.Join(options.OutputDir, renderFolderTemplate(options.FolderTemplate, albumArtist, album.AlbumInfo.Name, albumArtist, album.AlbumInfo.ReleaseDate, "", 0))
+
+	jobID := fmt.Sprintf("album-%d", time.Now().UnixNano())
+	job := backend.AlbumJob{
+		ID:        jobID,
+		AlbumName: album.AlbumInfo.Name,
+		Artists:   album.AlbumInfo.Artists,
+		OutputDir: albumDir,
+	}
+
+	requests := make([]DownloadRequest, len(tracks))
+	for i, t := range tracks {
+		artist := t.Artists
+		if *options.UseFirstArtistOnly {
+			artist = backend.GetFirstArtist(artist)
+		}
+
+		trackDir := filepath.Join(options.OutputDir, renderFolderTemplate(options.FolderTemplate, artist, t.AlbumName, albumArtist, t.ReleaseDate, "", t.DiscNumber))
+
+		itemID := fmt.Sprintf("%s-%d", t.SpotifyID, time.Now().UnixNano())
+		backend.AddToQueue(itemID, t.Name, artist, t.AlbumName, t.SpotifyID)
+
+		requests[i] = albumTrackRequest(t, options, artist, albumArtist, trackDir, itemID)
+
+		job.Tracks = append(job.Tracks, backend.AlbumTrackStatus{
+			ItemID:      itemID,
+			SpotifyID:   t.SpotifyID,
+			Name:        t.Name,
+			Artists:     artist,
+			DiscNumber:  t.DiscNumber,
+			TrackNumber: t.TrackNumber,
+			Status:      backend.StatusQueued,
+		})
+	}
+
+	backend.StartAlbumJob(job)
+	a.emitAlbumProgress(jobID)
+	fmt.Printf("[Album] %s - %s (%d tracks)\n", album.AlbumInfo.Name, album.AlbumInfo.Artists, len(tracks))
+
+	if !options.SkipCover {
+		coverWritten := make(map[string]bool)
+		for _, req := range requests {
+			if coverWritten[req.OutputDir] {
+				continue
+			}
+			coverWritten[req.OutputDir] = true
+			if coverPath, err := writeAlbumCover(req.CoverURL, req.OutputDir, *options.EmbedMaxQualityCover); err != nil {
+				fmt.Printf("⚠ Failed to save album cover: %v\n", err)
+			} else if coverPath != "" {
+				backend.SetAlbumJobCover(jobID, coverPath)
+			}
+		}
+	}
+
+	// Tracks go through the download scheduler like any other batch, so
+	// they share its concurrency limits and retry policy.
+	stopProgress := a.watchAlbumProgress(jobID)
+	err = a.downloadAndWait(requests, func(i int, resp DownloadResponse) {
+		switch {
+		case !resp.Success:
+			status := backend.StatusFailed
+			if item, ok := backend.GetDownloadItem(requests[i].ItemID); ok && (item.Status == backend.StatusCancelled || item.Status == backend.StatusVerificationFailed) {
+				status = item.Status
+			}
+			backend.UpdateAlbumTrack(jobID, i, status, resp.Error, "")
+		case resp.AlreadyExists:
+			backend.UpdateAlbumTrack(jobID, i, backend.StatusSkipped, "", resp.File)
+		default:
+			backend.UpdateAlbumTrack(jobID, i, backend.StatusCompleted, "", resp.File)
+		}
+		a.emitAlbumProgress(jobID)
+	})
+	stopProgress()
+	if err != nil {
+		for i, req := range requests {
+			backend.FailDownloadItem(req.ItemID, err.Error())
+			backend.UpdateAlbumTrack(jobID, i, backend.StatusFailed, err.Error(), "")
+		}
+	}
+
+	backend.FinishAlbumJob(jobID)
+	a.emitAlbumProgress(jobID)
+
+	result, _ := backend.GetAlbumJob(jobID)
+	fmt.Printf("\n[Album] Completed: %d, Skipped: %d, Failed: %d, Cancelled: %d\n", result.Completed, result.Skipped, result.Failed, result.Cancelled)
+	return result, nil
+}
+
+func albumTrackRequest(t backend.AlbumTrackMetadata, options AlbumDownloadOptions, artist, albumArtist, trackDir, itemID string) DownloadRequest {
+	return DownloadRequest{
+		Service:              options.Service,
+		TrackName:            t.Name,
+		ArtistName:           artist,
+		AlbumName:            t.AlbumName,
+		AlbumArtist:          albumArtist,
+		ReleaseDate:          t.ReleaseDate,
+		CoverURL:             t.Images,
+		OutputDir:            trackDir,
+		AudioFormat:          options.AudioFormat,
+		FilenameFormat:       options.FilenameFormat,
+		TrackNumber:          *options.TrackNumber,
+		Position:             t.TrackNumber,
+		UseAlbumTrackNumber:  true,
+		SpotifyID:            t.SpotifyID,
+		EmbedLyrics:          *options.EmbedLyrics,
+		EmbedMaxQualityCover: *options.EmbedMaxQualityCover,
+		Duration:             t.DurationMS / 1000,
+		ItemID:               itemID,
+		SpotifyTrackNumber:   t.TrackNumber,
+		SpotifyDiscNumber:    t.DiscNumber,
+		SpotifyTotalTracks:   t.TotalTracks,
+		SpotifyTotalDiscs:    t.TotalDiscs,
+		AllowFallback:        *options.AllowFallback,
+		UseFirstArtistOnly:   *options.UseFirstArtistOnly,
+		UseSingleGenre:       *options.UseSingleGenre,
+		EmbedGenre:           *options.EmbedGenre,
+	}
+}
+
+func (a *App) GetAlbumJobs() []backend.AlbumJob {
+	return backend.GetAlbumJobs()
+}
+
+func (a *App) ClearFinishedAlbumJobs() {
+	backend.ClearFinishedAlbumJobs()
+}
+
+func (a *App) emitAlbumProgress(jobID string) {
+	if a.ctx == nil {
+		return
+	}
+	if job, ok := backend.GetAlbumJob(jobID); ok {
+		a.emit("album-progress", job)
+	}
+}
+
+// watchAlbumProgress emits the job every second while its tracks download,
+// so the album percentage follows the bytes received.
+func (a *App) watchAlbumProgress(jobID string) func() {
+	if a.ctx == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				a.emitAlbumProgress(jobID)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func applyAlbumDefaults(options *AlbumDownloadOptions, settings backend.Settings) {
+	setDefault := func(option **bool, value bool) {
+		if *option == nil {
+			*option = &value
+		}
+	}
+	setDefault(&options.TrackNumber, settings.TrackNumber)
+	setDefault(&options.EmbedLyrics, settings.EmbedLyrics)
+	setDefault(&options.EmbedMaxQualityCover, settings.EmbedMaxQualityCover)
+	setDefault(&options.AllowFallback, settings.AllowFallback)
+	setDefault(&options.UseFirstArtistOnly, settings.UseFirstArtistOnly)
+	setDefault(&options.UseSingleGenre, settings.UseSingleGenre)
+	setDefault(&options.EmbedGenre, settings.EmbedGenre)
+
+	if options.Service == "" {
+		options.Service = settings.Downloader
+	}
+	if options.OutputDir == "" {
+		options.OutputDir = settings.DownloadPath
+	}
+	if options.FilenameFormat == "" {
+		options.FilenameFormat = settings.FilenameTemplate
+		if options.FilenameFormat == "" {
+			options.FilenameFormat = "{title} - {artist}"
+		}
+	}
+	if options.FolderTemplate == "" {
+		options.FolderTemplate = settings.FolderTemplate
+		if options.FolderTemplate == "" {
+			options.FolderTemplate = "{album_artist}/{album}"
+		}
+	}
+	if options.AudioFormat == "" {
+		switch options.Service {
+		case "tidal":
+			options.AudioFormat = serviceQuality("tidal", settings)
+		case "qobuz":
+			options.AudioFormat = serviceQuality("qobuz", settings)
+		}
+	}
+}
+
+// numberAlbumTracks fills in the disc and track numbers missing from a
+// Spotify track list. Spotify restarts track_number on every disc, so the
+// numbers it provides are kept; tracks without one get the lowest number not
+// yet used on their disc. Track totals are counted per disc.
+func numberAlbumTracks(list []backend.AlbumTrackMetadata) []backend.AlbumTrackMetadata {
+	tracks := make([]backend.AlbumTrackMetadata, len(list))
+	copy(tracks, list)
+
+	perDisc := make(map[int]int)
+	used := make(map[int]map[int]bool)
+	totalDiscs := 1
+	for i := range tracks {
+		if tracks[i].DiscNumber <= 0 {
+			tracks[i].DiscNumber = 1
+		}
+		disc := tracks[i].DiscNumber
+		perDisc[disc]++
+		if disc > totalDiscs {
+			totalDiscs = disc
+		}
+		if used[disc] == nil {
+			used[disc] = make(map[int]bool)
+		}
+		if tracks[i].TrackNumber > 0 {
+			used[disc][tracks[i].TrackNumber] = true
+		}
+	}
+
+	next := make(map[int]int)
+	for i := range tracks {
+		disc := tracks[i].DiscNumber
+		if tracks[i].TrackNumber <= 0 {
+			n := next[disc] + 1
+			for used[disc][n] {
+				n++
+			}
+			used[disc][n] = true
+			next[disc] = n
+			tracks[i].TrackNumber = n
+		}
+		// Spotify's total counts the whole album, not the disc.
+		if tracks[i].TotalTracks <= 0 || totalDiscs > 1 {
+			tracks[i].TotalTracks = perDisc[disc]
+		}
+		if tracks[i].TotalDiscs <= 0 {
+			tracks[i].TotalDiscs = totalDiscs
+		}
+	}
+	return tracks
+}
+
+func writeAlbumCover(coverURL, dir string, maxQuality bool) (string, error) {
+	if coverURL == "" {
+		return "", nil
+	}
+
+	coverPath := filepath.Join(backend.SanitizeFolderPath(dir), "cover.jpg")
+	if info, err := os.Stat(coverPath); err == nil && info.Size() > 0 {
+		return coverPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(coverPath), 0755); err != nil {
+		return "", err
+	}
+	if err := backend.NewCoverClient().DownloadCoverToPath(coverURL, coverPath, maxQuality); err != nil {
+		os.Remove(coverPath)
+		return "", err
+	}
+	return coverPath, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+func TestApplyAlbumDefaults(t *testing.T) {
+	settings := backend.DefaultSettings()
+	settings.AllowFallback = true
+	settings.EmbedGenre = true
+	settings.TrackNumber = false
+	no := false
+	yes := true
+
+	tests := []struct {
+		name    string
+		options AlbumDownloadOptions
+		want    DownloadRequest
+	}{
+		{
+			name:    "unset options follow the settings",
+			options: AlbumDownloadOptions{},
+			want:    DownloadRequest{AllowFallback: true, EmbedGenre: true, TrackNumber: false},
+		},
+		{
+			name:    "explicit values win",
+			options: AlbumDownloadOptions{AllowFallback: &no, EmbedGenre: &no, TrackNumber: &yes},
+			want:    DownloadRequest{AllowFallback: false, EmbedGenre: false, TrackNumber: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			applyAlbumDefaults(&options, settings)
+			if options.Service != settings.Downloader || options.OutputDir != settings.DownloadPath {
+				t.Errorf("service, output dir = %q, %q", options.Service, options.OutputDir)
+			}
+
+			req := albumTrackRequest(backend.AlbumTrackMetadata{}, options, "", "", "", "")
+			if req.AllowFallback != tt.want.AllowFallback || req.EmbedGenre != tt.want.EmbedGenre || req.TrackNumber != tt.want.TrackNumber {
+				t.Errorf("fallback, genre, track number = %v, %v, %v; want %v, %v, %v",
+					req.AllowFallback, req.EmbedGenre, req.TrackNumber,
+					tt.want.AllowFallback, tt.want.EmbedGenre, tt.want.TrackNumber)
+			}
+			if req.EmbedLyrics != settings.EmbedLyrics || req.UseSingleGenre != settings.UseSingleGenre {
+				t.Errorf("lyrics, single genre = %v, %v", req.EmbedLyrics, req.UseSingleGenre)
+			}
+		})
+	}
+}
+
+func TestNumberAlbumTracks(t *testing.T) {
+	type track struct{ disc, number, total, discs int }
+	tests := []struct {
+		name string
+		list []track
+		want []track
+	}{
+		{
+			name: "single disc keeps Spotify's numbers",
+			list: []track{{1, 1, 3, 1}, {1, 2, 3, 1}, {1, 3, 3, 1}},
+			want: []track{{1, 1, 3, 1}, {1, 2, 3, 1}, {1, 3, 3, 1}},
+		},
+		{
+			name: "numbers restart on each disc",
+			list: []track{{1, 1, 4, 2}, {1, 2, 4, 2}, {2, 1, 4, 2}, {2, 2, 4, 2}},
+			want: []track{{1, 1, 2, 2}, {1, 2, 2, 2}, {2, 1, 2, 2}, {2, 2, 2, 2}},
+		},
+		{
+			name: "partial multi-disc list keeps the real numbers",
+			list: []track{{2, 5, 20, 2}, {1, 7, 20, 2}, {2, 3, 20, 2}},
+			want: []track{{2, 5, 2, 2}, {1, 7, 1, 2}, {2, 3, 2, 2}},
+		},
+		{
+			name: "missing numbers take the lowest free number on the disc",
+			list: []track{{1, 2, 0, 0}, {1, 0, 0, 0}, {0, 0, 0, 0}, {2, 0, 0, 0}},
+			want: []track{{1, 2, 3, 2}, {1, 1, 3, 2}, {1, 3, 3, 2}, {2, 1, 1, 2}},
+		},
+		{
+			name: "missing totals on a single disc",
+			list: []track{{0, 0, 0, 0}, {0, 0, 0, 0}},
+			want: []track{{1, 1, 2, 1}, {1, 2, 2, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := make([]backend.AlbumTrackMetadata, len(tt.list))
+			for i, tr := range tt.list {
+				list[i] = backend.AlbumTrackMetadata{DiscNumber: tr.disc, TrackNumber: tr.number, TotalTracks: tr.total, TotalDiscs: tr.discs}
+			}
+			got := numberAlbumTracks(list)
+			for i, g := range got {
+				gt := track{g.DiscNumber, g.TrackNumber, g.TotalTracks, g.TotalDiscs}
+				if gt != tt.want[i] {
+					t.Errorf("track %d = %+v, want %+v", i, gt, tt.want[i])
+				}
+			}
+			if list[0].DiscNumber != tt.list[0].disc || list[0].TrackNumber != tt.list[0].number {
+				t.Error("the input list was modified")
+			}
+		})
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 
 	"path/filepath"
+	"slices"
 
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/afkarxyz/SpotiFLAC/backend"
@@ -18,7 +22,13 @@ import (
 )
 
 type App struct {
-	ctx context.Context
+	ctx         context.Context
+	gui         bool
+	scheduler   *backend.DownloadScheduler
+	stopWatcher context.CancelFunc
+
+	settingsLock  sync.RWMutex
+	settingsCache *backend.Settings
 }
 
 func NewApp() *App {
@@ -38,15 +48,44 @@ func (a *App) getFirstArtist(artistString string) string {
 	return artistString
 }
 
+// startup is the Wails startup hook. Headless runs call initBackend instead,
+// since their context carries no Wails runtime to emit events on.
 func (a *App) startup(ctx context.Context) {
+	a.initBackend(ctx)
+	a.gui = true
+
+	backend.SetQueueEmitter(func(name string, data interface{}) {
+		runtime.EventsEmit(ctx, name, data)
+	})
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	a.stopWatcher = cancel
+	go a.runArtistWatcher(watchCtx)
+}
+
+func (a *App) initBackend(ctx context.Context) {
 	a.ctx = ctx
 
 	if err := backend.InitHistoryDB("SpotiFLAC"); err != nil {
 		fmt.Printf("Failed to init history DB: %v\n", err)
 	}
+
+	a.scheduler = backend.NewDownloadScheduler(backend.DefaultMaxConcurrentDownloads, backend.DefaultServiceLimits(), a.runDownloadJob)
+
+	applyBandwidthLimit(a.settings())
+}
+
+// emit sends a frontend event, or does nothing when running headless.
+func (a *App) emit(name string, data ...interface{}) {
+	if a.gui {
+		runtime.EventsEmit(a.ctx, name, data...)
+	}
 }
 
 func (a *App) shutdown(ctx context.Context) {
+	if a.stopWatcher != nil {
+		a.stopWatcher()
+	}
 	backend.CloseHistoryDB()
 }
 
@@ -91,15 +130,18 @@ type DownloadRequest struct {
 	UseFirstArtistOnly   bool   `json:"use_first_artist_only,omitempty"`
 	UseSingleGenre       bool   `json:"use_single_genre,omitempty"`
 	EmbedGenre           bool   `json:"embed_genre,omitempty"`
+	ISRC                 string `json:"isrc,omitempty"`
 }
 
 type DownloadResponse struct {
-	Success       bool   `json:"success"`
-	Message       string `json:"message"`
-	File          string `json:"file,omitempty"`
-	Error         string `json:"error,omitempty"`
-	AlreadyExists bool   `json:"already_exists,omitempty"`
-	ItemID        string `json:"item_id,omitempty"`
+	Success         bool   `json:"success"`
+	Message         string `json:"message"`
+	File            string `json:"file,omitempty"`
+	Error           string `json:"error,omitempty"`
+	AlreadyExists   bool   `json:"already_exists,omitempty"`
+	ItemID          string `json:"item_id,omitempty"`
+	Service         string `json:"service,omitempty"`
+	SelectionReason string `json:"selection_reason,omitempty"`
 }
 
 func (a *App) GetStreamingURLs(spotifyTrackID string, region string) (string, error) {
@@ -137,25 +179,21 @@ func (a *App) GetSpotifyMetadata(req SpotifyMetadataRequest) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(req.Timeout*float64(time.Second)))
 	defer cancel()
 
-	settings, err := a.LoadSettings()
+	settings := a.settings()
 
-	if err == nil && settings != nil {
-		if useAPI, ok := settings["useSpotFetchAPI"].(bool); ok && useAPI {
-			if apiURL, ok := settings["spotFetchAPIUrl"].(string); ok && apiURL != "" {
+	if settings.UseSpotFetchAPI && settings.SpotFetchAPIUrl != "" {
 
-				data, err := backend.GetSpotifyDataWithAPI(ctx, req.URL, true, apiURL, req.Batch, time.Duration(req.Delay*float64(time.Second)))
-				if err != nil {
-					return "", fmt.Errorf("failed to fetch metadata from API: %v", err)
-				}
-
-				jsonData, err := json.MarshalIndent(data, "", "  ")
-				if err != nil {
-					return "", fmt.Errorf("failed to encode response: %v", err)
-				}
+		data, err := backend.GetSpotifyDataWithAPI(ctx, req.URL, true, settings.SpotFetchAPIUrl, req.Batch, time.Duration(req.Delay*float64(time.Second)))
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch metadata from API: %v", err)
+		}
 
-				return string(jsonData), nil
-			}
+		jsonData, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode response: %v", err)
 		}
+
+		return string(jsonData), nil
 	}
 
 	data, err := backend.GetFilteredSpotifyData(ctx, req.URL, req.Batch, time.Duration(req.Delay*float64(time.Second)))
@@ -218,6 +256,7 @@ func (a *App) SearchSpotifyByType(req SpotifySearchByTypeRequest) ([]backend.Sea
 }
 
 func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
+	originalRequest, _ := json.Marshal(req)
 
 	if req.Service == "qobuz" && req.SpotifyID == "" {
 		return DownloadResponse{
@@ -254,6 +293,13 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 	}
 
 	itemID := req.ItemID
+	if isCancelledDownloadItem(itemID) {
+		return DownloadResponse{
+			Success: false,
+			Error:   "Download cancelled",
+			ItemID:  itemID,
+		}, fmt.Errorf("download cancelled")
+	}
 	if itemID == "" {
 
 		if req.SpotifyID != "" {
@@ -269,9 +315,17 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 	backend.StartDownloadItem(itemID)
 	defer backend.SetDownloading(false)
 
-	spotifyURL := ""
-	if req.SpotifyID != "" {
-		spotifyURL = fmt.Sprintf("https://open.spotify.com/track/%s", req.SpotifyID)
+	downloadCtx, cancelDownload := context.WithCancel(context.Background())
+	backend.RegisterDownloadCancel(itemID, cancelDownload)
+	defer func() {
+		backend.UnregisterDownloadCancel(itemID)
+		cancelDownload()
+	}()
+
+	if item, ok := backend.GetDownloadItem(itemID); ok {
+		if err := backend.SavePersistedDownload(item, req.Service, originalRequest); err != nil {
+			fmt.Printf("Warning: Failed to persist download item: %v\n", err)
+		}
 	}
 
 	if req.SpotifyID != "" && (req.Copyright == "" || req.Publisher == "" || req.SpotifyTotalDiscs == 0 || req.ReleaseDate == "" || req.SpotifyTotalTracks == 0 || req.SpotifyTrackNumber == 0) {
@@ -318,22 +372,34 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}
 	}
 
-	if req.TrackName != "" && req.ArtistName != "" {
+	if req.ISRC == "" {
+		req.ISRC = backend.CachedISRC(req.SpotifyID)
+	}
+
+	existingPath := ""
+	if req.SpotifyID != "" || req.ISRC != "" {
+		if path, ok := a.libraryIndexFor(req.OutputDir).Find(req.ISRC, req.SpotifyID); ok && fileExists(path) {
+			existingPath = path
+		}
+	}
+	if existingPath == "" && req.TrackName != "" && req.ArtistName != "" {
 		expectedFilename := backend.BuildExpectedFilename(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.SpotifyDiscNumber, req.UseAlbumTrackNumber)
 		expectedPath := filepath.Join(req.OutputDir, expectedFilename)
 
-		if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 100*1024 {
-
-			backend.SkipDownloadItem(itemID, expectedPath)
-			return DownloadResponse{
-				Success:       true,
-				Message:       "File already exists",
-				File:          expectedPath,
-				AlreadyExists: true,
-				ItemID:        itemID,
-			}, nil
+		if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 100*1024 && !backend.ReadTrackIdentity(expectedPath).Conflicts(req.ISRC, req.SpotifyID) {
+			existingPath = expectedPath
 		}
 	}
+	if existingPath != "" {
+		backend.SkipDownloadItem(itemID, existingPath)
+		return DownloadResponse{
+			Success:       true,
+			Message:       "File already exists",
+			File:          existingPath,
+			AlreadyExists: true,
+			ItemID:        itemID,
+		}, nil
+	}
 
 	lyricsChan := make(chan string, 1)
 	isrcChan := make(chan string, 1)
@@ -354,77 +420,203 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			close(lyricsChan)
 		}
 
-		go func() {
-			client := backend.NewSongLinkClient()
-			isrc, _ := client.GetISRC(req.SpotifyID)
-			isrcChan <- isrc
-		}()
+		if req.ISRC != "" {
+			isrcChan <- req.ISRC
+		} else {
+			go func() {
+				client := backend.NewSongLinkClient()
+				isrc, _ := client.GetISRC(req.SpotifyID)
+				isrcChan <- isrc
+			}()
+		}
 	} else {
 		close(lyricsChan)
-		close(isrcChan)
+		isrcChan <- req.ISRC
+	}
+
+	trackReq := backend.TrackRequest{
+		ItemID:               itemID,
+		SpotifyID:            req.SpotifyID,
+		ServiceURL:           req.ServiceURL,
+		APIURL:               req.ApiURL,
+		OutputDir:            req.OutputDir,
+		Quality:              req.AudioFormat,
+		FilenameFormat:       req.FilenameFormat,
+		PlaylistName:         req.PlaylistName,
+		PlaylistOwner:        req.PlaylistOwner,
+		IncludeTrackNumber:   req.TrackNumber,
+		Position:             req.Position,
+		UseAlbumTrackNumber:  req.UseAlbumTrackNumber,
+		TrackName:            req.TrackName,
+		ArtistName:           req.ArtistName,
+		AlbumName:            req.AlbumName,
+		AlbumArtist:          req.AlbumArtist,
+		ReleaseDate:          req.ReleaseDate,
+		CoverURL:             req.CoverURL,
+		TrackNumber:          req.SpotifyTrackNumber,
+		DiscNumber:           req.SpotifyDiscNumber,
+		TotalTracks:          req.SpotifyTotalTracks,
+		TotalDiscs:           req.SpotifyTotalDiscs,
+		Copyright:            req.Copyright,
+		Publisher:            req.Publisher,
+		EmbedMaxQualityCover: req.EmbedMaxQualityCover,
+		AllowFallback:        req.AllowFallback,
+		UseFirstArtistOnly:   req.UseFirstArtistOnly,
+		UseSingleGenre:       req.UseSingleGenre,
+		EmbedGenre:           req.EmbedGenre,
+	}
+
+	settings := a.settings()
+	trackReq.EmbedSourceTags = settings.EmbedSourceTags
+	services := serviceFallbackChain(req.Service, req.AllowFallback, settings)
+	if len(services) == 0 {
+		err := fmt.Errorf("unknown service: %s", req.Service)
+		backend.FailDownloadItem(itemID, err.Error())
+		return DownloadResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Unknown service: %s", req.Service),
+			ItemID:  itemID,
+		}, err
 	}
 
-	switch req.Service {
-	case "amazon":
+	var result *backend.TrackResult
+	var attemptErrors []string
+	var attemptErrs []error
+	isrcReceived := false
+	verifyDownloads := settings.VerifyDownloads
 
-		downloader := backend.NewAmazonDownloader()
-		if req.ServiceURL != "" {
-			filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
+	var selection *backend.ProviderSelection
+	if req.Service == "best" {
+		if !isrcReceived {
+			trackReq.ISRC = <-isrcChan
+			isrcReceived = true
+		}
+
+		fmt.Println("Comparing available quality across services...")
+		selection, err = backend.SelectBestProvider(downloadCtx, services, trackReq)
+		if err != nil {
+			fmt.Printf("⚠ Best quality lookup failed, using fallback order: %v\n", err)
 		} else {
-			filename, err = downloader.DownloadBySpotifyID(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
+			fmt.Printf("✓ Selected %s\n", selection.Reason)
+			services = preferService(services, selection.Service)
+			req.Service = selection.Service
+			trackReq.Quality = selection.Quality
+			trackReq.ServiceURL = selection.ServiceURL
+			trackReq.APIURL = ""
 		}
+	}
 
-	case "tidal":
-		if req.ApiURL == "" || req.ApiURL == "auto" {
-			downloader := backend.NewTidalDownloader("")
-			if req.ServiceURL != "" {
-				filename, err = downloader.DownloadByURLWithFallback(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
-			} else {
-				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
+	for i, service := range services {
+		if downloadCtx.Err() != nil {
+			break
+		}
+
+		provider, providerErr := backend.GetProvider(service)
+		if providerErr != nil {
+			continue
+		}
+
+		attemptReq := trackReq
+		if service != req.Service {
+			attemptReq.ServiceURL = ""
+			attemptReq.APIURL = ""
+			attemptReq.Quality = serviceQuality(service, settings)
+			if req.Service == "auto" {
+				attemptReq.Quality = autoServiceQuality(service, settings)
 			}
-		} else {
-			downloader := backend.NewTidalDownloader(req.ApiURL)
-			if req.ServiceURL != "" {
-				filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
-			} else {
-				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
+		}
+
+		if service == "qobuz" {
+			if !isrcReceived {
+				fmt.Println("Waiting for ISRC (Qobuz dependency)...")
+				trackReq.ISRC = <-isrcChan
+				isrcReceived = true
 			}
+			attemptReq.ISRC = trackReq.ISRC
 		}
 
-	case "qobuz":
+		if i > 0 {
+			fmt.Printf("⚠ Falling back to %s...\n", service)
+		}
 
-		fmt.Println("Waiting for ISRC (Qobuz dependency)...")
-		isrc := <-isrcChan
-		downloader := backend.NewQobuzDownloader()
-		quality := req.AudioFormat
-		if quality == "" {
-			quality = "6"
+		release := func() {}
+		if a.scheduler != nil {
+			release, err = a.scheduler.AcquireProvider(downloadCtx, service)
+			if err != nil {
+				break
+			}
 		}
-		filename, err = downloader.DownloadTrackWithISRC(isrc, req.SpotifyID, req.OutputDir, quality, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
 
-	case "deezer":
-		downloader := backend.NewDeezerDownloader()
-		filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.UseFirstArtistOnly, req.UseSingleGenre, req.EmbedGenre)
+		var verifyErr *backend.VerificationError
+		for try := 0; try < 2; try++ {
+			attempt := backend.DownloadAttempt{
+				Service:   service,
+				StartTime: time.Now().Unix(),
+			}
+			result, err = provider.Download(downloadCtx, attemptReq)
+			if err == nil && verifyDownloads {
+				err = verifyDownloadedFile(result, req.Duration)
+			}
+			attempt.EndTime = time.Now().Unix()
 
-	default:
-		return DownloadResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Unknown service: %s", req.Service),
-		}, fmt.Errorf("unknown service: %s", req.Service)
-	}
+			if err == nil {
+				backend.AddDownloadAttempt(itemID, attempt)
+				break
+			}
 
-	if err != nil {
-		backend.FailDownloadItem(itemID, fmt.Sprintf("Download failed: %v", err))
+			attempt.Error = err.Error()
+			backend.AddDownloadAttempt(itemID, attempt)
+			if try > 0 || !errors.As(err, &verifyErr) || downloadCtx.Err() != nil {
+				break
+			}
+			fmt.Printf("⚠ %s: %v, retrying once...\n", service, err)
+		}
+		release()
 
-		if filename != "" && !strings.HasPrefix(filename, "EXISTS:") {
+		if err == nil {
+			break
+		}
+
+		attemptErrors = append(attemptErrors, fmt.Sprintf("[%s] %v", service, err))
+		attemptErrs = append(attemptErrs, err)
+		fmt.Printf("✗ %s failed: %v\n", service, err)
+
+		if result != nil && result.Path != "" && !result.AlreadyExists {
 
-			if _, statErr := os.Stat(filename); statErr == nil {
-				fmt.Printf("Removing corrupted/partial file after failed download: %s\n", filename)
-				if removeErr := os.Remove(filename); removeErr != nil {
-					fmt.Printf("Warning: Failed to remove corrupted file %s: %v\n", filename, removeErr)
+			if _, statErr := os.Stat(result.Path); statErr == nil {
+				fmt.Printf("Removing corrupted/partial file after failed download: %s\n", result.Path)
+				if removeErr := os.Remove(result.Path); removeErr != nil {
+					fmt.Printf("Warning: Failed to remove corrupted file %s: %v\n", result.Path, removeErr)
 				}
 			}
 		}
+		result = nil
+	}
+
+	if result == nil && downloadCtx.Err() != nil {
+		backend.CancelDownloadItem(itemID)
+		fmt.Printf("✗ Download cancelled: %s - %s\n", req.TrackName, req.ArtistName)
+
+		return DownloadResponse{
+			Success: false,
+			Error:   "Download cancelled",
+			ItemID:  itemID,
+		}, fmt.Errorf("download cancelled")
+	}
+
+	if result == nil {
+		var verifyErr *backend.VerificationError
+		verificationFailed := err != nil && errors.As(err, &verifyErr)
+		if len(attemptErrors) == 0 {
+			err = fmt.Errorf("no available service for: %s", req.Service)
+		} else {
+			err = &downloadAttemptsError{msg: strings.Join(attemptErrors, "; "), errs: attemptErrs}
+		}
+		if verificationFailed {
+			backend.FailDownloadItemVerification(itemID, fmt.Sprintf("Download failed: %v", err))
+		} else {
+			backend.FailDownloadItem(itemID, fmt.Sprintf("Download failed: %v", err))
+		}
 
 		return DownloadResponse{
 			Success: false,
@@ -433,10 +625,21 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}, err
 	}
 
-	alreadyExists := false
-	if strings.HasPrefix(filename, "EXISTS:") {
-		alreadyExists = true
-		filename = strings.TrimPrefix(filename, "EXISTS:")
+	filename = result.Path
+	alreadyExists := result.AlreadyExists
+	selectionReason := ""
+	if selection != nil {
+		selectionReason = selection.Reason
+		if result.Service != selection.Service {
+			selectionReason = fmt.Sprintf("%s; %s failed, downloaded from %s", selection.Reason, selection.Service, result.Service)
+		}
+	}
+	format := req.AudioFormat
+	if result.Quality != "" {
+		format = result.Quality
+	}
+	if result.QualityFallback() {
+		fmt.Printf("⚠ %s delivered quality %s instead of %s\n", result.Service, result.Quality, result.RequestedQuality)
 	}
 
 	if !alreadyExists && req.SpotifyID != "" && req.EmbedLyrics && (strings.HasSuffix(filename, ".flac") || strings.HasSuffix(filename, ".mp3") || strings.HasSuffix(filename, ".m4a")) {
@@ -449,7 +652,7 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 
 			fmt.Printf("Embedding into: %s\n", filename)
 
-			if err := backend.EmbedLyricsOnlyUniversal(filename, lyrics); err != nil {
+			if err := backend.EmbedLyricsOnlyUniversal(downloadCtx, filename, lyrics); err != nil {
 				fmt.Printf("Failed to embed lyrics: %v\n", err)
 			} else {
 				fmt.Printf("Lyrics embedded successfully!\n")
@@ -478,8 +681,13 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 
 			backend.CompleteDownloadItem(itemID, filename, 0)
 		}
+		backend.AddToLibraryIndexes(filename)
 
-		go func(fPath, track, artist, album, sID, cover, format string) {
+		source := *result
+		if source.ISRC == "" {
+			source.ISRC = trackReq.ISRC
+		}
+		go func(fPath, track, artist, album, sID, cover, format, sourceReason string, source backend.TrackResult) {
 			quality := "Unknown"
 			durationStr := "--:--"
 
@@ -499,15 +707,30 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			}
 
 			item := backend.HistoryItem{
-				SpotifyID:   sID,
-				Title:       track,
-				Artists:     artist,
-				Album:       album,
-				DurationStr: durationStr,
-				CoverURL:    cover,
-				Quality:     quality,
-				Format:      strings.ToUpper(format),
-				Path:        fPath,
+				SpotifyID:    sID,
+				Title:        track,
+				Artists:      artist,
+				Album:        album,
+				DurationStr:  durationStr,
+				CoverURL:     cover,
+				Quality:      quality,
+				Format:       strings.ToUpper(format),
+				Path:         fPath,
+				Service:      source.Service,
+				SourceReason: sourceReason,
+				ISRC:         source.ISRC,
+
+				API:              source.API,
+				SourceID:         source.SourceID,
+				SourceQuality:    source.Quality,
+				RequestedQuality: source.RequestedQuality,
+			}
+
+			if item.ISRC == "" {
+				item.ISRC = backend.ReadTrackIdentity(fPath).ISRC
+			}
+			if info, err := os.Stat(fPath); err == nil {
+				item.Size = info.Size()
 			}
 
 			if item.Format == "" || item.Format == "LOSSLESS" {
@@ -523,18 +746,114 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			}
 
 			backend.AddHistoryItem(item, "SpotiFLAC")
-		}(filename, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID, req.CoverURL, req.AudioFormat)
+		}(filename, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID, req.CoverURL, format, selectionReason, source)
 	}
 
 	return DownloadResponse{
-		Success:       true,
-		Message:       message,
-		File:          filename,
-		AlreadyExists: alreadyExists,
-		ItemID:        itemID,
+		Success:         true,
+		Message:         message,
+		File:            filename,
+		AlreadyExists:   alreadyExists,
+		ItemID:          itemID,
+		Service:         result.Service,
+		SelectionReason: selectionReason,
 	}, nil
 }
 
+// downloadAttemptsError reports every failed service attempt in one message
+// while keeping the underlying errors available for retry classification.
+type downloadAttemptsError struct {
+	msg  string
+	errs []error
+}
+
+func (e *downloadAttemptsError) Error() string   { return e.msg }
+func (e *downloadAttemptsError) Unwrap() []error { return e.errs }
+
+// serviceFallbackChain lists the services to try for a request. auto and best
+// always use the full order; a specific service only falls through to the
+// others when fallback is allowed.
+func serviceFallbackChain(service string, allowFallback bool, settings backend.Settings) []string {
+	order := settings.FallbackOrder
+	if (service == "auto" || service == "best") && len(order) == 0 {
+		order = strings.Split(settings.AutoOrder, "-")
+	}
+
+	var chain []string
+	seen := make(map[string]bool)
+	if service != "auto" && service != "best" {
+		if _, err := backend.GetProvider(service); err != nil {
+			return nil
+		}
+		chain = append(chain, service)
+		seen[service] = true
+		if !allowFallback {
+			return chain
+		}
+	}
+	for _, name := range order {
+		if seen[name] {
+			continue
+		}
+		if _, err := backend.GetProvider(name); err != nil {
+			continue
+		}
+		chain = append(chain, name)
+		seen[name] = true
+	}
+	return chain
+}
+
+func preferService(services []string, preferred string) []string {
+	ordered := []string{preferred}
+	for _, service := range services {
+		if service != preferred {
+			ordered = append(ordered, service)
+		}
+	}
+	return ordered
+}
+
+func serviceQuality(service string, settings backend.Settings) string {
+	switch service {
+	case "tidal":
+		return settings.TidalQuality
+	case "qobuz":
+		return settings.QobuzQuality
+	}
+	return "LOSSLESS"
+}
+
+// libraryIndexFor returns the shared index of the download folder when
+// outputDir lies inside it, or of outputDir itself otherwise.
+func (a *App) libraryIndexFor(outputDir string) *backend.LibraryIndex {
+	if root := a.settings().DownloadPath; root != "" {
+		if rel, err := filepath.Rel(root, outputDir); err == nil && !strings.HasPrefix(rel, "..") {
+			return backend.CachedLibraryIndex(root)
+		}
+	}
+	return backend.CachedLibraryIndex(outputDir)
+}
+
+// autoServiceQuality maps the auto mode's 16/24-bit preference onto the
+// quality names of each service.
+func autoServiceQuality(service string, settings backend.Settings) string {
+	hiRes := settings.AutoQuality == "24"
+	switch service {
+	case "tidal":
+		if hiRes {
+			return "HI_RES_LOSSLESS"
+		}
+		return "LOSSLESS"
+	case "qobuz":
+		if hiRes {
+			return "27"
+		}
+		return "6"
+	}
+	return serviceQuality(service, settings)
+}
+
 func (a *App) OpenFolder(path string) error {
 	if path == "" {
 		return fmt.Errorf("path is required")
@@ -576,6 +895,7 @@ func (a *App) ClearCompletedDownloads() {
 
 func (a *App) ClearAllDownloads() {
 	backend.ClearAllDownloads()
+	releaseFinishedWaiters()
 }
 
 func (a *App) AddToDownloadQueue(spotifyID, trackName, artistName, albumName string) string {
@@ -584,12 +904,245 @@ func (a *App) AddToDownloadQueue(spotifyID, trackName, artistName, albumName str
 	return itemID
 }
 
+func (a *App) EnqueueDownloads(requests []DownloadRequest) ([]string, error) {
+	if a.scheduler == nil {
+		return nil, fmt.Errorf("download scheduler is not running")
+	}
+
+	a.scheduler.SetLimits(downloadConcurrencyLimits(a.settings()))
+
+	itemIDs := make([]string, 0, len(requests))
+	jobs := make([]backend.DownloadJob, 0, len(requests))
+	for _, req := range requests {
+		if req.Service == "" {
+			req.Service = "tidal"
+		}
+
+		queueDownloadItem(&req)
+
+		payload, err := json.Marshal(req)
+		if err != nil {
+			return itemIDs, fmt.Errorf("failed to encode download request: %w", err)
+		}
+
+		if item, ok := backend.GetDownloadItem(req.ItemID); ok {
+			if err := backend.SavePersistedDownload(item, req.Service, payload); err != nil {
+				fmt.Printf("Warning: Failed to persist download item: %v\n", err)
+			}
+		}
+
+		itemIDs = append(itemIDs, req.ItemID)
+		jobs = append(jobs, backend.DownloadJob{
+			ItemID:   req.ItemID,
+			Provider: req.Service,
+			Request:  payload,
+		})
+	}
+
+	a.scheduler.Enqueue(jobs...)
+	return itemIDs, nil
+}
+
+func (a *App) GetResumableDownloads() ([]backend.DownloadItem, error) {
+	entries, err := backend.GetPersistedDownloads()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]backend.DownloadItem, 0, len(entries))
+	for _, entry := range entries {
+		if isActiveDownloadItem(entry.Item.ID) {
+			continue
+		}
+		items = append(items, entry.Item)
+	}
+	return items, nil
+}
+
+func (a *App) ResumeDownloads(itemIDs []string) (int, error) {
+	if a.scheduler == nil {
+		return 0, fmt.Errorf("download scheduler is not running")
+	}
+
+	entries, err := backend.GetPersistedDownloads()
+	if err != nil {
+		return 0, err
+	}
+
+	selected := make(map[string]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		selected[id] = true
+	}
+
+	a.scheduler.SetLimits(downloadConcurrencyLimits(a.settings()))
+
+	var jobs []backend.DownloadJob
+	for _, entry := range entries {
+		if len(selected) > 0 && !selected[entry.Item.ID] {
+			continue
+		}
+		if isActiveDownloadItem(entry.Item.ID) || len(entry.Request) == 0 {
+			continue
+		}
+
+		backend.RestoreDownloadItem(entry.Item)
+		jobs = append(jobs, backend.DownloadJob{
+			ItemID:   entry.Item.ID,
+			Provider: entry.Provider,
+			Request:  entry.Request,
+		})
+	}
+
+	a.scheduler.Enqueue(jobs...)
+	return len(jobs), nil
+}
+
+func (a *App) DiscardResumableDownloads() error {
+	entries, err := backend.GetPersistedDownloads()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if isActiveDownloadItem(entry.Item.ID) {
+			continue
+		}
+		if err := backend.DeletePersistedDownload(entry.Item.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isActiveDownloadItem(itemID string) bool {
+	item, ok := backend.GetDownloadItem(itemID)
+	return ok && (item.Status == backend.StatusQueued || item.Status == backend.StatusDownloading || item.Status == backend.StatusRetrying)
+}
+
+func verifyDownloadedFile(result *backend.TrackResult, expectedDuration int) error {
+	if result == nil || result.AlreadyExists || !strings.HasSuffix(strings.ToLower(result.Path), ".flac") {
+		return nil
+	}
+
+	fmt.Println("Verifying FLAC integrity...")
+	verification, err := backend.VerifyFLAC(result.Path, expectedDuration)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		if removeErr := os.Remove(result.Path); removeErr != nil && !os.IsNotExist(removeErr) {
+			fmt.Printf("Warning: Failed to remove corrupted file %s: %v\n", result.Path, removeErr)
+		}
+		return err
+	}
+
+	if verification.MD5Checked {
+		fmt.Printf("✓ FLAC verified (MD5 OK, %.1fs)\n", verification.Duration)
+	} else {
+		fmt.Printf("✓ FLAC decoded cleanly (no MD5 in STREAMINFO, %.1fs)\n", verification.Duration)
+	}
+	return nil
+}
+
+func isCancelledDownloadItem(itemID string) bool {
+	if itemID == "" {
+		return false
+	}
+	item, ok := backend.GetDownloadItem(itemID)
+	return ok && item.Status == backend.StatusCancelled
+}
+
+func (a *App) runDownloadJob(job backend.DownloadJob) {
+	if item, ok := backend.GetDownloadItem(job.ItemID); ok && item.Status != backend.StatusQueued {
+		releaseFinishedWaiters()
+		return
+	}
+
+	var req DownloadRequest
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		msg := fmt.Sprintf("Invalid download request: %v", err)
+		backend.FailDownloadItem(job.ItemID, msg)
+		finishDownload(job.ItemID, DownloadResponse{Success: false, Error: msg})
+		return
+	}
+	req.ItemID = job.ItemID
+
+	resp, err := a.DownloadTrack(req)
+	if err != nil {
+		fmt.Printf("✗ %s - %s: %v\n", req.TrackName, req.ArtistName, err)
+		a.scheduleRetry(job, err)
+		if item, ok := backend.GetDownloadItem(job.ItemID); ok && item.Status == backend.StatusRetrying {
+			return
+		}
+	}
+	finishDownload(job.ItemID, resp)
+}
+
+func downloadConcurrencyLimits(settings backend.Settings) (int, map[string]int) {
+	limits := backend.DefaultServiceLimits()
+	for service, n := range settings.ServiceConcurrency {
+		limits[service] = n
+	}
+	return settings.MaxConcurrentDownloads, limits
+}
+
+func applyBandwidthLimit(settings backend.Settings) {
+	var limit int64
+	if settings.BandwidthLimitMBps > 0 {
+		limit = int64(settings.BandwidthLimitMBps * 1024 * 1024)
+	}
+	if limit != backend.GetBandwidthLimit() {
+		if limit > 0 {
+			fmt.Printf("Bandwidth limit set to %.2f MB/s\n", float64(limit)/(1024*1024))
+		} else {
+			fmt.Println("Bandwidth limit removed")
+		}
+		backend.SetBandwidthLimit(limit)
+	}
+}
+
 func (a *App) MarkDownloadItemFailed(itemID, errorMsg string) {
+	if isCancelledDownloadItem(itemID) {
+		return
+	}
+	if item, ok := backend.GetDownloadItem(itemID); ok && item.Status == backend.StatusVerificationFailed {
+		return
+	}
 	backend.FailDownloadItem(itemID, errorMsg)
 }
 
+func (a *App) CancelDownload(itemID string) error {
+	item, ok := backend.GetDownloadItem(itemID)
+	if !ok {
+		return fmt.Errorf("download item not found: %s", itemID)
+	}
+
+	switch item.Status {
+	case backend.StatusQueued:
+		if a.scheduler != nil {
+			a.scheduler.Remove(itemID)
+		}
+		backend.CancelDownloadItem(itemID)
+		releaseFinishedWaiters()
+		return nil
+	case backend.StatusRetrying:
+		backend.CancelDownloadItem(itemID)
+		releaseFinishedWaiters()
+		return nil
+	case backend.StatusDownloading:
+		if !backend.CancelActiveDownload(itemID) {
+			return fmt.Errorf("download is not cancellable: %s", itemID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("download is not active: %s", itemID)
+	}
+}
+
 func (a *App) CancelAllQueuedItems() {
+	if a.scheduler != nil {
+		a.scheduler.Clear()
+	}
 	backend.CancelAllQueuedItems()
+	releaseFinishedWaiters()
 }
 
 func (a *App) ExportFailedDownloads() (string, error) {
@@ -598,7 +1151,7 @@ func (a *App) ExportFailedDownloads() (string, error) {
 
 	hasFailed := false
 	for _, item := range queueInfo.Queue {
-		if item.Status == backend.StatusFailed {
+		if item.Status == backend.StatusFailed || item.Status == backend.StatusVerificationFailed {
 			hasFailed = true
 			break
 		}
@@ -614,7 +1167,7 @@ func (a *App) ExportFailedDownloads() (string, error) {
 
 	count := 0
 	for _, item := range queueInfo.Queue {
-		if item.Status == backend.StatusFailed {
+		if item.Status == backend.StatusFailed || item.Status == backend.StatusVerificationFailed {
 			count++
 			line := fmt.Sprintf("%d. %s - %s", count, item.TrackName, item.ArtistName)
 			if item.AlbumName != "" {
@@ -642,6 +1195,14 @@ func (a *App) ExportFailedDownloads() (string, error) {
 				DisplayName: "Text Files (*.txt)",
 				Pattern:     "*.txt",
 			},
+			{
+				DisplayName: "JSON Download List (*.json)",
+				Pattern:     "*.json",
+			},
+			{
+				DisplayName: "CSV Download List (*.csv)",
+				Pattern:     "*.csv",
+			},
 		},
 	})
 
@@ -653,7 +1214,15 @@ func (a *App) ExportFailedDownloads() (string, error) {
 		return "Export cancelled", nil
 	}
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = writeDownloadListJSON(path, a.failedDownloadEntries())
+	case ".csv":
+		err = writeDownloadListCSV(path, a.failedDownloadEntries())
+	default:
+		err = os.WriteFile(path, []byte(content), 0644)
+	}
+	if err != nil {
 		return "", fmt.Errorf("failed to write file: %v", err)
 	}
 
@@ -669,6 +1238,14 @@ func (a *App) GetDownloadHistory() ([]backend.HistoryItem, error) {
 	return backend.GetHistoryItems("SpotiFLAC")
 }
 
+func (a *App) QueryDownloadHistory(query backend.HistoryQuery) (backend.HistoryPage, error) {
+	return backend.QueryHistoryItems(query, "SpotiFLAC")
+}
+
+func (a *App) GetHistoryStats(rangeName string) (backend.HistoryStats, error) {
+	return backend.GetHistoryStats(rangeName, "SpotiFLAC")
+}
+
 func (a *App) ClearDownloadHistory() error {
 	return backend.ClearHistory("SpotiFLAC")
 }
@@ -996,19 +1573,19 @@ type DownloadFFmpegResponse struct {
 }
 
 func (a *App) DownloadFFmpeg() DownloadFFmpegResponse {
-	runtime.EventsEmit(a.ctx, "ffmpeg:status", "starting")
+	a.emit("ffmpeg:status", "starting")
 	err := backend.DownloadFFmpeg(func(progress int) {
-		runtime.EventsEmit(a.ctx, "ffmpeg:progress", progress)
+		a.emit("ffmpeg:progress", progress)
 	})
 	if err != nil {
-		runtime.EventsEmit(a.ctx, "ffmpeg:status", "failed")
+		a.emit("ffmpeg:status", "failed")
 		return DownloadFFmpegResponse{
 			Success: false,
 			Error:   err.Error(),
 		}
 	}
 
-	runtime.EventsEmit(a.ctx, "ffmpeg:status", "completed")
+	a.emit("ffmpeg:status", "completed")
 	return DownloadFFmpegResponse{
 		Success: true,
 		Message: "FFmpeg installed successfully",
@@ -1149,6 +1726,7 @@ type CheckFileExistenceRequest struct {
 	IncludeTrackNumber  bool   `json:"include_track_number,omitempty"`
 	AudioFormat         string `json:"audio_format,omitempty"`
 	RelativePath        string `json:"relative_path,omitempty"`
+	ISRC                string `json:"isrc,omitempty"`
 }
 
 type CheckFileExistenceResult struct {
@@ -1171,6 +1749,18 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, tracks []Che
 
 	defaultFilenameFormat := "title-artist"
 
+	// Tags identify files regardless of how they were named, so a renamed
+	// file or a changed filename template does not trigger a re-download.
+	// The index is cached, so paths from it are checked before use.
+	var index *backend.LibraryIndex
+	if rootDir == "" {
+		index = backend.CachedLibraryIndex(outputDir)
+	} else if rel, err := filepath.Rel(rootDir, outputDir); err == nil && !strings.HasPrefix(rel, "..") {
+		index = backend.CachedLibraryIndex(rootDir)
+	} else {
+		index = backend.BuildLibraryIndex(true, outputDir, rootDir)
+	}
+
 	type result struct {
 		index  int
 		result CheckFileExistenceResult
@@ -1178,30 +1768,6 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, tracks []Che
 
 	resultsChan := make(chan result, len(tracks))
 
-	var rootDirFiles map[string]string
-	rootDirFilesOnce := false
-	getRootDirFiles := func() map[string]string {
-		if rootDirFilesOnce {
-			return rootDirFiles
-		}
-		rootDirFiles = make(map[string]string)
-		if rootDir != "" && rootDir != outputDir {
-			filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					return nil
-				}
-				if !info.IsDir() {
-					if strings.EqualFold(filepath.Ext(path), ".flac") || strings.EqualFold(filepath.Ext(path), ".mp3") {
-						rootDirFiles[info.Name()] = path
-					}
-				}
-				return nil
-			})
-		}
-		rootDirFilesOnce = true
-		return rootDirFiles
-	}
-
 	for i, track := range tracks {
 		go func(idx int, t CheckFileExistenceRequest) {
 			res := CheckFileExistenceResult{
@@ -1211,6 +1777,17 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, tracks []Che
 				Exists:     false,
 			}
 
+			if t.ISRC == "" {
+				t.ISRC = backend.CachedISRC(t.SpotifyID)
+			}
+
+			if path, ok := index.Find(t.ISRC, t.SpotifyID); ok && fileExists(path) {
+				res.Exists = true
+				res.FilePath = path
+				resultsChan <- result{index: idx, result: res}
+				return
+			}
+
 			if t.TrackName == "" || t.ArtistName == "" {
 				resultsChan <- result{index: idx, result: res}
 				return
@@ -1255,12 +1832,16 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, tracks []Che
 
 			expectedPath := filepath.Join(targetDir, expectedFilename)
 
-			if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 100*1024 {
+			// A file with a matching name only counts when its tags do not
+			// say it is a different recording.
+			if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 100*1024 && !index.Identity(expectedPath).Conflicts(t.ISRC, t.SpotifyID) {
 				res.Exists = true
 				res.FilePath = expectedPath
-			} else {
-
-				res.FilePath = expectedFilename
+			} else if rootDir != "" && rootDir != outputDir {
+				if path, ok := index.FindByFilename(expectedFilename); ok && fileExists(path) && !index.Identity(path).Conflicts(t.ISRC, t.SpotifyID) {
+					res.Exists = true
+					res.FilePath = path
+				}
 			}
 
 			resultsChan <- result{index: idx, result: res}
@@ -1268,39 +1849,9 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, tracks []Che
 	}
 
 	results := make([]CheckFileExistenceResult, len(tracks))
-	missingIndices := []int{}
-
 	for i := 0; i < len(tracks); i++ {
 		r := <-resultsChan
 		results[r.index] = r.result
-		if !results[r.index].Exists {
-			missingIndices = append(missingIndices, r.index)
-		}
-	}
-
-	if len(missingIndices) > 0 && rootDir != "" {
-		filesMap := getRootDirFiles()
-		if len(filesMap) > 0 {
-			for _, idx := range missingIndices {
-
-				expectedFilename := results[idx].FilePath
-				baseName := filepath.Base(expectedFilename)
-				if path, ok := filesMap[baseName]; ok {
-					results[idx].Exists = true
-					results[idx].FilePath = path
-				} else {
-					results[idx].FilePath = ""
-				}
-			}
-		} else {
-			for _, idx := range missingIndices {
-				results[idx].FilePath = ""
-			}
-		}
-	} else {
-		for _, idx := range missingIndices {
-			results[idx].FilePath = ""
-		}
 	}
 
 	return results
@@ -1322,48 +1873,75 @@ func (a *App) GetConfigPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
-func (a *App) SaveSettings(settings map[string]interface{}) error {
+func (a *App) SaveSettings(raw map[string]interface{}) error {
 	configPath, err := a.GetConfigPath()
 	if err != nil {
 		return err
 	}
 
-	dir := filepath.Dir(configPath)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return err
-		}
+	settings, _, err := backend.ParseSettings(raw)
+	if err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	// The default music folder may not exist on a fresh install.
+	if settings.DownloadPath == backend.GetDefaultMusicPath() {
+		os.MkdirAll(settings.DownloadPath, 0755)
+	}
+	if err := settings.Validate(); err != nil {
+		return err
 	}
 
-	data, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
+	if err := backend.SaveSettingsFile(configPath, settings); err != nil {
 		return err
 	}
+	a.cacheSettings(settings)
 
-	return os.WriteFile(configPath, data, 0644)
+	applyBandwidthLimit(settings)
+	return nil
 }
 
-func (a *App) LoadSettings() (map[string]interface{}, error) {
+// LoadSettings returns nil when no config file has been written yet, so the
+// frontend can migrate its local copy.
+func (a *App) LoadSettings() (*backend.Settings, error) {
 	configPath, err := a.GetConfigPath()
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, nil
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
+	settings, exists, err := backend.LoadSettingsFile(configPath)
+	if err != nil || !exists {
 		return nil, err
 	}
+	a.cacheSettings(settings)
+	return &settings, nil
+}
 
-	var settings map[string]interface{}
-	if err := json.Unmarshal(data, &settings); err != nil {
-		return nil, err
+func (a *App) cacheSettings(settings backend.Settings) {
+	a.settingsLock.Lock()
+	defer a.settingsLock.Unlock()
+	a.settingsCache = &settings
+}
+
+// settings returns the saved settings, or the defaults when none are saved
+// or the file cannot be read. The file is only read until it has been
+// loaded once; SaveSettings keeps the cached copy current after that.
+func (a *App) settings() backend.Settings {
+	a.settingsLock.RLock()
+	cached := a.settingsCache
+	a.settingsLock.RUnlock()
+	if cached == nil {
+		settings, err := a.LoadSettings()
+		if err != nil || settings == nil {
+			return backend.DefaultSettings()
+		}
+		cached = settings
 	}
 
-	return settings, nil
+	settings := *cached
+	settings.FallbackOrder = slices.Clone(settings.FallbackOrder)
+	settings.RetryErrorClasses = slices.Clone(settings.RetryErrorClasses)
+	settings.ServiceConcurrency = maps.Clone(settings.ServiceConcurrency)
+	return settings
 }
 
 func (a *App) CheckFFmpegInstalled() (bool, error) {
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+func TestServiceFallbackChain(t *testing.T) {
+	settings := backend.Settings{
+		AutoOrder:     "tidal-qobuz-amazon-deezer",
+		FallbackOrder: []string{"qobuz", "tidal", "deezer"},
+	}
+
+	tests := []struct {
+		service       string
+		allowFallback bool
+		settings      backend.Settings
+		want          []string
+	}{
+		{"tidal", true, settings, []string{"tidal", "qobuz", "deezer"}},
+		{"tidal", false, settings, []string{"tidal"}},
+		{"amazon", false, settings, []string{"amazon"}},
+		{"auto", false, settings, []string{"qobuz", "tidal", "deezer"}},
+		{"best", true, settings, []string{"qobuz", "tidal", "deezer"}},
+		{"auto", false, backend.Settings{AutoOrder: "tidal-amazon"}, []string{"tidal", "amazon"}},
+		{"unknown", true, settings, nil},
+	}
+	for _, tt := range tests {
+		got := serviceFallbackChain(tt.service, tt.allowFallback, tt.settings)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("serviceFallbackChain(%q, %v) = %v, want %v", tt.service, tt.allowFallback, got, tt.want)
+		}
+	}
+}
+
+func TestSettingsCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	a := NewApp()
+	if got := a.settings(); got.DownloadPath != backend.DefaultSettings().DownloadPath {
+		t.Errorf("DownloadPath without a config = %q", got.DownloadPath)
+	}
+
+	configPath, err := a.GetConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := backend.DefaultSettings()
+	saved.DownloadPath = home
+	saved.FallbackOrder = []string{"qobuz", "tidal"}
+	if err := backend.SaveSettingsFile(configPath, saved); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.settings(); got.DownloadPath != home {
+		t.Fatalf("DownloadPath = %q, want %q", got.DownloadPath, home)
+	}
+
+	// Later changes to the file are not read again...
+	other := saved
+	other.DownloadPath = filepath.Join(home, "elsewhere")
+	if err := backend.SaveSettingsFile(configPath, other); err != nil {
+		t.Fatal(err)
+	}
+	got := a.settings()
+	if got.DownloadPath != home {
+		t.Errorf("DownloadPath after editing the file = %q, want the cached %q", got.DownloadPath, home)
+	}
+	got.FallbackOrder[0] = "deezer"
+	if a.settings().FallbackOrder[0] != "qobuz" {
+		t.Error("changing a returned copy changed the cache")
+	}
+
+	// ...but SaveSettings updates the cache.
+	saved.TidalQuality = "HI_RES_LOSSLESS"
+	data, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveSettings(raw); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.settings(); got.TidalQuality != "HI_RES_LOSSLESS" || got.DownloadPath != home {
+		t.Errorf("settings after SaveSettings = %q, %q", got.TidalQuality, got.DownloadPath)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+const artistWatchStartupDelay = 2 * time.Minute
+
+var artistWatchLock sync.Mutex
+
+type ArtistReleaseQueued struct {
+	ArtistID    string   `json:"artist_id"`
+	ArtistName  string   `json:"artist_name"`
+	ReleaseID   string   `json:"release_id"`
+	ReleaseName string   `json:"release_name"`
+	ReleaseType string   `json:"release_type"`
+	ReleaseDate string   `json:"release_date"`
+	ItemIDs     []string `json:"item_ids"`
+}
+
+func (a *App) WatchArtist(url string, filter backend.ArtistWatchFilter) (backend.WatchedArtist, error) {
+	artistID, err := backend.ParseSpotifyArtistID(url)
+	if err != nil {
+		return backend.WatchedArtist{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	name, releases, err := backend.FetchArtistReleases(ctx, artistID)
+	cancel()
+	if err != nil {
+		return backend.WatchedArtist{}, fmt.Errorf("failed to fetch artist discography: %w", err)
+	}
+
+	artistWatchLock.Lock()
+	defer artistWatchLock.Unlock()
+
+	artist, found, err := backend.GetWatchedArtist(artistID)
+	if err != nil {
+		return backend.WatchedArtist{}, err
+	}
+	if !found {
+		artist = backend.WatchedArtist{
+			ID:      artistID,
+			URL:     fmt.Sprintf("https://open.spotify.com/artist/%s", artistID),
+			AddedAt: time.Now().Unix(),
+		}
+	}
+	artist.Name = name
+	artist.Filter = filter
+	artist.LastChecked = time.Now().Unix()
+	artist.LastError = ""
+
+	// The existing catalogue is the baseline; only releases that show up
+	// after this point are downloaded.
+	for _, release := range releases {
+		artist.MarkKnown(release.ID)
+	}
+
+	if err := backend.SaveWatchedArtist(artist); err != nil {
+		return backend.WatchedArtist{}, err
+	}
+	fmt.Printf("[Watch] Watching %s (%d known releases)\n", name, len(artist.KnownReleases))
+	return artist, nil
+}
+
+func (a *App) UpdateArtistWatchFilter(artistID string, filter backend.ArtistWatchFilter) error {
+	artistWatchLock.Lock()
+	defer artistWatchLock.Unlock()
+
+	artist, found, err := backend.GetWatchedArtist(artistID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("artist is not being watched: %s", artistID)
+	}
+	artist.Filter = filter
+	return backend.SaveWatchedArtist(artist)
+}
+
+func (a *App) UnwatchArtist(artistID string) error {
+	artistWatchLock.Lock()
+	defer artistWatchLock.Unlock()
+
+	return backend.DeleteWatchedArtist(artistID)
+}
+
+func (a *App) GetWatchedArtists() ([]backend.WatchedArtist, error) {
+	return backend.GetWatchedArtists()
+}
+
+func (a *App) CheckWatchedArtists() ([]ArtistReleaseQueued, error) {
+	return a.checkWatchedArtists(context.Background())
+}
+
+func (a *App) checkWatchedArtists(ctx context.Context) ([]ArtistReleaseQueued, error) {
+	if a.scheduler == nil {
+		return nil, fmt.Errorf("download scheduler is not running")
+	}
+
+	artistWatchLock.Lock()
+	defer artistWatchLock.Unlock()
+
+	artists, err := backend.GetWatchedArtists()
+	if err != nil {
+		return nil, err
+	}
+
+	queued := []ArtistReleaseQueued{}
+	for _, artist := range artists {
+		if ctx.Err() != nil {
+			return queued, ctx.Err()
+		}
+
+		found, err := a.checkWatchedArtist(ctx, &artist)
+		queued = append(queued, found...)
+
+		artist.LastChecked = time.Now().Unix()
+		artist.LastError = ""
+		if err != nil {
+			fmt.Printf("⚠ [Watch] %s: %v\n", artist.Name, err)
+			artist.LastError = err.Error()
+		}
+		if err := backend.SaveWatchedArtist(artist); err != nil {
+			fmt.Printf("⚠ [Watch] Failed to save %s: %v\n", artist.Name, err)
+		}
+	}
+	return queued, nil
+}
+
+func (a *App) checkWatchedArtist(ctx context.Context, artist *backend.WatchedArtist) ([]ArtistReleaseQueued, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	name, releases, err := backend.FetchArtistReleases(fetchCtx, artist.ID)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		artist.Name = name
+	}
+
+	var queued []ArtistReleaseQueued
+	for _, release := range releases {
+		if artist.IsKnown(release.ID) {
+			continue
+		}
+		if !artist.Filter.Matches(release) {
+			fmt.Printf("[Watch] %s: skipping %s (%s, %s)\n", artist.Name, release.Name, release.AlbumType, release.ReleaseDate)
+			artist.MarkKnown(release.ID)
+			continue
+		}
+
+		fmt.Printf("[Watch] %s: new %s %s (%s)\n", artist.Name, release.AlbumType, release.Name, release.ReleaseDate)
+		itemIDs, err := a.enqueueRelease(ctx, release)
+		if err != nil {
+			// Left unknown so the next check retries it.
+			fmt.Printf("✗ [Watch] Failed to queue %s: %v\n", release.Name, err)
+			continue
+		}
+		artist.MarkKnown(release.ID)
+
+		entry := ArtistReleaseQueued{
+			ArtistID:    artist.ID,
+			ArtistName:  artist.Name,
+			ReleaseID:   release.ID,
+			ReleaseName: release.Name,
+			ReleaseType: release.AlbumType,
+			ReleaseDate: release.ReleaseDate,
+			ItemIDs:     itemIDs,
+		}
+		queued = append(queued, entry)
+		a.emit("artist-release-queued", entry)
+	}
+	return queued, nil
+}
+
+func (a *App) enqueueRelease(ctx context.Context, release backend.DiscographyAlbumMetadata) ([]string, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	data, err := backend.GetFilteredSpotifyData(fetchCtx, release.ExternalURL, false, 0)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	album, ok := data.(*backend.AlbumResponsePayload)
+	if !ok || len(album.TrackList) == 0 {
+		return nil, fmt.Errorf("no tracks found")
+	}
+
+	settings := a.settings()
+	options := AlbumDownloadOptions{}
+	applyAlbumDefaults(&options, settings)
+
+	albumArtist := album.AlbumInfo.Artists
+	if *options.UseFirstArtistOnly {
+		albumArtist = backend.GetFirstArtist(albumArtist)
+	}
+
+	tracks := numberAlbumTracks(album.TrackList)
+	requests := make([]DownloadRequest, 0, len(tracks))
+	for _, t := range tracks {
+		artist := t.Artists
+		if *options.UseFirstArtistOnly {
+			artist = backend.GetFirstArtist(artist)
+		}
+		trackDir := filepath.Join(options.OutputDir, renderFolderTemplate(options.FolderTemplate, artist, t.AlbumName, albumArtist, t.ReleaseDate, "", t.DiscNumber))
+		requests = append(requests, albumTrackRequest(t, options, artist, albumArtist, trackDir, ""))
+	}
+
+	return a.EnqueueDownloads(requests)
+}
+
+// runArtistWatcher is started by the GUI only; a one-shot CLI run exits long
+// before the first check would be due.
+func (a *App) runArtistWatcher(ctx context.Context) {
+	timer := time.NewTimer(artistWatchStartupDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if artists, _ := backend.GetWatchedArtists(); len(artists) > 0 {
+			fmt.Printf("[Watch] Checking %d watched artists\n", len(artists))
+			if queued, err := a.checkWatchedArtists(ctx); err != nil {
+				fmt.Printf("⚠ [Watch] %v\n", err)
+			} else if len(queued) > 0 {
+				fmt.Printf("✓ [Watch] Queued %d new releases\n", len(queued))
+			}
+		}
+
+		timer.Reset(time.Duration(a.settings().ArtistWatchIntervalHours * float64(time.Hour)))
+	}
+}
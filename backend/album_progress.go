@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+type AlbumTrackStatus struct {
+	ItemID      string         `json:"item_id"`
+	SpotifyID   string         `json:"spotify_id"`
+	Name        string         `json:"name"`
+	Artists     string         `json:"artists"`
+	DiscNumber  int            `json:"disc_number"`
+	TrackNumber int            `json:"track_number"`
+	Status      DownloadStatus `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	FilePath    string         `json:"file_path,omitempty"`
+}
+
+type AlbumJob struct {
+	ID        string             `json:"id"`
+	AlbumName string             `json:"album_name"`
+	Artists   string             `json:"artists"`
+	OutputDir string             `json:"output_dir"`
+	CoverPath string             `json:"cover_path,omitempty"`
+	Status    DownloadStatus     `json:"status"`
+	Total     int                `json:"total"`
+	Completed int                `json:"completed"`
+	Skipped   int                `json:"skipped"`
+	Failed    int                `json:"failed"`
+	Cancelled int                `json:"cancelled"`
+	Current   int                `json:"current"`
+	Percent   float64            `json:"percent"`
+	StartTime int64              `json:"start_time"`
+	EndTime   int64              `json:"end_time"`
+	Tracks    []AlbumTrackStatus `json:"tracks"`
+}
+
+type albumTrackRef struct {
+	jobID string
+	index int
+}
+
+var (
+	albumJobs     = make(map[string]*AlbumJob)
+	albumJobOrder []string
+	albumTracks   = make(map[string]albumTrackRef)
+	albumJobsLock sync.RWMutex
+)
+
+func StartAlbumJob(job AlbumJob) {
+	albumJobsLock.Lock()
+	defer albumJobsLock.Unlock()
+
+	job.Status = StatusDownloading
+	job.Total = len(job.Tracks)
+	job.StartTime = time.Now().Unix()
+	if _, exists := albumJobs[job.ID]; !exists {
+		albumJobOrder = append(albumJobOrder, job.ID)
+	}
+	albumJobs[job.ID] = &job
+	for i, track := range job.Tracks {
+		if track.ItemID != "" {
+			albumTracks[track.ItemID] = albumTrackRef{jobID: job.ID, index: i}
+		}
+	}
+}
+
+// albumItemStarted marks the album track of a queue item as downloading.
+func albumItemStarted(itemID string) {
+	albumJobsLock.RLock()
+	ref, ok := albumTracks[itemID]
+	if ok {
+		job, exists := albumJobs[ref.jobID]
+		ok = exists && job.Status == StatusDownloading
+	}
+	albumJobsLock.RUnlock()
+	if ok {
+		UpdateAlbumTrack(ref.jobID, ref.index, StatusDownloading, "", "")
+	}
+}
+
+func SetAlbumJobCover(id, coverPath string) {
+	albumJobsLock.Lock()
+	defer albumJobsLock.Unlock()
+
+	if job, ok := albumJobs[id]; ok {
+		job.CoverPath = coverPath
+	}
+}
+
+func UpdateAlbumTrack(id string, index int, status DownloadStatus, errorMsg, filePath string) {
+	albumJobsLock.Lock()
+	defer albumJobsLock.Unlock()
+
+	job, ok := albumJobs[id]
+	if !ok || index < 0 || index >= len(job.Tracks) {
+		return
+	}
+
+	track := &job.Tracks[index]
+	track.Status = status
+	track.Error = errorMsg
+	track.FilePath = filePath
+
+	if status == StatusDownloading {
+		job.Current = index + 1
+	}
+
+	job.Completed, job.Skipped, job.Failed, job.Cancelled = 0, 0, 0, 0
+	for _, t := range job.Tracks {
+		switch t.Status {
+		case StatusCompleted:
+			job.Completed++
+		case StatusSkipped:
+			job.Skipped++
+		case StatusFailed, StatusVerificationFailed:
+			job.Failed++
+		case StatusCancelled:
+			job.Cancelled++
+		}
+	}
+}
+
+func FinishAlbumJob(id string) {
+	albumJobsLock.Lock()
+	defer albumJobsLock.Unlock()
+
+	job, ok := albumJobs[id]
+	if !ok {
+		return
+	}
+	job.EndTime = time.Now().Unix()
+	job.Current = 0
+	if job.Failed > 0 && job.Completed == 0 && job.Skipped == 0 {
+		job.Status = StatusFailed
+	} else {
+		job.Status = StatusCompleted
+	}
+}
+
+func GetAlbumJob(id string) (AlbumJob, bool) {
+	albumJobsLock.RLock()
+	defer albumJobsLock.RUnlock()
+
+	job, ok := albumJobs[id]
+	if !ok {
+		return AlbumJob{}, false
+	}
+	return copyAlbumJob(job), true
+}
+
+func GetAlbumJobs() []AlbumJob {
+	albumJobsLock.RLock()
+	defer albumJobsLock.RUnlock()
+
+	jobs := make([]AlbumJob, 0, len(albumJobOrder))
+	for _, id := range albumJobOrder {
+		if job, ok := albumJobs[id]; ok {
+			jobs = append(jobs, copyAlbumJob(job))
+		}
+	}
+	return jobs
+}
+
+func ClearFinishedAlbumJobs() {
+	albumJobsLock.Lock()
+	defer albumJobsLock.Unlock()
+
+	var remaining []string
+	for _, id := range albumJobOrder {
+		job, ok := albumJobs[id]
+		if ok && job.Status == StatusDownloading {
+			remaining = append(remaining, id)
+			continue
+		}
+		if ok {
+			for _, track := range job.Tracks {
+				delete(albumTracks, track.ItemID)
+			}
+		}
+		delete(albumJobs, id)
+	}
+	albumJobOrder = remaining
+}
+
+// copyAlbumJob also works out the job's percentage: finished tracks count
+// in full and tracks being downloaded by the share of bytes received.
+func copyAlbumJob(job *AlbumJob) AlbumJob {
+	c := *job
+	c.Tracks = make([]AlbumTrackStatus, len(job.Tracks))
+	copy(c.Tracks, job.Tracks)
+
+	var done float64
+	for _, t := range c.Tracks {
+		switch t.Status {
+		case StatusQueued:
+		case StatusDownloading:
+			if item, ok := GetDownloadItem(t.ItemID); ok && item.Status == StatusDownloading {
+				done += item.Percent / 100
+			}
+		default:
+			done++
+		}
+	}
+	if len(c.Tracks) > 0 {
+		c.Percent = done / float64(len(c.Tracks)) * 100
+	}
+	return c
+}
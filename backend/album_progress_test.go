@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAlbumJobProgress(t *testing.T) {
+	ids := []string{"album-test-1", "album-test-2", "album-test-3", "album-test-4"}
+	for _, id := range ids {
+		AddToQueue(id, id, "Artist", "Album", "")
+	}
+	t.Cleanup(func() {
+		ClearAllDownloads()
+		ClearFinishedAlbumJobs()
+	})
+
+	job := AlbumJob{ID: "album-test"}
+	for _, id := range ids {
+		job.Tracks = append(job.Tracks, AlbumTrackStatus{ItemID: id, Status: StatusQueued})
+	}
+	StartAlbumJob(job)
+
+	StartDownloadItem(ids[0])
+	StartDownloadItem(ids[1])
+	SetItemExpectedSize(ids[1], 4*1024*1024)
+	UpdateItemProgress(ids[1], 1, 0)
+	CompleteDownloadItem(ids[0], "/music/1.flac", 10)
+	UpdateAlbumTrack("album-test", 0, StatusCompleted, "", "/music/1.flac")
+
+	got, ok := GetAlbumJob("album-test")
+	if !ok {
+		t.Fatal("album job not found")
+	}
+	wantStatus := []DownloadStatus{StatusCompleted, StatusDownloading, StatusQueued, StatusQueued}
+	for i, track := range got.Tracks {
+		if track.Status != wantStatus[i] {
+			t.Errorf("track %d status = %s, want %s", i, track.Status, wantStatus[i])
+		}
+	}
+	if got.Current != 2 {
+		t.Errorf("Current = %d, want 2", got.Current)
+	}
+	// One track done and a quarter of the second out of four.
+	if math.Abs(got.Percent-31.25) > 0.01 {
+		t.Errorf("Percent = %.2f, want 31.25", got.Percent)
+	}
+
+	FinishAlbumJob("album-test")
+	StartDownloadItem(ids[2])
+	if got, _ := GetAlbumJob("album-test"); got.Tracks[2].Status != StatusQueued {
+		t.Error("a finished album job was updated")
+	}
+}
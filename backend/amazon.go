@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,7 +12,6 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"time"
 )
 
 type AmazonDownloader struct {
@@ -33,7 +33,7 @@ type AmazonStreamResponse struct {
 func NewAmazonDownloader() *AmazonDownloader {
 	return &AmazonDownloader{
 		client: &http.Client{
-			Timeout: 120 * time.Second,
+			Transport: DownloadTransport,
 		},
 		regions: []string{"us", "eu"},
 	}
@@ -103,7 +103,9 @@ func (a *AmazonDownloader) GetAmazonURLFromSpotify(spotifyTrackID string) (strin
 	return amazonURL, nil
 }
 
-func (a *AmazonDownloader) DownloadFromAfkarXYZ(amazonURL, outputDir, quality string) (string, error) {
+const amazonAPIBase = "https://amzn.afkarxyz.fun/api/track/"
+
+func (a *AmazonDownloader) DownloadFromAfkarXYZ(ctx context.Context, amazonURL, outputDir, quality, itemID string) (string, error) {
 
 	asinRegex := regexp.MustCompile(`(B[0-9A-Z]{9})`)
 	asin := asinRegex.FindString(amazonURL)
@@ -111,8 +113,8 @@ func (a *AmazonDownloader) DownloadFromAfkarXYZ(amazonURL, outputDir, quality st
 		return "", fmt.Errorf("failed to extract ASIN from URL: %s", amazonURL)
 	}
 
-	apiURL := fmt.Sprintf("https://amzn.afkarxyz.fun/api/track/%s", asin)
-	req, err := http.NewRequest("GET", apiURL, nil)
+	apiURL := amazonAPIBase + asin
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return "", err
 	}
@@ -147,39 +149,18 @@ func (a *AmazonDownloader) DownloadFromAfkarXYZ(amazonURL, outputDir, quality st
 	fileName := fmt.Sprintf("%s.m4a", asin)
 	filePath := filepath.Join(outputDir, fileName)
 
-	out, err := os.Create(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
-
-	dlReq, _ := http.NewRequest("GET", downloadURL, nil)
-	dlReq.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/145.0.0.0 Safari/537.36")
-
-	dlResp, err := a.client.Do(dlReq)
-	if err != nil {
-		return "", err
-	}
-	defer dlResp.Body.Close()
-
 	fmt.Printf("Downloading track: %s\n", fileName)
-	pw := NewProgressWriter(out)
-	_, err = io.Copy(pw, dlResp.Body)
-	if err != nil {
-		out.Close()
-		os.Remove(filePath)
+	if _, err := DownloadToFile(ctx, a.client, downloadURL, filePath, itemID); err != nil {
 		return "", err
 	}
 
-	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
-
 	if apiResp.DecryptionKey != "" {
 		fmt.Printf("Decrypting file...\n")
 
 		ffprobePath, err := GetFFprobePath()
 		var codec string
 		if err == nil {
-			cmdProbe := exec.Command(ffprobePath,
+			cmdProbe := exec.CommandContext(ctx, ffprobePath,
 				"-v", "quiet",
 				"-select_streams", "a:0",
 				"-show_entries", "stream=codec_name",
@@ -216,7 +197,7 @@ func (a *AmazonDownloader) DownloadFromAfkarXYZ(amazonURL, outputDir, quality st
 
 		key := strings.TrimSpace(apiResp.DecryptionKey)
 
-		cmd := exec.Command(ffmpegPath,
+		cmd := exec.CommandContext(ctx, ffmpegPath,
 			"-decryption_key", key,
 			"-i", filePath,
 			"-c", "copy",
@@ -227,6 +208,11 @@ func (a *AmazonDownloader) DownloadFromAfkarXYZ(amazonURL, outputDir, quality st
 		setHideWindow(cmd)
 		output, err := cmd.CombinedOutput()
 		if err != nil {
+			os.Remove(decryptedPath)
+			os.Remove(filePath)
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
 
 			outStr := string(output)
 			if len(outStr) > 500 {
@@ -236,6 +222,8 @@ func (a *AmazonDownloader) DownloadFromAfkarXYZ(amazonURL, outputDir, quality st
 		}
 
 		if info, err := os.Stat(decryptedPath); err != nil || info.Size() == 0 {
+			os.Remove(decryptedPath)
+			os.Remove(filePath)
 			return "", fmt.Errorf("decrypted file missing or empty")
 		}
 
@@ -255,31 +243,50 @@ func (a *AmazonDownloader) DownloadFromAfkarXYZ(amazonURL, outputDir, quality st
 	return filePath, nil
 }
 
-func (a *AmazonDownloader) DownloadFromService(amazonURL, outputDir, quality string) (string, error) {
-	return a.DownloadFromAfkarXYZ(amazonURL, outputDir, quality)
+func (a *AmazonDownloader) DownloadFromService(ctx context.Context, amazonURL, outputDir, quality, itemID string) (string, error) {
+	return a.DownloadFromAfkarXYZ(ctx, amazonURL, outputDir, quality, itemID)
 }
 
-func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filenameFormat, playlistName, playlistOwner string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, spotifyCoverURL string, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, embedMaxQualityCover bool, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string, useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool) (string, error) {
+func (a *AmazonDownloader) DownloadByURL(ctx context.Context, amazonURL string, req TrackRequest) (*TrackResult, error) {
+	outputDir := req.OutputDir
+	filenameFormat := req.FilenameFormat
+	position := req.Position
+	spotifyTrackName := req.TrackName
+	spotifyArtistName := req.ArtistName
+	spotifyAlbumName := req.AlbumName
+	spotifyAlbumArtist := req.AlbumArtist
+	spotifyReleaseDate := req.ReleaseDate
+	spotifyDiscNumber := req.DiscNumber
+	spotifyURL := req.SpotifyURL()
+
+	result := &TrackResult{
+		Service:  "amazon",
+		API:      apiHost(amazonAPIBase),
+		Quality:  req.Quality,
+		SourceID: regexp.MustCompile(`(B[0-9A-Z]{9})`).FindString(amazonURL),
+	}
 
 	if outputDir != "." {
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create output directory: %w", err)
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
 
 	if spotifyTrackName != "" && spotifyArtistName != "" {
 		filenameArtist := spotifyArtistName
 		filenameAlbumArtist := spotifyAlbumArtist
-		if useFirstArtistOnly {
+		if req.UseFirstArtistOnly {
 			filenameArtist = GetFirstArtist(spotifyArtistName)
 			filenameAlbumArtist = GetFirstArtist(spotifyAlbumArtist)
 		}
-		expectedFilename := BuildExpectedFilename(spotifyTrackName, filenameArtist, spotifyAlbumName, filenameAlbumArtist, spotifyReleaseDate, filenameFormat, playlistName, playlistOwner, includeTrackNumber, position, spotifyDiscNumber, false)
+		expectedFilename := BuildExpectedFilename(spotifyTrackName, filenameArtist, spotifyAlbumName, filenameAlbumArtist, spotifyReleaseDate, filenameFormat, req.PlaylistName, req.PlaylistOwner, req.IncludeTrackNumber, position, spotifyDiscNumber, false)
 		expectedPath := filepath.Join(outputDir, expectedFilename)
 
 		if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 0 {
 			fmt.Printf("File already exists: %s (%.2f MB)\n", expectedPath, float64(fileInfo.Size())/(1024*1024))
-			return "EXISTS:" + expectedPath, nil
+			result.Path = expectedPath
+			result.AlreadyExists = true
+			return result, nil
 		}
 	}
 
@@ -289,24 +296,18 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 	}
 
 	metaChan := make(chan mbResult, 1)
-	if embedGenre && spotifyURL != "" {
+	if req.EmbedGenre && spotifyURL != "" {
 		go func() {
-			res := mbResult{}
-			var isrc string
-			parts := strings.Split(spotifyURL, "/")
-			if len(parts) > 0 {
-				sID := strings.Split(parts[len(parts)-1], "?")[0]
-				if sID != "" {
-					client := NewSongLinkClient()
-					if val, err := client.GetISRC(sID); err == nil {
-						isrc = val
-					}
+			res := mbResult{ISRC: req.ISRC}
+			if res.ISRC == "" {
+				client := NewSongLinkClient()
+				if val, err := client.GetISRC(req.SpotifyID); err == nil {
+					res.ISRC = val
 				}
 			}
-			res.ISRC = isrc
-			if isrc != "" {
+			if res.ISRC != "" {
 				fmt.Println("Fetching MusicBrainz metadata...")
-				if fetchedMeta, err := FetchMusicBrainzMetadata(isrc, spotifyTrackName, spotifyArtistName, spotifyAlbumName, useSingleGenre, embedGenre); err == nil {
+				if fetchedMeta, err := FetchMusicBrainzMetadata(res.ISRC, spotifyTrackName, spotifyArtistName, spotifyAlbumName, req.UseSingleGenre, req.EmbedGenre); err == nil {
 					res.Metadata = fetchedMeta
 					fmt.Println("✓ MusicBrainz metadata fetched")
 				} else {
@@ -321,17 +322,20 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 
 	fmt.Printf("Using Amazon URL: %s\n", amazonURL)
 
-	filePath, err := a.DownloadFromService(amazonURL, outputDir, quality)
+	filePath, err := a.DownloadFromService(ctx, amazonURL, outputDir, req.Quality, req.ItemID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var isrc string
+	isrc := req.ISRC
 	var mbMeta Metadata
 	if spotifyURL != "" {
-		result := <-metaChan
-		isrc = result.ISRC
-		mbMeta = result.Metadata
+		if res, ok := <-metaChan; ok {
+			if res.ISRC != "" {
+				isrc = res.ISRC
+			}
+			mbMeta = res.Metadata
+		}
 	}
 
 	originalFileDir := filepath.Dir(filePath)
@@ -341,7 +345,7 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 		safeArtist := sanitizeFilename(spotifyArtistName)
 		safeAlbumArtist := sanitizeFilename(spotifyAlbumArtist)
 
-		if useFirstArtistOnly {
+		if req.UseFirstArtistOnly {
 			safeArtist = sanitizeFilename(GetFirstArtist(spotifyArtistName))
 			safeAlbumArtist = sanitizeFilename(GetFirstArtist(spotifyAlbumArtist))
 		}
@@ -390,7 +394,7 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 				newFilename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
 			}
 
-			if includeTrackNumber && position > 0 {
+			if req.IncludeTrackNumber && position > 0 {
 				newFilename = fmt.Sprintf("%02d. %s", position, newFilename)
 			}
 		}
@@ -414,10 +418,10 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 
 	coverPath := ""
 
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = filePath + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -426,7 +430,7 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 		}
 	}
 
-	trackNumberToEmbed := spotifyTrackNumber
+	trackNumberToEmbed := req.TrackNumber
 	if trackNumberToEmbed == 0 {
 		trackNumberToEmbed = 1
 	}
@@ -438,18 +442,23 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 		AlbumArtist: spotifyAlbumArtist,
 		Date:        spotifyReleaseDate,
 		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
+		TotalTracks: req.TotalTracks,
 		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
+		TotalDiscs:  req.TotalDiscs,
 		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
+		Copyright:   req.Copyright,
+		Publisher:   req.Publisher,
 		Description: "https://github.com/afkarxyz/SpotiFLAC",
 		ISRC:        isrc,
 		Genre:       mbMeta.Genre,
 	}
+	metadata = withSourceTags(metadata, req, result)
 
-	if err := EmbedMetadataToConvertedFile(filePath, metadata, coverPath); err != nil {
+	if err := EmbedMetadataToConvertedFile(ctx, filePath, metadata, coverPath); err != nil {
+		if ctx.Err() != nil {
+			os.Remove(filePath)
+			return nil, ctx.Err()
+		}
 		fmt.Printf("Warning: Failed to embed metadata: %v\n", err)
 	} else {
 		fmt.Println("Metadata embedded successfully")
@@ -469,17 +478,31 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 
 	fmt.Println("Done")
 	fmt.Println("✓ Downloaded successfully from Amazon Music")
-	return filePath, nil
+	result.Path = filePath
+	result.ISRC = isrc
+	return result, nil
 }
 
-func (a *AmazonDownloader) DownloadBySpotifyID(spotifyTrackID, outputDir, quality, filenameFormat, playlistName, playlistOwner string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, spotifyCoverURL string, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, embedMaxQualityCover bool, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string,
-	useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool,
-) (string, error) {
+func (a *AmazonDownloader) DownloadBySpotifyID(ctx context.Context, req TrackRequest) (*TrackResult, error) {
 
-	amazonURL, err := a.GetAmazonURLFromSpotify(spotifyTrackID)
+	amazonURL, err := a.GetAmazonURLFromSpotify(req.SpotifyID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return a.DownloadByURL(amazonURL, outputDir, quality, filenameFormat, playlistName, playlistOwner, includeTrackNumber, position, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, spotifyCoverURL, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks, embedMaxQualityCover, spotifyTotalDiscs, spotifyCopyright, spotifyPublisher, spotifyURL, useFirstArtistOnly, useSingleGenre, embedGenre)
+	return a.DownloadByURL(ctx, amazonURL, req)
+}
+
+type amazonProvider struct{}
+
+func (amazonProvider) Name() string {
+	return "amazon"
+}
+
+func (amazonProvider) Download(ctx context.Context, req TrackRequest) (*TrackResult, error) {
+	downloader := NewAmazonDownloader()
+	if req.ServiceURL != "" {
+		return downloader.DownloadByURL(ctx, req.ServiceURL, req)
+	}
+	return downloader.DownloadBySpotifyID(ctx, req)
 }
@@ -0,0 +1,197 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const watchedArtistsBucket = "WatchedArtists"
+
+type ArtistWatchFilter struct {
+	ReleaseTypes []string `json:"release_types,omitempty"`
+	Since        string   `json:"since,omitempty"`
+	Until        string   `json:"until,omitempty"`
+}
+
+type WatchedArtist struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Filter        ArtistWatchFilter `json:"filter"`
+	KnownReleases []string          `json:"known_releases"`
+	AddedAt       int64             `json:"added_at"`
+	LastChecked   int64             `json:"last_checked"`
+	LastError     string            `json:"last_error,omitempty"`
+}
+
+func (w *WatchedArtist) IsKnown(releaseID string) bool {
+	for _, id := range w.KnownReleases {
+		if id == releaseID {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WatchedArtist) MarkKnown(releaseID string) {
+	if !w.IsKnown(releaseID) {
+		w.KnownReleases = append(w.KnownReleases, releaseID)
+	}
+}
+
+func (f ArtistWatchFilter) Matches(release DiscographyAlbumMetadata) bool {
+	if len(f.ReleaseTypes) > 0 {
+		matched := false
+		for _, t := range f.ReleaseTypes {
+			if strings.EqualFold(t, release.AlbumType) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if f.Since != "" && padReleaseDate(release.ReleaseDate, false) < padReleaseDate(f.Since, false) {
+		return false
+	}
+	if f.Until != "" && padReleaseDate(release.ReleaseDate, false) > padReleaseDate(f.Until, true) {
+		return false
+	}
+	return true
+}
+
+// Spotify reports release dates with year, month or day precision.
+func padReleaseDate(date string, end bool) string {
+	date = strings.TrimSpace(date)
+	switch len(date) {
+	case 4:
+		if end {
+			return date + "-12-31"
+		}
+		return date + "-01-01"
+	case 7:
+		if end {
+			return date + "-31"
+		}
+		return date + "-01"
+	}
+	return date
+}
+
+func ParseSpotifyArtistID(url string) (string, error) {
+	parsed, err := parseSpotifyURI(url)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Type != "artist" && parsed.Type != "artist_discography" {
+		return "", fmt.Errorf("URL is not a Spotify artist: %s", url)
+	}
+	return parsed.ID, nil
+}
+
+func FetchArtistReleases(ctx context.Context, artistID string) (string, []DiscographyAlbumMetadata, error) {
+	client := NewSpotifyMetadataClient()
+	raw, err := client.fetchArtistDiscography(ctx, spotifyURI{Type: "artist_discography", ID: artistID, DiscographyGroup: "all"})
+	if err != nil {
+		return "", nil, err
+	}
+
+	releases := make([]DiscographyAlbumMetadata, 0, len(raw.Discography.All))
+	for _, alb := range raw.Discography.All {
+		if alb.ID == "" {
+			continue
+		}
+		releases = append(releases, DiscographyAlbumMetadata{
+			ID:          alb.ID,
+			Name:        alb.Name,
+			AlbumType:   strings.ToLower(alb.Type),
+			ReleaseDate: alb.Date,
+			TotalTracks: alb.TotalTracks,
+			Artists:     raw.Name,
+			Images:      alb.Cover,
+			ExternalURL: fmt.Sprintf("https://open.spotify.com/album/%s", alb.ID),
+		})
+	}
+	return raw.Name, releases, nil
+}
+
+func SaveWatchedArtist(artist WatchedArtist) error {
+	if historyDB == nil {
+		if err := InitHistoryDB("SpotiFLAC"); err != nil {
+			return err
+		}
+	}
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(watchedArtistsBucket))
+		if err != nil {
+			return err
+		}
+
+		buf, err := json.Marshal(artist)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(artist.ID), buf)
+	})
+}
+
+func GetWatchedArtist(id string) (WatchedArtist, bool, error) {
+	var artist WatchedArtist
+	found := false
+	if historyDB == nil {
+		return artist, false, nil
+	}
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchedArtistsBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &artist)
+	})
+	return artist, found, err
+}
+
+func GetWatchedArtists() ([]WatchedArtist, error) {
+	if historyDB == nil {
+		return []WatchedArtist{}, nil
+	}
+	artists := []WatchedArtist{}
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchedArtistsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var artist WatchedArtist
+			if err := json.Unmarshal(v, &artist); err == nil {
+				artists = append(artists, artist)
+			}
+			return nil
+		})
+	})
+	return artists, err
+}
+
+func DeleteWatchedArtist(id string) error {
+	if historyDB == nil {
+		return nil
+	}
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchedArtistsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(id))
+	})
+}
@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	bandwidthChunkSize = 32 * 1024
+
+	// Download clients have no overall Timeout, since a capped transfer of a
+	// large file can legitimately take a long time. Instead the server must
+	// answer within downloadHeaderTimeout and then never go quiet for longer
+	// than downloadStallTimeout. Time spent waiting on the limiter doesn't
+	// count towards either.
+	downloadHeaderTimeout = 60 * time.Second
+	downloadStallTimeout  = 60 * time.Second
+)
+
+// tokenBucket refills at rate bytes per second up to one second of burst.
+// A zero rate means unlimited.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+var downloadLimiter = &tokenBucket{}
+
+// DownloadTransport is shared by every client that fetches audio, covers or
+// FFmpeg so the bandwidth cap applies to all of them at once.
+var DownloadTransport http.RoundTripper = &limitedTransport{base: newDownloadBaseTransport()}
+
+func newDownloadBaseTransport() http.RoundTripper {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+	t := base.Clone()
+	t.ResponseHeaderTimeout = downloadHeaderTimeout
+	return t
+}
+
+// ErrDownloadStalled is returned when a response body delivers no data for
+// downloadStallTimeout.
+var ErrDownloadStalled = errors.New("download stalled")
+
+func SetBandwidthLimit(bytesPerSecond int64) {
+	downloadLimiter.mu.Lock()
+	defer downloadLimiter.mu.Unlock()
+
+	if bytesPerSecond < 0 {
+		bytesPerSecond = 0
+	}
+	downloadLimiter.rate = float64(bytesPerSecond)
+	downloadLimiter.tokens = 0
+	downloadLimiter.last = time.Now()
+}
+
+func GetBandwidthLimit() int64 {
+	downloadLimiter.mu.Lock()
+	defer downloadLimiter.mu.Unlock()
+	return int64(downloadLimiter.rate)
+}
+
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	if b.rate <= 0 {
+		b.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	b.tokens -= float64(n)
+
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.ReadCloser
+	stall   time.Duration
+	stalled atomic.Bool
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunkSize {
+		p = p[:bandwidthChunkSize]
+	}
+
+	// A blocked Read can only be interrupted by closing the body.
+	watchdog := time.AfterFunc(r.stall, func() {
+		r.stalled.Store(true)
+		r.reader.Close()
+	})
+	n, err := r.reader.Read(p)
+	watchdog.Stop()
+	if r.stalled.Load() {
+		return n, fmt.Errorf("%w: no data for %s", ErrDownloadStalled, r.stall)
+	}
+
+	if n > 0 {
+		if waitErr := downloadLimiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *limitedReader) Close() error {
+	return r.reader.Close()
+}
+
+type limitedTransport struct {
+	base http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+	resp.Body = &limitedReader{ctx: req.Context(), reader: resp.Body, stall: downloadStallTimeout}
+	return resp, nil
+}
@@ -2,6 +2,7 @@ package backend
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -19,7 +20,7 @@ type DeezerDownloader struct {
 func NewDeezerDownloader() *DeezerDownloader {
 	return &DeezerDownloader{
 		client: &http.Client{
-			Timeout: 300 * time.Second,
+			Transport: DownloadTransport,
 		},
 	}
 }
@@ -30,8 +31,14 @@ type YoinkifyRequest struct {
 	GenreSource string `json:"genreSource"`
 }
 
-func (d *DeezerDownloader) DownloadFromYoinkify(spotifyURL, outputDir string) (string, error) {
-	apiURL := "https://yoinkify.lol/api/download"
+const deezerAPIURL = "https://yoinkify.lol/api/download"
+
+// DownloadFromYoinkify does not go through DownloadToFile and cannot be
+// resumed: the file is produced by a POST that tags and streams it on the
+// fly, with no Range support, ETag or stable length to validate a partial
+// file against. An interrupted transfer is removed and started over.
+func (d *DeezerDownloader) DownloadFromYoinkify(ctx context.Context, spotifyURL, outputDir, itemID string) (string, error) {
+	apiURL := deezerAPIURL
 
 	payload := YoinkifyRequest{
 		URL:         spotifyURL,
@@ -44,7 +51,7 @@ func (d *DeezerDownloader) DownloadFromYoinkify(spotifyURL, outputDir string) (s
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", err
 	}
@@ -72,7 +79,10 @@ func (d *DeezerDownloader) DownloadFromYoinkify(spotifyURL, outputDir string) (s
 	defer out.Close()
 
 	fmt.Printf("Downloading track from Deezer...\n")
-	pw := NewProgressWriter(out)
+	pw := NewProgressWriterWithID(out, itemID)
+	if resp.ContentLength > 0 {
+		pw.SetExpectedSize(resp.ContentLength)
+	}
 	_, err = io.Copy(pw, resp.Body)
 	if err != nil {
 		out.Close()
@@ -84,27 +94,45 @@ func (d *DeezerDownloader) DownloadFromYoinkify(spotifyURL, outputDir string) (s
 	return filePath, nil
 }
 
-func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playlistName, playlistOwner string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, spotifyCoverURL string, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, embedMaxQualityCover bool, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string, useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool) (string, error) {
+func (d *DeezerDownloader) Download(ctx context.Context, req TrackRequest) (*TrackResult, error) {
+	outputDir := req.OutputDir
+	filenameFormat := req.FilenameFormat
+	position := req.Position
+	spotifyTrackName := req.TrackName
+	spotifyArtistName := req.ArtistName
+	spotifyAlbumName := req.AlbumName
+	spotifyAlbumArtist := req.AlbumArtist
+	spotifyReleaseDate := req.ReleaseDate
+	spotifyDiscNumber := req.DiscNumber
+	spotifyURL := req.SpotifyURL()
+
+	result := &TrackResult{
+		Service: "deezer",
+		API:     apiHost(deezerAPIURL),
+		Quality: "flac",
+	}
 
 	if outputDir != "." {
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create output directory: %w", err)
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
 
 	if spotifyTrackName != "" && spotifyArtistName != "" {
 		filenameArtist := spotifyArtistName
 		filenameAlbumArtist := spotifyAlbumArtist
-		if useFirstArtistOnly {
+		if req.UseFirstArtistOnly {
 			filenameArtist = GetFirstArtist(spotifyArtistName)
 			filenameAlbumArtist = GetFirstArtist(spotifyAlbumArtist)
 		}
-		expectedFilename := BuildExpectedFilename(spotifyTrackName, filenameArtist, spotifyAlbumName, filenameAlbumArtist, spotifyReleaseDate, filenameFormat, playlistName, playlistOwner, includeTrackNumber, position, spotifyDiscNumber, false)
+		expectedFilename := BuildExpectedFilename(spotifyTrackName, filenameArtist, spotifyAlbumName, filenameAlbumArtist, spotifyReleaseDate, filenameFormat, req.PlaylistName, req.PlaylistOwner, req.IncludeTrackNumber, position, spotifyDiscNumber, false)
 		expectedPath := filepath.Join(outputDir, expectedFilename)
 
 		if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 0 {
 			fmt.Printf("File already exists: %s (%.2f MB)\n", expectedPath, float64(fileInfo.Size())/(1024*1024))
-			return "EXISTS:" + expectedPath, nil
+			result.Path = expectedPath
+			result.AlreadyExists = true
+			return result, nil
 		}
 	}
 
@@ -114,24 +142,18 @@ func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playli
 	}
 
 	metaChan := make(chan mbResult, 1)
-	if (embedGenre || true) && spotifyURL != "" {
+	if spotifyURL != "" {
 		go func() {
-			res := mbResult{}
-			var isrc string
-			parts := strings.Split(spotifyURL, "/")
-			if len(parts) > 0 {
-				sID := strings.Split(parts[len(parts)-1], "?")[0]
-				if sID != "" {
-					client := NewSongLinkClient()
-					if val, err := client.GetISRC(sID); err == nil {
-						isrc = val
-					}
+			res := mbResult{ISRC: req.ISRC}
+			if res.ISRC == "" {
+				client := NewSongLinkClient()
+				if val, err := client.GetISRC(req.SpotifyID); err == nil {
+					res.ISRC = val
 				}
 			}
-			res.ISRC = isrc
-			if isrc != "" && embedGenre {
+			if res.ISRC != "" && req.EmbedGenre {
 				fmt.Println("Fetching MusicBrainz metadata...")
-				if fetchedMeta, err := FetchMusicBrainzMetadata(isrc, spotifyTrackName, spotifyArtistName, spotifyAlbumName, useSingleGenre, embedGenre); err == nil {
+				if fetchedMeta, err := FetchMusicBrainzMetadata(res.ISRC, spotifyTrackName, spotifyArtistName, spotifyAlbumName, req.UseSingleGenre, req.EmbedGenre); err == nil {
 					res.Metadata = fetchedMeta
 					fmt.Println("✓ MusicBrainz metadata fetched")
 				} else {
@@ -144,24 +166,24 @@ func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playli
 		close(metaChan)
 	}
 
-	filePath, err := d.DownloadFromYoinkify(spotifyURL, outputDir)
+	filePath, err := d.DownloadFromYoinkify(ctx, spotifyURL, outputDir, req.ItemID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var isrc string
+	isrc := req.ISRC
 	var mbMeta Metadata
 	if spotifyURL != "" {
-		result := <-metaChan
-		isrc = result.ISRC
-		mbMeta = result.Metadata
+		res := <-metaChan
+		isrc = res.ISRC
+		mbMeta = res.Metadata
 	}
 
 	if spotifyTrackName != "" && spotifyArtistName != "" {
 		safeArtist := sanitizeFilename(spotifyArtistName)
 		safeAlbumArtist := sanitizeFilename(spotifyAlbumArtist)
 
-		if useFirstArtistOnly {
+		if req.UseFirstArtistOnly {
 			safeArtist = sanitizeFilename(GetFirstArtist(spotifyArtistName))
 			safeAlbumArtist = sanitizeFilename(GetFirstArtist(spotifyAlbumArtist))
 		}
@@ -206,7 +228,7 @@ func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playli
 				newFilename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
 			}
 
-			if includeTrackNumber && position > 0 {
+			if req.IncludeTrackNumber && position > 0 {
 				newFilename = fmt.Sprintf("%02d. %s", position, newFilename)
 			}
 		}
@@ -226,10 +248,10 @@ func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playli
 	fmt.Println("Embedding Spotify metadata...")
 
 	coverPath := ""
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = filePath + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -238,7 +260,7 @@ func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playli
 		}
 	}
 
-	trackNumberToEmbed := spotifyTrackNumber
+	trackNumberToEmbed := req.TrackNumber
 	if trackNumberToEmbed == 0 {
 		trackNumberToEmbed = 1
 	}
@@ -250,18 +272,23 @@ func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playli
 		AlbumArtist: spotifyAlbumArtist,
 		Date:        spotifyReleaseDate,
 		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
+		TotalTracks: req.TotalTracks,
 		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
+		TotalDiscs:  req.TotalDiscs,
 		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
+		Copyright:   req.Copyright,
+		Publisher:   req.Publisher,
 		Description: "https://github.com/afkarxyz/SpotiFLAC",
 		ISRC:        isrc,
 		Genre:       mbMeta.Genre,
 	}
+	metadata = withSourceTags(metadata, req, result)
 
-	if err := EmbedMetadataToConvertedFile(filePath, metadata, coverPath); err != nil {
+	if err := EmbedMetadataToConvertedFile(ctx, filePath, metadata, coverPath); err != nil {
+		if ctx.Err() != nil {
+			os.Remove(filePath)
+			return nil, ctx.Err()
+		}
 		fmt.Printf("Warning: Failed to embed metadata: %v\n", err)
 	} else {
 		fmt.Println("Metadata embedded successfully")
@@ -269,5 +296,28 @@ func (d *DeezerDownloader) Download(spotifyID, outputDir, filenameFormat, playli
 
 	fmt.Println("Done")
 	fmt.Println("✓ Downloaded successfully from Deezer")
-	return filePath, nil
+	result.Path = filePath
+	result.ISRC = isrc
+	return result, nil
+}
+
+type deezerProvider struct{}
+
+func (deezerProvider) Name() string {
+	return "deezer"
+}
+
+func (deezerProvider) Download(ctx context.Context, req TrackRequest) (*TrackResult, error) {
+	return NewDeezerDownloader().Download(ctx, req)
+}
+
+func (deezerProvider) ProbeQuality(ctx context.Context, req TrackRequest) (*QualityProbe, error) {
+	if _, err := NewSongLinkClient().GetDeezerURLFromSpotify(req.SpotifyID); err != nil {
+		return nil, err
+	}
+	return &QualityProbe{
+		Quality:    "flac",
+		BitDepth:   16,
+		SampleRate: 44100,
+	}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"archive/zip"
 
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -290,7 +291,7 @@ func downloadAndExtract(url, destDir string, progressCallback func(int), progres
 	defer os.Remove(tmpFile.Name())
 	defer tmpFile.Close()
 
-	client := &http.Client{}
+	client := &http.Client{Transport: DownloadTransport}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -693,14 +694,14 @@ func ConvertAudio(req ConvertAudioRequest) ([]ConvertAudioResult, error) {
 				return
 			}
 
-			if err := EmbedMetadataToConvertedFile(outputFile, inputMetadata, coverArtPath); err != nil {
+			if err := EmbedMetadataToConvertedFile(context.Background(), outputFile, inputMetadata, coverArtPath); err != nil {
 				fmt.Printf("[FFmpeg] Warning: Failed to embed metadata: %v\n", err)
 			} else {
 				fmt.Printf("[FFmpeg] Metadata embedded successfully\n")
 			}
 
 			if lyrics != "" {
-				if err := EmbedLyricsOnlyUniversal(outputFile, lyrics); err != nil {
+				if err := EmbedLyricsOnlyUniversal(context.Background(), outputFile, lyrics); err != nil {
 					fmt.Printf("[FFmpeg] Warning: Failed to embed lyrics: %v\n", err)
 				} else {
 					fmt.Printf("[FFmpeg] Lyrics embedded successfully\n")
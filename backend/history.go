@@ -23,6 +23,20 @@ type HistoryItem struct {
 	Format      string `json:"format"`
 	Path        string `json:"path"`
 	Timestamp   int64  `json:"timestamp"`
+
+	Service      string `json:"service,omitempty"`
+	SourceReason string `json:"source_reason,omitempty"`
+	ISRC         string `json:"isrc,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+
+	// Provenance as reported by the provider; see TrackResult.
+	API              string `json:"api,omitempty"`
+	SourceID         string `json:"source_id,omitempty"`
+	SourceQuality    string `json:"source_quality,omitempty"`
+	RequestedQuality string `json:"requested_quality,omitempty"`
+
+	// Missing is set by RelinkHistory when the file could not be found.
+	Missing bool `json:"missing,omitempty"`
 }
 
 var historyDB *bolt.DB
@@ -49,8 +63,10 @@ func InitHistoryDB(appName string) error {
 	}
 
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(historyBucket))
-		return err
+		if _, err := tx.CreateBucketIfNotExists([]byte(historyBucket)); err != nil {
+			return err
+		}
+		return ensureHistoryIndex(tx)
 	})
 
 	if err != nil {
@@ -90,26 +106,45 @@ func AddHistoryItem(item HistoryItem, appName string) error {
 		}
 
 		if b.Stats().KeyN >= maxHistory {
-			c := b.Cursor()
-
 			toDelete := maxHistory / 20
 			if toDelete < 1 {
 				toDelete = 1
 			}
 
-			count := 0
-			for k, _ := c.First(); k != nil && count < toDelete; k, _ = c.Next() {
-				if err := b.Delete(k); err != nil {
-					return err
-				}
-				count++
+			if err := evictOldestHistory(tx, b, toDelete); err != nil {
+				return err
 			}
 		}
 
-		return b.Put([]byte(item.ID), buf)
+		if err := b.Put([]byte(item.ID), buf); err != nil {
+			return err
+		}
+		return indexHistoryItem(tx, item)
 	})
 }
 
+// evictOldestHistory removes the n oldest download history items along with
+// their index entries.
+func evictOldestHistory(tx *bolt.Tx, b *bolt.Bucket, n int) error {
+	var evicted []HistoryItem
+	c := b.Cursor()
+	for k, v := c.First(); k != nil && len(evicted) < n; k, v = c.Next() {
+		var item HistoryItem
+		json.Unmarshal(v, &item)
+		item.ID = string(k)
+		evicted = append(evicted, item)
+	}
+	for _, item := range evicted {
+		if err := b.Delete([]byte(item.ID)); err != nil {
+			return err
+		}
+		if err := unindexHistoryItem(tx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GetHistoryItems(appName string) ([]HistoryItem, error) {
 	if historyDB == nil {
 		if err := InitHistoryDB(appName); err != nil {
@@ -147,7 +182,15 @@ func ClearHistory(appName string) error {
 		}
 	}
 	return historyDB.Update(func(tx *bolt.Tx) error {
-		return tx.DeleteBucket([]byte(historyBucket))
+		if tx.Bucket([]byte(historyIndexBucket)) != nil {
+			if err := tx.DeleteBucket([]byte(historyIndexBucket)); err != nil {
+				return err
+			}
+		}
+		if err := tx.DeleteBucket([]byte(historyBucket)); err != nil {
+			return err
+		}
+		return ensureHistoryIndex(tx)
 	})
 }
 
@@ -306,6 +349,15 @@ func DeleteHistoryItem(id string, appName string) error {
 			return nil
 		}
 
+		if v := b.Get([]byte(id)); v != nil {
+			var item HistoryItem
+			if err := json.Unmarshal(v, &item); err == nil {
+				item.ID = id
+				if err := unindexHistoryItem(tx, item); err != nil {
+					return err
+				}
+			}
+		}
 		return b.Delete([]byte(id))
 	})
 }
@@ -0,0 +1,454 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Secondary indexes over DownloadHistory live in nested buckets of
+// historyIndexBucket. Each entry maps a sortable key (the indexed value
+// followed by the item ID) to the item ID.
+const (
+	historyIndexBucket  = "DownloadHistoryIndex"
+	historyIndexVersion = 2
+
+	historyIndexTimestamp = "timestamp"
+	historyIndexDuration  = "duration"
+	historyIndexTitle     = "title"
+	historyIndexArtists   = "artists"
+	historyIndexArtist    = "artist"
+	historyIndexAlbum     = "album"
+	historyIndexFormat    = "format"
+	historyIndexQuality   = "quality"
+	historyIndexService   = "service"
+
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 500
+)
+
+var (
+	historyIndexNames = []string{
+		historyIndexTimestamp, historyIndexDuration, historyIndexTitle, historyIndexArtists,
+		historyIndexArtist, historyIndexAlbum, historyIndexFormat, historyIndexQuality, historyIndexService,
+	}
+	historyIndexVersionKey = []byte("version")
+)
+
+type HistoryQuery struct {
+	Artist  string `json:"artist,omitempty"`
+	Album   string `json:"album,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Quality string `json:"quality,omitempty"`
+	Service string `json:"service,omitempty"`
+	From    int64  `json:"from,omitempty"`
+	To      int64  `json:"to,omitempty"`
+	Text    string `json:"text,omitempty"`
+
+	// SortBy is one of timestamp, title, artist, album or duration. Order
+	// defaults to desc for timestamp and asc for everything else.
+	SortBy string `json:"sort_by,omitempty"`
+	Order  string `json:"order,omitempty"`
+
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// HistoryPage is one page of a history query. Total is the size of the whole
+// history; Matched is the number of items matching the query and is only
+// counted for the first page (no cursor), since it needs a full scan.
+type HistoryPage struct {
+	Items      []HistoryItem `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Total      int           `json:"total"`
+	Matched    int           `json:"matched"`
+}
+
+func historyTextKey(value, id string) []byte {
+	return []byte(strings.ToLower(strings.TrimSpace(value)) + "\x00" + id)
+}
+
+func historyNumberKey(n int64, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(n))
+	return append(key, id...)
+}
+
+func historyTextPrefix(value string) []byte {
+	return []byte(strings.ToLower(strings.TrimSpace(value)) + "\x00")
+}
+
+func splitHistoryArtists(artists string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(artists, func(r rune) bool { return r == ',' || r == ';' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func parseHistoryDuration(s string) int64 {
+	var seconds int64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
+func historyIndexKeys(item HistoryItem) map[string][][]byte {
+	keys := map[string][][]byte{
+		historyIndexTimestamp: {historyNumberKey(item.Timestamp, item.ID)},
+		historyIndexDuration:  {historyNumberKey(parseHistoryDuration(item.DurationStr), item.ID)},
+		historyIndexTitle:     {historyTextKey(item.Title, item.ID)},
+		historyIndexArtists:   {historyTextKey(item.Artists, item.ID)},
+		historyIndexAlbum:     {historyTextKey(item.Album, item.ID)},
+		historyIndexFormat:    {historyTextKey(item.Format, item.ID)},
+		historyIndexQuality:   {historyTextKey(item.Quality, item.ID)},
+		historyIndexService:   {historyTextKey(item.Service, item.ID)},
+	}
+	for _, artist := range splitHistoryArtists(item.Artists) {
+		keys[historyIndexArtist] = append(keys[historyIndexArtist], historyTextKey(artist, item.ID))
+	}
+	return keys
+}
+
+func historyIndexes(tx *bolt.Tx) (*bolt.Bucket, error) {
+	root, err := tx.CreateBucketIfNotExists([]byte(historyIndexBucket))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range historyIndexNames {
+		if _, err := root.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+func indexHistoryItem(tx *bolt.Tx, item HistoryItem) error {
+	root, err := historyIndexes(tx)
+	if err != nil {
+		return err
+	}
+	for name, keys := range historyIndexKeys(item) {
+		b := root.Bucket([]byte(name))
+		for _, key := range keys {
+			if err := b.Put(key, []byte(item.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func unindexHistoryItem(tx *bolt.Tx, item HistoryItem) error {
+	root := tx.Bucket([]byte(historyIndexBucket))
+	if root == nil {
+		return nil
+	}
+	for name, keys := range historyIndexKeys(item) {
+		b := root.Bucket([]byte(name))
+		if b == nil {
+			continue
+		}
+		for _, key := range keys {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ensureHistoryIndex rebuilds the indexes when they were written by an older
+// version, or by a version that did not maintain them at all. Items are
+// upgraded to the current layout on the way through.
+func ensureHistoryIndex(tx *bolt.Tx) error {
+	if root := tx.Bucket([]byte(historyIndexBucket)); root != nil {
+		if v := root.Get(historyIndexVersionKey); v != nil && string(v) == strconv.Itoa(historyIndexVersion) {
+			return nil
+		}
+		if err := tx.DeleteBucket([]byte(historyIndexBucket)); err != nil {
+			return err
+		}
+	}
+
+	root, err := historyIndexes(tx)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	if b := tx.Bucket([]byte(historyBucket)); b != nil {
+		upgraded := make(map[string][]byte)
+		err := b.ForEach(func(k, v []byte) error {
+			if data, ok := upgradeHistoryItem(v); ok {
+				upgraded[string(k)] = data
+				v = data
+			}
+			var item HistoryItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return nil
+			}
+			count++
+			return indexHistoryItem(tx, item)
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range upgraded {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+	}
+	if count > 0 {
+		fmt.Printf("✓ Indexed %d history items\n", count)
+	}
+	return root.Put(historyIndexVersionKey, []byte(strconv.Itoa(historyIndexVersion)))
+}
+
+// upgradeHistoryItem moves the service name from the "source" field used by
+// earlier versions into "service".
+func upgradeHistoryItem(data []byte) ([]byte, bool) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false
+	}
+	source, ok := raw["source"]
+	if !ok {
+		return nil, false
+	}
+	delete(raw, "source")
+	if _, exists := raw["service"]; !exists {
+		raw["service"] = source
+	}
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false
+	}
+	return upgraded, true
+}
+
+// collectHistoryIDs returns the IDs of every entry in an index whose key
+// starts with prefix.
+func collectHistoryIDs(root *bolt.Bucket, index string, prefix []byte) map[string]bool {
+	ids := make(map[string]bool)
+	c := root.Bucket([]byte(index)).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		ids[string(v)] = true
+	}
+	return ids
+}
+
+func collectHistoryIDsInRange(root *bolt.Bucket, from, to int64) map[string]bool {
+	ids := make(map[string]bool)
+	c := root.Bucket([]byte(historyIndexTimestamp)).Cursor()
+	for k, v := c.Seek(historyNumberKey(from, "")); k != nil; k, v = c.Next() {
+		if to > 0 && int64(binary.BigEndian.Uint64(k[:8])) > to {
+			break
+		}
+		ids[string(v)] = true
+	}
+	return ids
+}
+
+func intersectHistoryIDs(a, b map[string]bool) map[string]bool {
+	if a == nil {
+		return b
+	}
+	for id := range a {
+		if !b[id] {
+			delete(a, id)
+		}
+	}
+	return a
+}
+
+func matchesHistoryText(item HistoryItem, terms []string) bool {
+	haystack := strings.ToLower(item.Title + "\n" + item.Artists + "\n" + item.Album)
+	for _, term := range terms {
+		if !strings.Contains(haystack, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func QueryHistoryItems(query HistoryQuery, appName string) (HistoryPage, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return HistoryPage{}, err
+		}
+	}
+
+	sortIndex := historyIndexTimestamp
+	switch query.SortBy {
+	case "", "timestamp", "date":
+	case "title":
+		sortIndex = historyIndexTitle
+	case "artist":
+		sortIndex = historyIndexArtists
+	case "album":
+		sortIndex = historyIndexAlbum
+	case "duration":
+		sortIndex = historyIndexDuration
+	default:
+		return HistoryPage{}, fmt.Errorf("unknown sort field: %s", query.SortBy)
+	}
+
+	desc := query.Order == "desc" || (query.Order == "" && sortIndex == historyIndexTimestamp)
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultHistoryPageSize
+	}
+	if limit > maxHistoryPageSize {
+		limit = maxHistoryPageSize
+	}
+
+	var cursorKey []byte
+	if query.Cursor != "" {
+		var err error
+		if cursorKey, err = base64.RawURLEncoding.DecodeString(query.Cursor); err != nil {
+			return HistoryPage{}, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+
+	terms := strings.Fields(strings.ToLower(query.Text))
+	page := HistoryPage{Items: []HistoryItem{}}
+
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		items := tx.Bucket([]byte(historyBucket))
+		root := tx.Bucket([]byte(historyIndexBucket))
+		if items == nil || root == nil {
+			return nil
+		}
+		page.Total = items.Stats().KeyN
+
+		var candidates map[string]bool
+		for index, value := range map[string]string{
+			historyIndexArtist:  query.Artist,
+			historyIndexAlbum:   query.Album,
+			historyIndexFormat:  query.Format,
+			historyIndexQuality: query.Quality,
+			historyIndexService: query.Service,
+		} {
+			if value != "" {
+				candidates = intersectHistoryIDs(candidates, collectHistoryIDs(root, index, historyTextPrefix(value)))
+			}
+		}
+		// The timestamp index bounds the scan itself; other sort orders
+		// need the date range resolved up front.
+		bounded := sortIndex == historyIndexTimestamp
+		if !bounded && (query.From > 0 || query.To > 0) {
+			candidates = intersectHistoryIDs(candidates, collectHistoryIDsInRange(root, query.From, query.To))
+		}
+		if candidates != nil && len(candidates) == 0 {
+			return nil
+		}
+
+		// Without text terms or a date-bounded scan, the candidates (or the
+		// whole history) are exactly the matches.
+		counting := cursorKey == nil
+		if counting && len(terms) == 0 && !(bounded && (query.From > 0 || query.To > 0)) {
+			if candidates == nil {
+				page.Matched = page.Total
+			} else {
+				page.Matched = len(candidates)
+			}
+			counting = false
+		}
+
+		c := root.Bucket([]byte(sortIndex)).Cursor()
+		var k, v []byte
+		switch {
+		case cursorKey != nil && desc:
+			if k, v = c.Seek(cursorKey); k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		case cursorKey != nil:
+			if k, v = c.Seek(cursorKey); k != nil && bytes.Equal(k, cursorKey) {
+				k, v = c.Next()
+			}
+		case desc && bounded && query.To > 0:
+			if k, v = c.Seek(historyNumberKey(query.To+1, "")); k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		case desc:
+			k, v = c.Last()
+		case bounded && query.From > 0:
+			k, v = c.Seek(historyNumberKey(query.From, ""))
+		default:
+			k, v = c.First()
+		}
+
+		var lastKey []byte
+		matched := 0
+		for ; k != nil; k, v = advanceHistoryCursor(c, desc) {
+			if bounded {
+				ts := int64(binary.BigEndian.Uint64(k[:8]))
+				if (desc && query.From > 0 && ts < query.From) || (!desc && query.To > 0 && ts > query.To) {
+					break
+				}
+			}
+			if candidates != nil && !candidates[string(v)] {
+				continue
+			}
+
+			data := items.Get(v)
+			if data == nil {
+				continue
+			}
+			var item HistoryItem
+			if err := json.Unmarshal(data, &item); err != nil {
+				continue
+			}
+			if len(terms) > 0 && !matchesHistoryText(item, terms) {
+				continue
+			}
+
+			if len(page.Items) == limit {
+				if page.NextCursor == "" {
+					page.NextCursor = base64.RawURLEncoding.EncodeToString(lastKey)
+				}
+				if !counting {
+					break
+				}
+				matched++
+				continue
+			}
+			page.Items = append(page.Items, item)
+			matched++
+			lastKey = append([]byte(nil), k...)
+		}
+		if counting {
+			page.Matched = matched
+		}
+		return nil
+	})
+
+	return page, err
+}
+
+func advanceHistoryCursor(c *bolt.Cursor, desc bool) ([]byte, []byte) {
+	if desc {
+		return c.Prev()
+	}
+	return c.Next()
+}
@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTestHistoryDB points historyDB at a fresh database in a temp dir for
+// the duration of the test.
+func openTestHistoryDB(t *testing.T) {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "history.db"), 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(historyBucket)); err != nil {
+			return err
+		}
+		return ensureHistoryIndex(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := historyDB
+	historyDB = db
+	t.Cleanup(func() {
+		historyDB = prev
+		db.Close()
+	})
+}
+
+// putTestHistory stores items as given, keeping their IDs and timestamps.
+func putTestHistory(t *testing.T, items ...HistoryItem) {
+	t.Helper()
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+		for _, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(item.ID), data); err != nil {
+				return err
+			}
+			if err := indexHistoryItem(tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func historyIDs(items []HistoryItem) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestQueryHistoryItemsPaging(t *testing.T) {
+	openTestHistoryDB(t)
+
+	artists := []string{"Alpha", "Beta, Alpha", "Gamma"}
+	formats := []string{"FLAC", "MP3"}
+	var items []HistoryItem
+	for i := 1; i <= 12; i++ {
+		items = append(items, HistoryItem{
+			ID:          fmt.Sprintf("item-%02d", i),
+			Title:       fmt.Sprintf("Song %02d", i),
+			Artists:     artists[i%len(artists)],
+			Album:       fmt.Sprintf("Album %d", i%2),
+			Format:      formats[i%len(formats)],
+			DurationStr: fmt.Sprintf("%d:00", 13-i),
+			Timestamp:   int64(1000 + i*10),
+		})
+	}
+	putTestHistory(t, items...)
+
+	tests := []struct {
+		name    string
+		query   HistoryQuery
+		want    []string
+		matched int
+	}{
+		{
+			name:    "newest first by default",
+			query:   HistoryQuery{Limit: 5},
+			want:    []string{"item-12", "item-11", "item-10", "item-09", "item-08", "item-07", "item-06", "item-05", "item-04", "item-03", "item-02", "item-01"},
+			matched: 12,
+		},
+		{
+			name:    "oldest first",
+			query:   HistoryQuery{Order: "asc", Limit: 4},
+			want:    []string{"item-01", "item-02", "item-03", "item-04", "item-05", "item-06", "item-07", "item-08", "item-09", "item-10", "item-11", "item-12"},
+			matched: 12,
+		},
+		{
+			name:    "by duration",
+			query:   HistoryQuery{SortBy: "duration", Limit: 3, Format: "flac"},
+			want:    []string{"item-12", "item-10", "item-08", "item-06", "item-04", "item-02"},
+			matched: 6,
+		},
+		{
+			name:    "artist filter matches every credited artist",
+			query:   HistoryQuery{Artist: "alpha", Limit: 3},
+			want:    []string{"item-12", "item-10", "item-09", "item-07", "item-06", "item-04", "item-03", "item-01"},
+			matched: 8,
+		},
+		{
+			name:    "date range on the timestamp index",
+			query:   HistoryQuery{From: 1030, To: 1070, Limit: 2},
+			want:    []string{"item-07", "item-06", "item-05", "item-04", "item-03"},
+			matched: 5,
+		},
+		{
+			name:    "date range with another sort",
+			query:   HistoryQuery{SortBy: "title", From: 1030, To: 1050, Limit: 2},
+			want:    []string{"item-03", "item-04", "item-05"},
+			matched: 3,
+		},
+		{
+			name:    "text search",
+			query:   HistoryQuery{Text: "gamma album 1", Limit: 2},
+			want:    []string{"item-11", "item-05"},
+			matched: 2,
+		},
+		{
+			name:    "no matches",
+			query:   HistoryQuery{Service: "tidal"},
+			want:    []string{},
+			matched: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			query := tt.query
+			for pages := 0; ; pages++ {
+				if pages > len(items) {
+					t.Fatal("paging did not terminate")
+				}
+				page, err := QueryHistoryItems(query, "test")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page.Total != len(items) {
+					t.Errorf("Total = %d, want %d", page.Total, len(items))
+				}
+				if query.Cursor == "" && page.Matched != tt.matched {
+					t.Errorf("Matched = %d, want %d", page.Matched, tt.matched)
+				}
+				if query.Limit > 0 && len(page.Items) > query.Limit {
+					t.Errorf("page has %d items, limit %d", len(page.Items), query.Limit)
+				}
+				got = append(got, historyIDs(page.Items)...)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryHistoryItemsRejectsBadInput(t *testing.T) {
+	openTestHistoryDB(t)
+
+	tests := []HistoryQuery{
+		{SortBy: "size"},
+		{Cursor: "not base64!"},
+	}
+	for _, query := range tests {
+		if _, err := QueryHistoryItems(query, "test"); err == nil {
+			t.Errorf("QueryHistoryItems(%+v) succeeded, want an error", query)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type HistoryRelink struct {
+	ID      string `json:"id"`
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	MatchBy string `json:"match_by"`
+}
+
+type HistoryRelinkResult struct {
+	Checked  int             `json:"checked"`
+	Present  int             `json:"present"`
+	Missing  int             `json:"missing"`
+	Relinked []HistoryRelink `json:"relinked"`
+}
+
+// findHistoryFile looks an item up in the library by its Spotify ID or ISRC
+// and, failing that, by title and artist as long as the file's own
+// identifiers do not contradict the item.
+func findHistoryFile(index *LibraryIndex, item HistoryItem) (string, string) {
+	if path, ok := index.Find("", item.SpotifyID); ok {
+		return path, "spotify_id"
+	}
+	if path, ok := index.Find(item.ISRC, ""); ok {
+		return path, "isrc"
+	}
+	if path, ok := index.FindByTags(item.Title, item.Artists); ok {
+		if !index.Identity(path).Conflicts(item.ISRC, item.SpotifyID) {
+			return path, "tags"
+		}
+	}
+	return "", ""
+}
+
+// RelinkHistory checks that every download history path still exists. Items
+// whose file is gone are searched for under libraryRoot and pointed at the
+// file found there; the rest are flagged as missing. Items that turn up
+// again have the flag cleared.
+func RelinkHistory(libraryRoot string, appName string) (HistoryRelinkResult, error) {
+	result := HistoryRelinkResult{Relinked: []HistoryRelink{}}
+
+	items, err := GetHistoryItems(appName)
+	if err != nil {
+		return result, err
+	}
+
+	updates := make(map[string]HistoryItem)
+	var missing []HistoryItem
+	for _, item := range items {
+		result.Checked++
+		if item.Path != "" {
+			if _, err := os.Stat(item.Path); err == nil {
+				result.Present++
+				if item.Missing {
+					item.Missing = false
+					updates[item.ID] = item
+				}
+				continue
+			}
+		}
+		missing = append(missing, item)
+	}
+
+	if len(missing) > 0 {
+		var index *LibraryIndex
+		if libraryRoot != "" {
+			fmt.Printf("Scanning %s for %d missing history items...\n", libraryRoot, len(missing))
+			index = BuildLibraryIndex(true, libraryRoot)
+		}
+		for _, item := range missing {
+			if index != nil {
+				if path, matchBy := findHistoryFile(index, item); path != "" {
+					result.Relinked = append(result.Relinked, HistoryRelink{ID: item.ID, OldPath: item.Path, NewPath: path, MatchBy: matchBy})
+					item.Path = path
+					item.Missing = false
+					updates[item.ID] = item
+					continue
+				}
+			}
+			result.Missing++
+			if !item.Missing {
+				item.Missing = true
+				updates[item.ID] = item
+			}
+		}
+	}
+
+	if len(updates) == 0 {
+		return result, nil
+	}
+
+	err = historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+		if b == nil {
+			return nil
+		}
+		for id, item := range updates {
+			// Skip items deleted while the library was being scanned.
+			if b.Get([]byte(id)) == nil {
+				continue
+			}
+			buf, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(id), buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	fmt.Printf("✓ Relinked %d history items, %d still missing\n", len(result.Relinked), result.Missing)
+	return result, nil
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testLibraryIndex(files map[string]TrackIdentity) *LibraryIndex {
+	index := &LibraryIndex{
+		byISRC:      make(map[string]string),
+		bySpotifyID: make(map[string]string),
+		byFilename:  make(map[string]string),
+		byTags:      make(map[string]string),
+		identities:  make(map[string]TrackIdentity),
+	}
+	for path, identity := range files {
+		index.add(path, identity)
+	}
+	return index
+}
+
+func TestFindHistoryFile(t *testing.T) {
+	index := testLibraryIndex(map[string]TrackIdentity{
+		"/lib/a.flac":  {SpotifyID: "sp-a", ISRC: "USAAA0000001", Title: "Song A", Artist: "Alpha"},
+		"/lib/b.flac":  {ISRC: "USBBB0000002", Title: "Song B", Artist: "Beta"},
+		"/lib/c.flac":  {Title: "Song C", Artist: "Gamma"},
+		"/lib/d1.flac": {Title: "Intro", Artist: "Delta"},
+		"/lib/d2.flac": {Title: "Intro", Artist: "Delta"},
+		"/lib/e.flac":  {ISRC: "USEEE0000005", Title: "Song E", Artist: "Epsilon"},
+	})
+
+	tests := []struct {
+		name     string
+		item     HistoryItem
+		wantPath string
+		wantBy   string
+	}{
+		{"spotify id", HistoryItem{SpotifyID: "sp-a", Title: "Renamed"}, "/lib/a.flac", "spotify_id"},
+		{"isrc ignores case", HistoryItem{SpotifyID: "sp-other", ISRC: "usbbb0000002"}, "/lib/b.flac", "isrc"},
+		{"tags use the first artist", HistoryItem{Title: "song c", Artists: "GAMMA, Omega"}, "/lib/c.flac", "tags"},
+		{"tags without identifiers on the file", HistoryItem{SpotifyID: "sp-c", ISRC: "USCCC0000003", Title: "Song C", Artists: "Gamma"}, "/lib/c.flac", "tags"},
+		{"ambiguous tags", HistoryItem{Title: "Intro", Artists: "Delta"}, "", ""},
+		{"tags with a conflicting isrc", HistoryItem{ISRC: "USXXX0000009", Title: "Song E", Artists: "Epsilon"}, "", ""},
+		{"tags with a conflicting spotify id", HistoryItem{SpotifyID: "sp-b", Title: "Song A", Artists: "Alpha"}, "", ""},
+		{"unknown", HistoryItem{SpotifyID: "sp-z", ISRC: "USZZZ0000000", Title: "Nothing", Artists: "Nobody"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, by := findHistoryFile(index, tt.item)
+			if path != tt.wantPath || by != tt.wantBy {
+				t.Errorf("findHistoryFile() = %q, %q; want %q, %q", path, by, tt.wantPath, tt.wantBy)
+			}
+		})
+	}
+}
+
+func TestRelinkHistory(t *testing.T) {
+	openTestHistoryDB(t)
+
+	dir := t.TempDir()
+	present := filepath.Join(dir, "present.flac")
+	if err := os.WriteFile(present, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "Music")
+	moved := filepath.Join(root, "Alpha", "Moved.flac")
+	writeTaggedFLAC(t, moved, "TITLE", "Moved", "ARTIST", "Alpha", "ISRC", "USAAA0000001")
+
+	putTestHistory(t,
+		HistoryItem{ID: "present", Title: "Present", Path: present, Missing: true, Timestamp: 4},
+		HistoryItem{ID: "moved", Title: "Moved", Artists: "Alpha", ISRC: "USAAA0000001", Path: filepath.Join(dir, "old", "Moved.flac"), Timestamp: 3},
+		HistoryItem{ID: "gone", Title: "Gone", Artists: "Beta", Path: filepath.Join(dir, "Gone.flac"), Timestamp: 2},
+		HistoryItem{ID: "nopath", Title: "No Path", Timestamp: 1},
+	)
+
+	result, err := RelinkHistory(root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Checked != 4 || result.Present != 1 || result.Missing != 2 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.Relinked) != 1 || result.Relinked[0].ID != "moved" || result.Relinked[0].NewPath != moved || result.Relinked[0].MatchBy != "isrc" {
+		t.Errorf("relinked = %+v", result.Relinked)
+	}
+
+	items, err := GetHistoryItems("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		path    string
+		missing bool
+	}{
+		"present": {present, false},
+		"moved":   {moved, false},
+		"gone":    {filepath.Join(dir, "Gone.flac"), true},
+		"nopath":  {"", true},
+	}
+	for _, item := range items {
+		w := want[item.ID]
+		if item.Path != w.path || item.Missing != w.missing {
+			t.Errorf("%s: path %q missing %v, want %q %v", item.ID, item.Path, item.Missing, w.path, w.missing)
+		}
+	}
+
+	// Without a library root nothing is searched for, and items already
+	// flagged are left as they are.
+	os.Remove(present)
+	result, err = RelinkHistory("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Present != 1 || result.Missing != 3 || len(result.Relinked) != 0 {
+		t.Errorf("second result = %+v", result)
+	}
+}
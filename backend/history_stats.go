@@ -0,0 +1,184 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type HistoryStatsEntry struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+type HistoryStats struct {
+	Range  string `json:"range"`
+	From   int64  `json:"from,omitempty"`
+	To     int64  `json:"to"`
+	Tracks int    `json:"tracks"`
+	Bytes  int64  `json:"bytes"`
+
+	Formats      []HistoryStatsEntry `json:"formats"`
+	QualityTiers []HistoryStatsEntry `json:"quality_tiers"`
+	Services     []HistoryStatsEntry `json:"services"`
+	Artists      []HistoryStatsEntry `json:"artists"`
+	Months       []HistoryStatsEntry `json:"months"`
+}
+
+// HistoryStatsRange resolves a range name to a start time. Accepted names
+// are "all" (or empty), "year" and "month" for the current calendar period,
+// and a day count such as "7d" or "30d".
+func HistoryStatsRange(name string, now time.Time) (int64, error) {
+	switch name {
+	case "", "all":
+		return 0, nil
+	case "year":
+		return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()).Unix(), nil
+	case "month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Unix(), nil
+	}
+	if days, err := strconv.Atoi(strings.TrimSuffix(name, "d")); err == nil && strings.HasSuffix(name, "d") && days > 0 {
+		return now.AddDate(0, 0, -days).Unix(), nil
+	}
+	return 0, fmt.Errorf("unknown stats range: %s", name)
+}
+
+// HistoryQualityTier groups a recorded quality such as "24-bit/96.0kHz" into
+// 16/44.1, 24/48 or 24/96+ (anything at 88.2kHz or above). Lossy and
+// unparseable entries are reported as such.
+func HistoryQualityTier(quality string) string {
+	if strings.Contains(quality, "kbps") {
+		return "Lossy"
+	}
+
+	var bits int
+	var khz float64
+	if _, err := fmt.Sscanf(quality, "%d-bit/%fkHz", &bits, &khz); err != nil {
+		return "Unknown"
+	}
+	switch {
+	case bits <= 16:
+		return "16/44.1"
+	case khz >= 88.2:
+		return "24/96+"
+	default:
+		return "24/48"
+	}
+}
+
+type historyStatsCounter map[string]*HistoryStatsEntry
+
+func (c historyStatsCounter) add(name string, size int64) {
+	if name == "" {
+		name = "Unknown"
+	}
+	entry, ok := c[name]
+	if !ok {
+		entry = &HistoryStatsEntry{Name: name}
+		c[name] = entry
+	}
+	entry.Count++
+	entry.Bytes += size
+}
+
+// sorted returns the entries by descending count, or by name when byName is
+// set.
+func (c historyStatsCounter) sorted(byName bool) []HistoryStatsEntry {
+	entries := make([]HistoryStatsEntry, 0, len(c))
+	for _, entry := range c {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !byName && entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// GetHistoryStats aggregates the download history from the given time
+// onwards. Items recorded before file sizes were tracked fall back to the
+// size of the file currently on disk.
+func GetHistoryStats(rangeName string, appName string) (HistoryStats, error) {
+	now := time.Now()
+	stats := HistoryStats{Range: rangeName, To: now.Unix()}
+
+	from, err := HistoryStatsRange(rangeName, now)
+	if err != nil {
+		return stats, err
+	}
+	stats.From = from
+
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return stats, err
+		}
+	}
+
+	var items []HistoryItem
+	err = historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+		root := tx.Bucket([]byte(historyIndexBucket))
+		if b == nil || root == nil {
+			return nil
+		}
+		c := root.Bucket([]byte(historyIndexTimestamp)).Cursor()
+		for k, id := c.Seek(historyNumberKey(from, "")); k != nil; k, id = c.Next() {
+			var item HistoryItem
+			if data := b.Get(id); data != nil && json.Unmarshal(data, &item) == nil {
+				items = append(items, item)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	formats := make(historyStatsCounter)
+	tiers := make(historyStatsCounter)
+	services := make(historyStatsCounter)
+	artists := make(historyStatsCounter)
+	months := make(historyStatsCounter)
+
+	// Files are only stat'ed once the read transaction is closed; a long
+	// read transaction keeps bbolt from remapping the file as it grows.
+	for _, item := range items {
+		size := item.Size
+		if size == 0 && item.Path != "" {
+			if info, err := os.Stat(item.Path); err == nil {
+				size = info.Size()
+			}
+		}
+
+		stats.Tracks++
+		stats.Bytes += size
+		formats.add(item.Format, size)
+		tiers.add(HistoryQualityTier(item.Quality), size)
+		services.add(item.Service, size)
+		months.add(time.Unix(item.Timestamp, 0).Format("2006-01"), size)
+
+		names := splitHistoryArtists(item.Artists)
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, name := range names {
+			artists.add(name, size)
+		}
+	}
+
+	stats.Formats = formats.sorted(false)
+	stats.QualityTiers = tiers.sorted(false)
+	stats.Services = services.sorted(false)
+	stats.Artists = artists.sorted(false)
+	stats.Months = months.sorted(true)
+	return stats, nil
+}
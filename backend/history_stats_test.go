@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryStatsRange(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"all", 0, false},
+		{"year", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), false},
+		{"month", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).Unix(), false},
+		{"7d", now.AddDate(0, 0, -7).Unix(), false},
+		{"30d", now.AddDate(0, 0, -30).Unix(), false},
+		{"0d", 0, true},
+		{"-3d", 0, true},
+		{"30", 0, true},
+		{"week", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := HistoryStatsRange(tt.name, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("HistoryStatsRange(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("HistoryStatsRange(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryQualityTier(t *testing.T) {
+	tests := []struct {
+		quality string
+		want    string
+	}{
+		{"16-bit/44.1kHz", "16/44.1"},
+		{"16-bit/48.0kHz", "16/44.1"},
+		{"24-bit/44.1kHz", "24/48"},
+		{"24-bit/48.0kHz", "24/48"},
+		{"24-bit/88.2kHz", "24/96+"},
+		{"24-bit/192.0kHz", "24/96+"},
+		{"320kbps", "Lossy"},
+		{"MP3 320kbps", "Lossy"},
+		{"", "Unknown"},
+		{"HI_RES_LOSSLESS", "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := HistoryQualityTier(tt.quality); got != tt.want {
+			t.Errorf("HistoryQualityTier(%q) = %q, want %q", tt.quality, got, tt.want)
+		}
+	}
+}
+
+func TestGetHistoryStats(t *testing.T) {
+	openTestHistoryDB(t)
+
+	dir := t.TempDir()
+	onDisk := filepath.Join(dir, "old.flac")
+	if err := os.WriteFile(onDisk, make([]byte, 300), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	putTestHistory(t,
+		HistoryItem{ID: "a", Artists: "Alpha, Beta", Format: "FLAC", Quality: "24-bit/96.0kHz", Service: "qobuz", Size: 1000, Timestamp: now.Add(-time.Hour).Unix()},
+		HistoryItem{ID: "b", Artists: "Alpha", Format: "FLAC", Quality: "16-bit/44.1kHz", Service: "tidal", Path: onDisk, Timestamp: now.Add(-48 * time.Hour).Unix()},
+		HistoryItem{ID: "c", Artists: "Gamma", Format: "MP3", Quality: "320kbps", Service: "tidal", Size: 50, Timestamp: now.AddDate(0, 0, -40).Unix()},
+	)
+
+	tests := []struct {
+		rangeName string
+		tracks    int
+		bytes     int64
+		artists   map[string]int
+		services  map[string]int
+	}{
+		{"all", 3, 1350, map[string]int{"Alpha": 2, "Beta": 1, "Gamma": 1}, map[string]int{"tidal": 2, "qobuz": 1}},
+		{"30d", 2, 1300, map[string]int{"Alpha": 2, "Beta": 1}, map[string]int{"tidal": 1, "qobuz": 1}},
+		{"1d", 1, 1000, map[string]int{"Alpha": 1, "Beta": 1}, map[string]int{"qobuz": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rangeName, func(t *testing.T) {
+			stats, err := GetHistoryStats(tt.rangeName, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Tracks != tt.tracks || stats.Bytes != tt.bytes {
+				t.Errorf("tracks, bytes = %d, %d; want %d, %d", stats.Tracks, stats.Bytes, tt.tracks, tt.bytes)
+			}
+			checkStatsCounts(t, "artists", stats.Artists, tt.artists)
+			checkStatsCounts(t, "services", stats.Services, tt.services)
+		})
+	}
+
+	if _, err := GetHistoryStats("fortnight", "test"); err == nil {
+		t.Error("expected an error for an unknown range")
+	}
+}
+
+func checkStatsCounts(t *testing.T, label string, entries []HistoryStatsEntry, want map[string]int) {
+	t.Helper()
+	if len(entries) != len(want) {
+		t.Errorf("%s = %+v, want %v", label, entries, want)
+		return
+	}
+	for _, entry := range entries {
+		if want[entry.Name] != entry.Count {
+			t.Errorf("%s[%s] = %d, want %d", label, entry.Name, entry.Count, want[entry.Name])
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package backend
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const historyExportVersion = 2
+
+var historyCSVHeader = []string{
+	"kind", "timestamp", "spotify_id", "title", "artists", "album", "duration", "quality", "format",
+	"path", "relative_path", "service", "source_reason", "isrc", "size", "cover_url",
+	"api", "source_id", "source_quality", "requested_quality",
+	"url", "type", "name", "info", "image", "data",
+}
+
+// HistoryExportItem carries the path relative to the exporting machine's
+// music folder so it can be rebuilt under another root on import.
+type HistoryExportItem struct {
+	HistoryItem
+	RelativePath string `json:"relative_path,omitempty"`
+}
+
+type HistoryExport struct {
+	Version    int                 `json:"version"`
+	ExportedAt string              `json:"exported_at"`
+	MusicRoot  string              `json:"music_root,omitempty"`
+	Downloads  []HistoryExportItem `json:"downloads"`
+	Fetches    []FetchHistoryItem  `json:"fetches"`
+}
+
+type HistoryImportResult struct {
+	Downloads int `json:"downloads"`
+	Fetches   int `json:"fetches"`
+	Skipped   int `json:"skipped"`
+}
+
+func ExportHistory(musicRoot string, appName string) (HistoryExport, error) {
+	export := HistoryExport{
+		Version:    historyExportVersion,
+		ExportedAt: time.Now().Format(time.RFC3339),
+		MusicRoot:  musicRoot,
+		Downloads:  []HistoryExportItem{},
+		Fetches:    []FetchHistoryItem{},
+	}
+
+	downloads, err := GetHistoryItems(appName)
+	if err != nil {
+		return export, err
+	}
+	for _, item := range downloads {
+		entry := HistoryExportItem{HistoryItem: item}
+		if musicRoot != "" && item.Path != "" {
+			if rel, err := filepath.Rel(musicRoot, item.Path); err == nil && !strings.HasPrefix(rel, "..") {
+				entry.RelativePath = filepath.ToSlash(rel)
+			}
+		}
+		export.Downloads = append(export.Downloads, entry)
+	}
+
+	fetches, err := GetFetchHistoryItems(appName)
+	if err != nil {
+		return export, err
+	}
+	export.Fetches = append(export.Fetches, fetches...)
+	return export, nil
+}
+
+func WriteHistoryExport(path string, export HistoryExport) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	case ".csv":
+		return writeHistoryCSV(path, export)
+	default:
+		return fmt.Errorf("unsupported history format: %s", filepath.Ext(path))
+	}
+}
+
+func writeHistoryCSV(path string, export HistoryExport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(historyCSVHeader); err != nil {
+		return err
+	}
+
+	row := func(values map[string]string) []string {
+		record := make([]string, len(historyCSVHeader))
+		for i, name := range historyCSVHeader {
+			record[i] = values[name]
+		}
+		return record
+	}
+
+	for _, item := range export.Downloads {
+		if err := w.Write(row(map[string]string{
+			"kind":          "download",
+			"timestamp":     strconv.FormatInt(item.Timestamp, 10),
+			"spotify_id":    item.SpotifyID,
+			"title":         item.Title,
+			"artists":       item.Artists,
+			"album":         item.Album,
+			"duration":      item.DurationStr,
+			"quality":       item.Quality,
+			"format":        item.Format,
+			"path":          item.Path,
+			"relative_path": item.RelativePath,
+			"service":       item.Service,
+			"source_reason": item.SourceReason,
+			"isrc":          item.ISRC,
+			"size":          strconv.FormatInt(item.Size, 10),
+			"cover_url":     item.CoverURL,
+
+			"api":               item.API,
+			"source_id":         item.SourceID,
+			"source_quality":    item.SourceQuality,
+			"requested_quality": item.RequestedQuality,
+		})); err != nil {
+			return err
+		}
+	}
+	for _, item := range export.Fetches {
+		if err := w.Write(row(map[string]string{
+			"kind":      "fetch",
+			"timestamp": strconv.FormatInt(item.Timestamp, 10),
+			"url":       item.URL,
+			"type":      item.Type,
+			"name":      item.Name,
+			"info":      item.Info,
+			"image":     item.Image,
+			"data":      item.Data,
+		})); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func ReadHistoryExport(path string) (HistoryExport, error) {
+	var export HistoryExport
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return export, err
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return export, fmt.Errorf("invalid history file: %w", err)
+		}
+		if export.Version > historyExportVersion {
+			return export, fmt.Errorf("history file version %d is newer than supported version %d", export.Version, historyExportVersion)
+		}
+		return export, nil
+	case ".csv":
+		return readHistoryCSV(path)
+	default:
+		return export, fmt.Errorf("unsupported history format: %s", filepath.Ext(path))
+	}
+}
+
+func readHistoryCSV(path string) (HistoryExport, error) {
+	var export HistoryExport
+
+	f, err := os.Open(path)
+	if err != nil {
+		return export, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return export, fmt.Errorf("invalid history file: %w", err)
+	}
+	if len(records) == 0 {
+		return export, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["kind"]; !ok {
+		return export, fmt.Errorf("invalid history file: missing kind column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	for line, record := range records[1:] {
+		timestamp, _ := strconv.ParseInt(field(record, "timestamp"), 10, 64)
+		switch field(record, "kind") {
+		case "download":
+			service := field(record, "service")
+			if service == "" {
+				service = field(record, "source")
+			}
+			size, _ := strconv.ParseInt(field(record, "size"), 10, 64)
+			export.Downloads = append(export.Downloads, HistoryExportItem{
+				HistoryItem: HistoryItem{
+					SpotifyID:    field(record, "spotify_id"),
+					Title:        field(record, "title"),
+					Artists:      field(record, "artists"),
+					Album:        field(record, "album"),
+					DurationStr:  field(record, "duration"),
+					CoverURL:     field(record, "cover_url"),
+					Quality:      field(record, "quality"),
+					Format:       field(record, "format"),
+					Path:         field(record, "path"),
+					Timestamp:    timestamp,
+					Service:      service,
+					SourceReason: field(record, "source_reason"),
+					ISRC:         field(record, "isrc"),
+					Size:         size,
+
+					API:              field(record, "api"),
+					SourceID:         field(record, "source_id"),
+					SourceQuality:    field(record, "source_quality"),
+					RequestedQuality: field(record, "requested_quality"),
+				},
+				RelativePath: field(record, "relative_path"),
+			})
+		case "fetch":
+			export.Fetches = append(export.Fetches, FetchHistoryItem{
+				URL:       field(record, "url"),
+				Type:      field(record, "type"),
+				Name:      field(record, "name"),
+				Info:      field(record, "info"),
+				Image:     field(record, "image"),
+				Data:      field(record, "data"),
+				Timestamp: timestamp,
+			})
+		default:
+			return export, fmt.Errorf("invalid history file: unknown kind on line %d", line+2)
+		}
+	}
+	return export, nil
+}
+
+func historyIdentity(item HistoryItem) string {
+	if item.SpotifyID != "" {
+		return item.SpotifyID
+	}
+	return strings.ToLower(item.Title + "\x00" + item.Artists)
+}
+
+func historyMergeKey(item HistoryItem) string {
+	return fmt.Sprintf("%s\x00%d", historyIdentity(item), item.Timestamp)
+}
+
+func historyItemID(timestamp int64, seq uint64) string {
+	return fmt.Sprintf("%d-%d", time.Unix(timestamp, 0).UnixNano(), seq)
+}
+
+// ImportHistory merges an export into the local history. Downloads already
+// present with the same Spotify ID and timestamp are skipped, and fetches
+// keep whichever copy is newer. Downloads without a timestamp are skipped
+// when the track is in the history at all. Paths recorded relative to the
+// exporting music folder are rebuilt under musicRoot. Imported downloads
+// older than everything the history limit keeps are counted as skipped.
+func ImportHistory(export HistoryExport, musicRoot string, appName string) (HistoryImportResult, error) {
+	var result HistoryImportResult
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return result, err
+		}
+	}
+
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(historyBucket))
+		if err != nil {
+			return err
+		}
+
+		existing := make(map[string]bool)
+		known := make(map[string]bool)
+		count := 0
+		b.ForEach(func(k, v []byte) error {
+			count++
+			var item HistoryItem
+			if json.Unmarshal(v, &item) == nil {
+				existing[historyMergeKey(item)] = true
+				known[historyIdentity(item)] = true
+			}
+			return nil
+		})
+
+		var imported []string
+		for _, entry := range export.Downloads {
+			item := entry.HistoryItem
+			if item.Timestamp == 0 {
+				// Without a timestamp a re-import can't be told apart from
+				// a new download, so any copy of the track counts.
+				if known[historyIdentity(item)] {
+					result.Skipped++
+					continue
+				}
+				item.Timestamp = time.Now().Unix()
+			}
+			key := historyMergeKey(item)
+			if existing[key] {
+				result.Skipped++
+				continue
+			}
+			existing[key] = true
+			known[historyIdentity(item)] = true
+
+			if entry.RelativePath != "" && musicRoot != "" {
+				item.Path = filepath.Join(musicRoot, filepath.FromSlash(entry.RelativePath))
+			}
+
+			seq, _ := b.NextSequence()
+			item.ID = historyItemID(item.Timestamp, seq)
+			buf, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(item.ID), buf); err != nil {
+				return err
+			}
+			if err := indexHistoryItem(tx, item); err != nil {
+				return err
+			}
+			imported = append(imported, item.ID)
+		}
+
+		if excess := count + len(imported) - maxHistory; excess > 0 {
+			if err := evictOldestHistory(tx, b, excess); err != nil {
+				return err
+			}
+		}
+		for _, id := range imported {
+			if b.Get([]byte(id)) == nil {
+				result.Skipped++
+			} else {
+				result.Downloads++
+			}
+		}
+
+		fb, err := tx.CreateBucketIfNotExists([]byte(fetchHistoryBucket))
+		if err != nil {
+			return err
+		}
+
+		type fetchEntry struct {
+			key  []byte
+			item FetchHistoryItem
+		}
+		fetches := make(map[string]fetchEntry)
+		fb.ForEach(func(k, v []byte) error {
+			var item FetchHistoryItem
+			if json.Unmarshal(v, &item) == nil {
+				fetches[item.Type+"\x00"+item.URL] = fetchEntry{key: append([]byte(nil), k...), item: item}
+			}
+			return nil
+		})
+
+		for _, item := range export.Fetches {
+			if item.Timestamp == 0 {
+				item.Timestamp = time.Now().Unix()
+			}
+			key := item.Type + "\x00" + item.URL
+			if current, ok := fetches[key]; ok {
+				if current.item.Timestamp >= item.Timestamp {
+					result.Skipped++
+					continue
+				}
+				if err := fb.Delete(current.key); err != nil {
+					return err
+				}
+			}
+
+			seq, _ := fb.NextSequence()
+			item.ID = historyItemID(item.Timestamp, seq)
+			buf, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if err := fb.Put([]byte(item.ID), buf); err != nil {
+				return err
+			}
+			fetches[key] = fetchEntry{key: []byte(item.ID), item: item}
+			result.Fetches++
+		}
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testHistoryExport() HistoryExport {
+	return HistoryExport{
+		Version:   historyExportVersion,
+		MusicRoot: "/old/Music",
+		Downloads: []HistoryExportItem{
+			{
+				HistoryItem: HistoryItem{
+					SpotifyID: "sp1", Title: "One, Two", Artists: "Alpha; Beta", Album: "Album \"A\"",
+					DurationStr: "3:05", Quality: "24-bit/96.0kHz", Format: "FLAC",
+					Path: "/old/Music/Alpha/One.flac", Timestamp: 1700000000,
+					Service: "qobuz", SourceReason: "best quality", ISRC: "USRC17607839", Size: 1234,
+					API: "https://api.example", SourceID: "42", SourceQuality: "27", RequestedQuality: "27",
+				},
+				RelativePath: "Alpha/One.flac",
+			},
+			{
+				HistoryItem: HistoryItem{Title: "Loose", Artists: "Gamma", Path: "/elsewhere/Loose.mp3", Timestamp: 1700000100},
+			},
+		},
+		Fetches: []FetchHistoryItem{
+			{URL: "https://open.spotify.com/album/x", Type: "album", Name: "X", Info: "10 tracks", Data: `{"a":1}`, Timestamp: 1700000200},
+		},
+	}
+}
+
+func TestHistoryExportRoundTrip(t *testing.T) {
+	for _, ext := range []string{".json", ".csv"} {
+		t.Run(ext, func(t *testing.T) {
+			export := testHistoryExport()
+			path := filepath.Join(t.TempDir(), "history"+ext)
+			if err := WriteHistoryExport(path, export); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadHistoryExport(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Downloads, export.Downloads) {
+				t.Errorf("downloads = %+v, want %+v", got.Downloads, export.Downloads)
+			}
+			if !reflect.DeepEqual(got.Fetches, export.Fetches) {
+				t.Errorf("fetches = %+v, want %+v", got.Fetches, export.Fetches)
+			}
+		})
+	}
+}
+
+func TestReadHistoryExportRejects(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"newer.json", `{"version": 99, "downloads": []}`},
+		{"broken.json", `{"version": `},
+		{"kind.csv", "kind,title\nplaylist,X\n"},
+		{"nokind.csv", "title,artists\nX,Y\n"},
+		{"history.txt", "x"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		os.WriteFile(path, []byte(tt.content), 0644)
+		if _, err := ReadHistoryExport(path); err == nil {
+			t.Errorf("ReadHistoryExport(%s) succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestImportHistory(t *testing.T) {
+	openTestHistoryDB(t)
+	putTestHistory(t, HistoryItem{ID: "existing", SpotifyID: "sp1", Title: "One, Two", Timestamp: 1700000000})
+	if err := AddFetchHistoryItem(FetchHistoryItem{URL: "https://open.spotify.com/album/x", Type: "album", Name: "X"}, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	export := testHistoryExport()
+	export.Downloads = append(export.Downloads, HistoryExportItem{
+		HistoryItem:  HistoryItem{SpotifyID: "sp2", Title: "Three", Timestamp: 1700000300},
+		RelativePath: "Delta/Three.flac",
+	})
+	export.Fetches = append(export.Fetches, FetchHistoryItem{URL: "https://open.spotify.com/playlist/y", Type: "playlist", Name: "Y", Timestamp: 1700000400})
+
+	result, err := ImportHistory(export, "/new/Music", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sp1 is already present, and the album fetch recorded above is newer
+	// than the exported one.
+	want := HistoryImportResult{Downloads: 2, Fetches: 1, Skipped: 2}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	items, err := GetHistoryItems("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[string]string)
+	for _, item := range items {
+		paths[item.Title] = item.Path
+	}
+	wantPaths := map[string]string{
+		"One, Two": "",
+		"Loose":    "/elsewhere/Loose.mp3",
+		"Three":    filepath.Join("/new/Music", "Delta", "Three.flac"),
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("paths = %v, want %v", paths, wantPaths)
+	}
+
+	page, err := QueryHistoryItems(HistoryQuery{Artist: "gamma"}, "test")
+	if err != nil || len(page.Items) != 1 {
+		t.Errorf("imported item not indexed: %+v, %v", page.Items, err)
+	}
+
+	// Importing the same file again adds nothing.
+	again, err := ImportHistory(export, "/new/Music", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Downloads != 0 || again.Fetches != 0 {
+		t.Errorf("second import = %+v, want nothing new", again)
+	}
+
+	fetches, err := GetFetchHistoryItems("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range fetches {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"X", "Y"}) {
+		t.Errorf("fetches = %v", names)
+	}
+}
+
+func TestImportHistoryWithoutTimestamp(t *testing.T) {
+	openTestHistoryDB(t)
+	putTestHistory(t, HistoryItem{ID: "existing", SpotifyID: "sp1", Title: "One", Timestamp: 1700000000})
+
+	export := HistoryExport{Version: historyExportVersion, Downloads: []HistoryExportItem{
+		{HistoryItem: HistoryItem{SpotifyID: "sp1", Title: "One"}},
+		{HistoryItem: HistoryItem{SpotifyID: "sp2", Title: "Two"}},
+		{HistoryItem: HistoryItem{Title: "Three", Artists: "Gamma"}},
+	}}
+	for i, want := range []HistoryImportResult{{Downloads: 2, Skipped: 1}, {Skipped: 3}} {
+		result, err := ImportHistory(export, "", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != want {
+			t.Errorf("import %d = %+v, want %+v", i+1, result, want)
+		}
+	}
+
+	items, err := GetHistoryItems("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Errorf("history has %d items, want 3", len(items))
+	}
+}
+
+func TestImportHistoryIntoFullHistory(t *testing.T) {
+	openTestHistoryDB(t)
+	items := make([]HistoryItem, maxHistory)
+	for i := range items {
+		ts := int64(1700000000 + i)
+		items[i] = HistoryItem{ID: historyItemID(ts, uint64(i)), SpotifyID: fmt.Sprintf("sp%d", i), Timestamp: ts}
+	}
+	putTestHistory(t, items...)
+
+	export := HistoryExport{Version: historyExportVersion, Downloads: []HistoryExportItem{
+		{HistoryItem: HistoryItem{SpotifyID: "old", Title: "Old", Timestamp: 1600000000}},
+		{HistoryItem: HistoryItem{SpotifyID: "new", Title: "New", Timestamp: 1800000000}},
+	}}
+	result, err := ImportHistory(export, "", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The old item is evicted straight away, so it isn't reported as added.
+	if want := (HistoryImportResult{Downloads: 1, Skipped: 1}); result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	page, err := QueryHistoryItems(HistoryQuery{Text: "New"}, "test")
+	if err != nil || len(page.Items) != 1 {
+		t.Errorf("new item missing: %+v, %v", page.Items, err)
+	}
+}
+
+func TestExportHistoryRelativePaths(t *testing.T) {
+	openTestHistoryDB(t)
+	root := filepath.Join(t.TempDir(), "Music")
+	putTestHistory(t,
+		HistoryItem{ID: "a", Title: "Inside", Path: filepath.Join(root, "Artist", "Inside.flac"), Timestamp: 2},
+		HistoryItem{ID: "b", Title: "Outside", Path: filepath.Join(filepath.Dir(root), "Other", "Outside.flac"), Timestamp: 1},
+	)
+
+	export, err := ExportHistory(root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, item := range export.Downloads {
+		got[item.Title] = item.RelativePath
+	}
+	want := map[string]string{"Inside": "Artist/Inside.flac", "Outside": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("relative paths = %v, want %v", got, want)
+	}
+}
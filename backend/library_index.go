@@ -0,0 +1,375 @@
+package backend
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+)
+
+var spotifyTrackTagPattern = regexp.MustCompile(`(?:open\.spotify\.com/(?:intl-[a-z-]+/)?track/|spotify:track:)([A-Za-z0-9]{22})`)
+
+// TrackIdentity holds the tags that identify a recording independently of
+// its filename. Title and Artist are only used as a last resort, since they
+// do not tell apart different versions of a track.
+type TrackIdentity struct {
+	ISRC      string `json:"isrc,omitempty"`
+	SpotifyID string `json:"spotify_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Artist    string `json:"artist,omitempty"`
+}
+
+// Matches reports whether the file tags positively identify the track.
+func (t TrackIdentity) Matches(isrc, spotifyID string) bool {
+	if t.SpotifyID != "" && t.SpotifyID == spotifyID {
+		return true
+	}
+	return t.ISRC != "" && strings.EqualFold(t.ISRC, isrc)
+}
+
+// Conflicts reports whether the tags identify a different recording, i.e.
+// an identifier is known on both sides and none of them agree.
+func (t TrackIdentity) Conflicts(isrc, spotifyID string) bool {
+	if t.Matches(isrc, spotifyID) {
+		return false
+	}
+	return (t.SpotifyID != "" && spotifyID != "") || (t.ISRC != "" && isrc != "")
+}
+
+type cachedTrackIdentity struct {
+	size     int64
+	modTime  time.Time
+	identity TrackIdentity
+}
+
+var (
+	trackIdentityCache     = make(map[string]cachedTrackIdentity)
+	trackIdentityCacheLock sync.RWMutex
+)
+
+// ReadTrackIdentity returns the identifying tags of an audio file.
+// Results are cached until the file's size or modification time changes.
+func ReadTrackIdentity(path string) TrackIdentity {
+	info, err := os.Stat(path)
+	if err != nil {
+		return TrackIdentity{}
+	}
+	return readTrackIdentityCached(path, info)
+}
+
+func readTrackIdentityCached(path string, info fs.FileInfo) TrackIdentity {
+	trackIdentityCacheLock.RLock()
+	cached, ok := trackIdentityCache[path]
+	trackIdentityCacheLock.RUnlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.identity
+	}
+
+	var identity TrackIdentity
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		identity = readFlacIdentity(path)
+	case ".mp3":
+		identity = readMp3Identity(path)
+	}
+
+	trackIdentityCacheLock.Lock()
+	trackIdentityCache[path] = cachedTrackIdentity{
+		size:     info.Size(),
+		modTime:  info.ModTime(),
+		identity: identity,
+	}
+	trackIdentityCacheLock.Unlock()
+	return identity
+}
+
+func readFlacIdentity(path string) TrackIdentity {
+	var identity TrackIdentity
+
+	f, err := flac.ParseFile(path)
+	if err != nil {
+		return identity
+	}
+
+	for _, block := range f.Meta {
+		if block.Type != flac.VorbisComment {
+			continue
+		}
+		cmt, err := flacvorbis.ParseFromMetaDataBlock(*block)
+		if err != nil {
+			continue
+		}
+		for _, comment := range cmt.Comments {
+			parts := strings.SplitN(comment, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			switch strings.ToUpper(parts[0]) {
+			case "ISRC":
+				if identity.ISRC == "" {
+					identity.ISRC = strings.TrimSpace(parts[1])
+				}
+			case "TITLE":
+				if identity.Title == "" {
+					identity.Title = strings.TrimSpace(parts[1])
+				}
+			case "ARTIST":
+				if identity.Artist == "" {
+					identity.Artist = strings.TrimSpace(parts[1])
+				}
+			case "URL", "SPOTIFY_URL", "WWW", "COMMENT", "DESCRIPTION":
+				if identity.SpotifyID == "" {
+					identity.SpotifyID = spotifyIDFromTag(parts[1])
+				}
+			}
+		}
+	}
+	return identity
+}
+
+func readMp3Identity(path string) TrackIdentity {
+	var identity TrackIdentity
+
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return identity
+	}
+	defer tag.Close()
+
+	if frame, ok := tag.GetLastFrame("TSRC").(id3v2.TextFrame); ok {
+		identity.ISRC = strings.TrimSpace(frame.Text)
+	}
+	identity.Title = strings.TrimSpace(tag.Title())
+	identity.Artist = strings.TrimSpace(tag.Artist())
+
+	for _, f := range tag.GetFrames("TXXX") {
+		if frame, ok := f.(id3v2.UserDefinedTextFrame); ok && identity.SpotifyID == "" {
+			identity.SpotifyID = spotifyIDFromTag(frame.Value)
+		}
+	}
+	for _, f := range tag.GetFrames(tag.CommonID("Comments")) {
+		if frame, ok := f.(id3v2.CommentFrame); ok && identity.SpotifyID == "" {
+			identity.SpotifyID = spotifyIDFromTag(frame.Text)
+		}
+	}
+	return identity
+}
+
+// trackTagKey matches title/artist tags loosely: case-insensitive and on the
+// first credited artist only.
+func trackTagKey(title, artists string) string {
+	title = strings.ToLower(strings.TrimSpace(title))
+	artist := strings.ToLower(strings.TrimSpace(GetFirstArtist(artists)))
+	if title == "" || artist == "" {
+		return ""
+	}
+	return title + "\x00" + artist
+}
+
+func spotifyIDFromTag(value string) string {
+	if m := spotifyTrackTagPattern.FindStringSubmatch(value); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// LibraryIndex maps track identifiers to audio files found under a set of
+// directories.
+type LibraryIndex struct {
+	mu          sync.RWMutex
+	byISRC      map[string]string
+	bySpotifyID map[string]string
+	byFilename  map[string]string
+	byTags      map[string]string
+	identities  map[string]TrackIdentity
+}
+
+// BuildLibraryIndex reads the identity tags of every FLAC and MP3 file in
+// dirs, ignoring files too small to be a finished download. Subdirectories
+// are included when recursive is set.
+func BuildLibraryIndex(recursive bool, dirs ...string) *LibraryIndex {
+	index := &LibraryIndex{
+		byISRC:      make(map[string]string),
+		bySpotifyID: make(map[string]string),
+		byFilename:  make(map[string]string),
+		byTags:      make(map[string]string),
+		identities:  make(map[string]TrackIdentity),
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+			if ext != ".flac" && ext != ".mp3" {
+				return nil
+			}
+			if _, seen := index.identities[path]; seen {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || info.Size() <= 100*1024 {
+				return nil
+			}
+			index.add(path, readTrackIdentityCached(path, info))
+			return nil
+		})
+	}
+	return index
+}
+
+// Add indexes a file written after the index was built.
+func (idx *LibraryIndex) Add(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	identity := readTrackIdentityCached(path, info)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.add(path, identity)
+}
+
+func (idx *LibraryIndex) add(path string, identity TrackIdentity) {
+	idx.identities[path] = identity
+	if _, ok := idx.byFilename[filepath.Base(path)]; !ok {
+		idx.byFilename[filepath.Base(path)] = path
+	}
+	if identity.ISRC != "" {
+		key := strings.ToUpper(identity.ISRC)
+		if _, ok := idx.byISRC[key]; !ok {
+			idx.byISRC[key] = path
+		}
+	}
+	if identity.SpotifyID != "" {
+		if _, ok := idx.bySpotifyID[identity.SpotifyID]; !ok {
+			idx.bySpotifyID[identity.SpotifyID] = path
+		}
+	}
+	if key := trackTagKey(identity.Title, identity.Artist); key != "" {
+		// Several files with the same title and artist are ambiguous, so
+		// the key is kept but no longer points at any of them.
+		if _, ok := idx.byTags[key]; ok {
+			idx.byTags[key] = ""
+		} else {
+			idx.byTags[key] = path
+		}
+	}
+}
+
+// Find returns the file tagged with the Spotify ID or, failing that, the ISRC.
+func (idx *LibraryIndex) Find(isrc, spotifyID string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if spotifyID != "" {
+		if path, ok := idx.bySpotifyID[spotifyID]; ok {
+			return path, true
+		}
+	}
+	if isrc != "" {
+		if path, ok := idx.byISRC[strings.ToUpper(isrc)]; ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// FindByTags returns the only indexed file tagged with the title and first
+// artist. Titles shared by several files are not matched.
+func (idx *LibraryIndex) FindByTags(title, artists string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	key := trackTagKey(title, artists)
+	if key == "" {
+		return "", false
+	}
+	path := idx.byTags[key]
+	return path, path != ""
+}
+
+// FindByFilename returns the first indexed file with the given basename.
+func (idx *LibraryIndex) FindByFilename(name string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	path, ok := idx.byFilename[name]
+	return path, ok
+}
+
+// Identity returns the tags of an indexed file, reading them for files that
+// were not part of the scan.
+func (idx *LibraryIndex) Identity(path string) TrackIdentity {
+	idx.mu.RLock()
+	identity, ok := idx.identities[path]
+	idx.mu.RUnlock()
+	if ok {
+		return identity
+	}
+	return ReadTrackIdentity(path)
+}
+
+const libraryIndexCacheTTL = 5 * time.Minute
+
+type cachedLibraryIndex struct {
+	index *LibraryIndex
+	built time.Time
+}
+
+var (
+	libraryIndexCache     = make(map[string]cachedLibraryIndex)
+	libraryIndexCacheLock sync.Mutex
+)
+
+// CachedLibraryIndex returns a recursive index of root that is shared by
+// the downloads of a batch, so the library is scanned once rather than once
+// per track. It is rebuilt after libraryIndexCacheTTL; files downloaded in
+// the meantime are added with AddToLibraryIndexes.
+func CachedLibraryIndex(root string) *LibraryIndex {
+	root = filepath.Clean(root)
+
+	libraryIndexCacheLock.Lock()
+	defer libraryIndexCacheLock.Unlock()
+
+	if cached, ok := libraryIndexCache[root]; ok && time.Since(cached.built) < libraryIndexCacheTTL {
+		return cached.index
+	}
+	index := BuildLibraryIndex(true, root)
+	libraryIndexCache[root] = cachedLibraryIndex{index: index, built: time.Now()}
+	return index
+}
+
+// AddToLibraryIndexes adds a new file to every cached index covering it.
+func AddToLibraryIndexes(path string) {
+	libraryIndexCacheLock.Lock()
+	var indexes []*LibraryIndex
+	for root, cached := range libraryIndexCache {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			indexes = append(indexes, cached.index)
+		}
+	}
+	libraryIndexCacheLock.Unlock()
+
+	for _, index := range indexes {
+		index.Add(path)
+	}
+}
@@ -0,0 +1,191 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+)
+
+// writeTaggedFLAC writes a FLAC file with the given Vorbis comments, padded
+// past the size BuildLibraryIndex ignores. The audio frames are not valid.
+func writeTaggedFLAC(t *testing.T, path string, comments ...string) {
+	t.Helper()
+	cmt := flacvorbis.New()
+	for i := 0; i+1 < len(comments); i += 2 {
+		cmt.Add(comments[i], comments[i+1])
+	}
+	block := cmt.Marshal()
+	frames := make([]byte, 101*1024)
+	frames[0], frames[1] = 0xFF, 0xF8
+	f := &flac.File{
+		Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}, &block},
+		Frames: frames,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTaggedMP3 writes an ID3v2 tag with an ISRC and a comment, followed by
+// enough filler to be indexed.
+func writeTaggedMP3(t *testing.T, path, isrc, comment string) {
+	t.Helper()
+	tag := id3v2.NewEmptyTag()
+	tag.SetVersion(4)
+	tag.AddTextFrame("TSRC", id3v2.EncodingUTF8, isrc)
+	tag.AddCommentFrame(id3v2.CommentFrame{Encoding: id3v2.EncodingUTF8, Language: "eng", Text: comment})
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := tag.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(make([]byte, 101*1024)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpotifyIDFromTag(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", "4uLU6hMCjMI75M1A2tKUQC"},
+		{"https://open.spotify.com/intl-de/track/4uLU6hMCjMI75M1A2tKUQC?si=x", "4uLU6hMCjMI75M1A2tKUQC"},
+		{"spotify:track:4uLU6hMCjMI75M1A2tKUQC", "4uLU6hMCjMI75M1A2tKUQC"},
+		{"https://open.spotify.com/album/4uLU6hMCjMI75M1A2tKUQC", ""},
+		{"Downloaded with SpotiFLAC", ""},
+	}
+	for _, tt := range tests {
+		if got := spotifyIDFromTag(tt.value); got != tt.want {
+			t.Errorf("spotifyIDFromTag(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTrackIdentityMatches(t *testing.T) {
+	identity := TrackIdentity{ISRC: "USRC17607839", SpotifyID: "4uLU6hMCjMI75M1A2tKUQC"}
+	tests := []struct {
+		name      string
+		isrc      string
+		spotifyID string
+		matches   bool
+		conflicts bool
+	}{
+		{"same spotify id", "", "4uLU6hMCjMI75M1A2tKUQC", true, false},
+		{"same isrc in another case", "usrc17607839", "", true, false},
+		{"isrc agrees, spotify id differs", "USRC17607839", "0000000000000000000000", true, false},
+		{"different isrc", "GBAAA0000001", "", false, true},
+		{"different spotify id", "", "0000000000000000000000", false, true},
+		{"nothing to compare", "", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := identity.Matches(tt.isrc, tt.spotifyID); got != tt.matches {
+				t.Errorf("Matches = %v, want %v", got, tt.matches)
+			}
+			if got := identity.Conflicts(tt.isrc, tt.spotifyID); got != tt.conflicts {
+				t.Errorf("Conflicts = %v, want %v", got, tt.conflicts)
+			}
+		})
+	}
+	if (TrackIdentity{}).Conflicts("USRC17607839", "4uLU6hMCjMI75M1A2tKUQC") {
+		t.Error("an untagged file should not conflict")
+	}
+}
+
+func TestBuildLibraryIndex(t *testing.T) {
+	root := t.TempDir()
+	top := filepath.Join(root, "Top.flac")
+	nested := filepath.Join(root, "Artist", "Album", "Nested.flac")
+	mp3 := filepath.Join(root, "Artist", "Song.mp3")
+	writeTaggedFLAC(t, top, "ISRC", "USRC17607839")
+	writeTaggedFLAC(t, nested, "ISRC", "GBAAA0000001", "COMMENT", "https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC")
+	writeTaggedMP3(t, mp3, "GBBBB0000002", "spotify:track:1111111111111111111111")
+	// Partial downloads are too small to count.
+	if err := os.WriteFile(filepath.Join(root, "Small.flac"), []byte("fLaC"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		isrc      string
+		spotifyID string
+		want      string
+	}{
+		{"isrc at the top", false, "usrc17607839", "", top},
+		{"nested file needs recursion", false, "GBAAA0000001", "", ""},
+		{"nested isrc", true, "GBAAA0000001", "", nested},
+		{"spotify id wins over isrc", true, "USRC17607839", "4uLU6hMCjMI75M1A2tKUQC", nested},
+		{"mp3 isrc", true, "GBBBB0000002", "", mp3},
+		{"mp3 spotify comment", true, "", "1111111111111111111111", mp3},
+		{"unknown", true, "ZZZZZ0000000", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := BuildLibraryIndex(tt.recursive, root)
+			got, ok := index.Find(tt.isrc, tt.spotifyID)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("Find(%q, %q) = %q, %v; want %q", tt.isrc, tt.spotifyID, got, ok, tt.want)
+			}
+		})
+	}
+
+	index := BuildLibraryIndex(true, root)
+	if _, ok := index.FindByFilename("Small.flac"); ok {
+		t.Error("a file below the size limit was indexed")
+	}
+	if got, _ := index.FindByFilename("Song.mp3"); got != mp3 {
+		t.Errorf("FindByFilename = %q, want %q", got, mp3)
+	}
+}
+
+func TestCachedLibraryIndex(t *testing.T) {
+	root := t.TempDir()
+	writeTaggedFLAC(t, filepath.Join(root, "Old.flac"), "ISRC", "USRC17607839")
+
+	index := CachedLibraryIndex(root)
+	t.Cleanup(func() {
+		libraryIndexCacheLock.Lock()
+		delete(libraryIndexCache, filepath.Clean(root))
+		libraryIndexCacheLock.Unlock()
+	})
+	if again := CachedLibraryIndex(root + string(filepath.Separator)); again != index {
+		t.Error("the same root was scanned twice")
+	}
+
+	added := filepath.Join(root, "Artist", "New.flac")
+	outside := filepath.Join(t.TempDir(), "Outside.flac")
+	writeTaggedFLAC(t, added, "ISRC", "GBAAA0000001")
+	writeTaggedFLAC(t, outside, "ISRC", "GBBBB0000002")
+	AddToLibraryIndexes(added)
+	AddToLibraryIndexes(outside)
+
+	tests := []struct {
+		isrc string
+		want string
+	}{
+		{"USRC17607839", filepath.Join(root, "Old.flac")},
+		{"GBAAA0000001", added},
+		{"GBBBB0000002", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := index.Find(tt.isrc, ""); got != tt.want {
+			t.Errorf("Find(%q) = %q, want %q", tt.isrc, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -33,6 +34,10 @@ type Metadata struct {
 	Description string
 	ISRC        string
 	Genre       string
+
+	Source        string
+	SourceID      string
+	SourceQuality string
 }
 
 func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
@@ -92,6 +97,10 @@ func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
 		_ = cmt.Add("ISRC", metadata.ISRC)
 	}
 
+	if metadata.URL != "" {
+		_ = cmt.Add("URL", metadata.URL)
+	}
+
 	if metadata.Genre != "" {
 		_ = cmt.Add("GENRE", metadata.Genre)
 	}
@@ -100,6 +109,16 @@ func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
 		_ = cmt.Add("LYRICS", metadata.Lyrics)
 	}
 
+	if metadata.Source != "" {
+		_ = cmt.Add("SOURCE", metadata.Source)
+	}
+	if metadata.SourceID != "" {
+		_ = cmt.Add("SOURCE_ID", metadata.SourceID)
+	}
+	if metadata.SourceQuality != "" {
+		_ = cmt.Add("SOURCE_QUALITY", metadata.SourceQuality)
+	}
+
 	cmtBlock := cmt.Marshal()
 	if cmtIdx < 0 {
 		f.Meta = append(f.Meta, &cmtBlock)
@@ -455,7 +474,7 @@ func EmbedLyricsOnlyMP3(filepath string, lyrics string) error {
 	return nil
 }
 
-func embedLyricsToM4A(filepath string, lyrics string) error {
+func embedLyricsToM4A(ctx context.Context, filepath string, lyrics string) error {
 
 	validatedLyrics, err := validateLyricsDuration(lyrics, filepath)
 	if err != nil {
@@ -481,7 +500,7 @@ func embedLyricsToM4A(filepath string, lyrics string) error {
 		}
 	}()
 
-	cmd := exec.Command(ffmpegPath,
+	cmd := exec.CommandContext(ctx, ffmpegPath,
 		"-i", filepath,
 		"-map", "0",
 		"-map_metadata", "0",
@@ -496,6 +515,9 @@ func embedLyricsToM4A(filepath string, lyrics string) error {
 	setHideWindow(cmd)
 
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		fmt.Printf("[FFmpeg] Error embedding lyrics to M4A: %s\n", string(output))
 		return fmt.Errorf("ffmpeg failed to embed lyrics: %s - %w", string(output), err)
@@ -509,7 +531,7 @@ func embedLyricsToM4A(filepath string, lyrics string) error {
 	return nil
 }
 
-func EmbedLyricsOnlyUniversal(filepath string, lyrics string) error {
+func EmbedLyricsOnlyUniversal(ctx context.Context, filepath string, lyrics string) error {
 	if lyrics == "" {
 		return nil
 	}
@@ -528,7 +550,7 @@ func EmbedLyricsOnlyUniversal(filepath string, lyrics string) error {
 	case ".flac":
 		return EmbedLyricsOnly(filepath, lyrics)
 	case ".m4a":
-		return embedLyricsToM4A(filepath, lyrics)
+		return embedLyricsToM4A(ctx, filepath, lyrics)
 	default:
 		return fmt.Errorf("unsupported file format for lyrics embedding: %s", ext)
 	}
@@ -795,7 +817,7 @@ func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
 	return metadata, nil
 }
 
-func EmbedMetadataToConvertedFile(filePath string, metadata Metadata, coverPath string) error {
+func EmbedMetadataToConvertedFile(ctx context.Context, filePath string, metadata Metadata, coverPath string) error {
 	ext := strings.ToLower(pathfilepath.Ext(filePath))
 
 	switch ext {
@@ -805,7 +827,7 @@ func EmbedMetadataToConvertedFile(filePath string, metadata Metadata, coverPath
 	case ".mp3":
 		return embedMetadataToMP3(filePath, metadata, coverPath)
 	case ".m4a":
-		return embedMetadataToM4A(filePath, metadata, coverPath)
+		return embedMetadataToM4A(ctx, filePath, metadata, coverPath)
 	default:
 		return fmt.Errorf("unsupported file format: %s", ext)
 	}
@@ -875,6 +897,21 @@ func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) er
 		tag.AddTextFrame("TSRC", id3v2.EncodingUTF8, metadata.ISRC)
 	}
 
+	for _, frame := range []struct{ name, value string }{
+		{"URL", metadata.URL},
+		{"SOURCE", metadata.Source},
+		{"SOURCE_ID", metadata.SourceID},
+		{"SOURCE_QUALITY", metadata.SourceQuality},
+	} {
+		if frame.value != "" {
+			tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+				Encoding:    id3v2.EncodingUTF8,
+				Description: frame.name,
+				Value:       frame.value,
+			})
+		}
+	}
+
 	if coverPath != "" && fileExists(coverPath) {
 
 		tag.DeleteFrames(tag.CommonID("Attached picture"))
@@ -901,7 +938,7 @@ func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) er
 	return nil
 }
 
-func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string) error {
+func embedMetadataToM4A(ctx context.Context, filePath string, metadata Metadata, coverPath string) error {
 	ffmpegPath, err := GetFFmpegPath()
 	if err != nil {
 		return fmt.Errorf("ffmpeg not found: %w", err)
@@ -971,14 +1008,25 @@ func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string) er
 
 	args = append(args, "-f", "ipod", tmpOutputFile)
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 	setHideWindow(cmd)
 
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return fmt.Errorf("ffmpeg failed to embed metadata: %s - %w", string(output), err)
 	}
 
+	if err := writeMP4FreeformTags(tmpOutputFile, [][2]string{
+		{"SOURCE", metadata.Source},
+		{"SOURCE_ID", metadata.SourceID},
+		{"SOURCE_QUALITY", metadata.SourceQuality},
+	}); err != nil {
+		fmt.Printf("⚠ Failed to write source tags: %v\n", err)
+	}
+
 	if err := os.Rename(tmpOutputFile, filePath); err != nil {
 		return fmt.Errorf("failed to replace original file: %w", err)
 	}
@@ -0,0 +1,292 @@
+package backend
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ffmpeg's ipod muxer only writes the iTunes tags it knows about, and
+// use_metadata_tags would swap all of them for QuickTime keys that most
+// players ignore. Custom tags are added as freeform iTunes atoms instead.
+
+const freeformMean = "com.apple.iTunes"
+
+type mp4Box struct {
+	typ      string
+	prefix   []byte // version and flags of full boxes like meta
+	payload  []byte
+	children []*mp4Box
+}
+
+func isMP4Container(typ string) bool {
+	switch typ {
+	case "moov", "trak", "mdia", "minf", "stbl", "udta", "meta", "ilst":
+		return true
+	}
+	return false
+}
+
+func parseMP4Boxes(data []byte) ([]*mp4Box, error) {
+	var boxes []*mp4Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated mp4 box")
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, fmt.Errorf("truncated mp4 box")
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			header = 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid mp4 box size")
+		}
+
+		box := &mp4Box{typ: string(data[4:8])}
+		body := data[header:size]
+		if isMP4Container(box.typ) {
+			if box.typ == "meta" {
+				if len(body) < 4 {
+					return nil, fmt.Errorf("truncated meta box")
+				}
+				box.prefix, body = body[:4], body[4:]
+			}
+			children, err := parseMP4Boxes(body)
+			if err != nil {
+				return nil, err
+			}
+			box.children = children
+		} else {
+			box.payload = body
+		}
+		boxes = append(boxes, box)
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+func (b *mp4Box) marshal() []byte {
+	body := append([]byte{}, b.prefix...)
+	if isMP4Container(b.typ) {
+		for _, child := range b.children {
+			body = append(body, child.marshal()...)
+		}
+	} else {
+		body = append(body, b.payload...)
+	}
+	out := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(out, uint32(8+len(body)))
+	copy(out[4:], b.typ)
+	return append(out, body...)
+}
+
+func (b *mp4Box) child(typ string) *mp4Box {
+	for _, c := range b.children {
+		if c.typ == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+func (b *mp4Box) childOrNew(typ string) *mp4Box {
+	if c := b.child(typ); c != nil {
+		return c
+	}
+	c := &mp4Box{typ: typ}
+	if typ == "meta" {
+		c.prefix = make([]byte, 4)
+		hdlr := append(make([]byte, 8), "mdirappl"...)
+		c.children = append(c.children, &mp4Box{typ: "hdlr", payload: append(hdlr, make([]byte, 9)...)})
+	}
+	b.children = append(b.children, c)
+	return c
+}
+
+func fullBox(typ string, payload []byte) []byte {
+	box := &mp4Box{typ: typ, payload: append(make([]byte, 4), payload...)}
+	return box.marshal()
+}
+
+// freeformName returns the name of a ---- atom, or "" for other atoms.
+func freeformName(b *mp4Box) string {
+	if b.typ != "----" {
+		return ""
+	}
+	children, err := parseMP4Boxes(b.payload)
+	if err != nil {
+		return ""
+	}
+	for _, c := range children {
+		if c.typ == "name" && len(c.payload) >= 4 {
+			return string(c.payload[4:])
+		}
+	}
+	return ""
+}
+
+func newFreeformAtom(name, value string) *mp4Box {
+	var payload []byte
+	payload = append(payload, fullBox("mean", []byte(freeformMean))...)
+	payload = append(payload, fullBox("name", []byte(name))...)
+	data := &mp4Box{typ: "data", payload: append([]byte{0, 0, 0, 1, 0, 0, 0, 0}, value...)}
+	payload = append(payload, data.marshal()...)
+	return &mp4Box{typ: "----", payload: payload}
+}
+
+// shiftChunkOffsets moves the sample table offsets that point past from by
+// delta bytes, for when moov grows in front of mdat.
+func shiftChunkOffsets(boxes []*mp4Box, from uint64, delta int64) error {
+	for _, b := range boxes {
+		switch b.typ {
+		case "stco", "co64":
+			if len(b.payload) < 8 {
+				return fmt.Errorf("truncated %s box", b.typ)
+			}
+			width := 4
+			if b.typ == "co64" {
+				width = 8
+			}
+			count := int(binary.BigEndian.Uint32(b.payload[4:]))
+			entries := b.payload[8:]
+			if len(entries) < count*width {
+				return fmt.Errorf("truncated %s box", b.typ)
+			}
+			for i := 0; i < count; i++ {
+				entry := entries[i*width:]
+				if width == 4 {
+					offset := uint64(binary.BigEndian.Uint32(entry))
+					if offset < from {
+						continue
+					}
+					shifted := int64(offset) + delta
+					if shifted < 0 || shifted > 0xFFFFFFFF {
+						return fmt.Errorf("chunk offset out of range")
+					}
+					binary.BigEndian.PutUint32(entry, uint32(shifted))
+				} else {
+					offset := binary.BigEndian.Uint64(entry)
+					if offset >= from {
+						binary.BigEndian.PutUint64(entry, uint64(int64(offset)+delta))
+					}
+				}
+			}
+		default:
+			if err := shiftChunkOffsets(b.children, from, delta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeMP4FreeformTags sets ----:com.apple.iTunes:<name> atoms on an MP4
+// file, replacing existing atoms of the same name. Empty values are skipped.
+func writeMP4FreeformTags(path string, tags [][2]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var moovOffset, moovSize uint64
+	for offset := uint64(0); offset < uint64(info.Size()); {
+		header := make([]byte, 16)
+		n, err := f.ReadAt(header, int64(offset))
+		if n < 8 {
+			return fmt.Errorf("failed to read mp4 box header: %w", err)
+		}
+		size := uint64(binary.BigEndian.Uint32(header))
+		switch size {
+		case 0:
+			size = uint64(info.Size()) - offset
+		case 1:
+			if n < 16 {
+				return fmt.Errorf("truncated mp4 box header")
+			}
+			size = binary.BigEndian.Uint64(header[8:])
+		}
+		if size < 8 {
+			return fmt.Errorf("invalid mp4 box size")
+		}
+		if string(header[4:8]) == "moov" {
+			moovOffset, moovSize = offset, size
+			break
+		}
+		offset += size
+	}
+	if moovSize == 0 {
+		return fmt.Errorf("no moov box found")
+	}
+
+	raw := make([]byte, moovSize)
+	if _, err := f.ReadAt(raw, int64(moovOffset)); err != nil {
+		return fmt.Errorf("failed to read moov box: %w", err)
+	}
+	boxes, err := parseMP4Boxes(raw)
+	if err != nil {
+		return err
+	}
+	moov := boxes[0]
+
+	ilst := moov.childOrNew("udta").childOrNew("meta").childOrNew("ilst")
+	for _, tag := range tags {
+		if tag[1] == "" {
+			continue
+		}
+		kept := ilst.children[:0]
+		for _, c := range ilst.children {
+			if freeformName(c) != tag[0] {
+				kept = append(kept, c)
+			}
+		}
+		ilst.children = append(kept, newFreeformAtom(tag[0], tag[1]))
+	}
+
+	moovEnd := moovOffset + moovSize
+	updated := moov.marshal()
+	if delta := int64(len(updated)) - int64(moovSize); delta != 0 {
+		if err := shiftChunkOffsets(moov.children, moovEnd, delta); err != nil {
+			return err
+		}
+		updated = moov.marshal()
+	}
+
+	tmpPath := path + ".tags.tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, io.NewSectionReader(f, 0, int64(moovOffset)))
+	if err == nil {
+		_, err = out.Write(updated)
+	}
+	if err == nil {
+		_, err = io.Copy(out, io.NewSectionReader(f, int64(moovEnd), info.Size()-int64(moovEnd)))
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		f.Close()
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write mp4 tags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestMP4 writes ftyp, a moov with an iTunes title and one chunk
+// offset, and the mdat that offset points into.
+func writeTestMP4(t *testing.T, path string) {
+	t.Helper()
+	title := &mp4Box{typ: "\xa9nam", payload: (&mp4Box{typ: "data", payload: append([]byte{0, 0, 0, 1, 0, 0, 0, 0}, "Song"...)}).marshal()}
+	stco := &mp4Box{typ: "stco", payload: make([]byte, 12)}
+	moov := &mp4Box{typ: "moov", children: []*mp4Box{
+		{typ: "trak", children: []*mp4Box{
+			{typ: "mdia", children: []*mp4Box{
+				{typ: "minf", children: []*mp4Box{
+					{typ: "stbl", children: []*mp4Box{stco}},
+				}},
+			}},
+		}},
+		{typ: "udta", children: []*mp4Box{
+			{typ: "meta", prefix: make([]byte, 4), children: []*mp4Box{
+				{typ: "ilst", children: []*mp4Box{title}},
+			}},
+		}},
+	}}
+	ftyp := (&mp4Box{typ: "ftyp", payload: []byte("M4A \x00\x00\x02\x00")}).marshal()
+
+	binary.BigEndian.PutUint32(stco.payload[4:], 1)
+	mdatOffset := len(ftyp) + len(moov.marshal())
+	binary.BigEndian.PutUint32(stco.payload[8:], uint32(mdatOffset+8))
+	mdat := (&mp4Box{typ: "mdat", payload: []byte("AUDIODATA")}).marshal()
+
+	data := append(append(ftyp, moov.marshal()...), mdat...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestMP4Tags(t *testing.T, path string) (map[string]string, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxes, err := parseMP4Boxes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var moov *mp4Box
+	for _, b := range boxes {
+		if b.typ == "moov" {
+			moov = b
+		}
+	}
+	tags := make(map[string]string)
+	for _, item := range moov.child("udta").child("meta").child("ilst").children {
+		name := item.typ
+		payload := item.payload
+		if item.typ == "----" {
+			name = "----:" + freeformName(item)
+		}
+		children, err := parseMP4Boxes(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range children {
+			if c.typ == "data" {
+				tags[name] = string(c.payload[8:])
+			}
+		}
+	}
+
+	stco := moov.child("trak").child("mdia").child("minf").child("stbl").child("stco")
+	offset := binary.BigEndian.Uint32(stco.payload[8:])
+	return tags, data[offset : offset+9]
+}
+
+func TestWriteMP4FreeformTags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	writeTestMP4(t, path)
+
+	if err := writeMP4FreeformTags(path, [][2]string{
+		{"SOURCE", "tidal"},
+		{"SOURCE_ID", "12345"},
+		{"SOURCE_QUALITY", ""},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMP4FreeformTags(path, [][2]string{{"SOURCE", "qobuz"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, audio := readTestMP4Tags(t, path)
+	want := map[string]string{
+		"\xa9nam":        "Song",
+		"----:SOURCE":    "qobuz",
+		"----:SOURCE_ID": "12345",
+	}
+	if len(tags) != len(want) {
+		t.Errorf("tags = %q, want %q", tags, want)
+	}
+	for name, value := range want {
+		if tags[name] != value {
+			t.Errorf("%q = %q, want %q", name, tags[name], value)
+		}
+	}
+	if !bytes.Equal(audio, []byte("AUDIODATA")) {
+		t.Errorf("chunk offset points at %q after moov grew", audio)
+	}
+}
+
+func TestWriteMP4FreeformTagsWithoutMeta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	moov := (&mp4Box{typ: "moov", children: []*mp4Box{{typ: "mvhd", payload: make([]byte, 100)}}}).marshal()
+	if err := os.WriteFile(path, moov, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeMP4FreeformTags(path, [][2]string{{"SOURCE", "amazon"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxes, err := parseMP4Boxes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := boxes[0].child("udta").child("meta")
+	if hdlr := meta.child("hdlr"); hdlr == nil || string(hdlr.payload[8:12]) != "mdir" {
+		t.Error("meta box has no mdir handler")
+	}
+	if ilst := meta.child("ilst"); ilst == nil || len(ilst.children) != 1 || freeformName(ilst.children[0]) != "SOURCE" {
+		t.Error("SOURCE atom not written")
+	}
+}
@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const PlaylistManifestName = ".spotiflac-sync.json"
+
+type PlaylistManifestTrack struct {
+	SpotifyID string `json:"spotify_id"`
+	Name      string `json:"name"`
+	Artists   string `json:"artists"`
+	File      string `json:"file"`
+	AddedAt   int64  `json:"added_at"`
+}
+
+type PlaylistManifest struct {
+	PlaylistURL  string                  `json:"playlist_url"`
+	PlaylistName string                  `json:"playlist_name"`
+	UpdatedAt    int64                   `json:"updated_at"`
+	Tracks       []PlaylistManifestTrack `json:"tracks"`
+}
+
+func LoadPlaylistManifest(dir string) (*PlaylistManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PlaylistManifestName))
+	if os.IsNotExist(err) {
+		return &PlaylistManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync manifest: %w", err)
+	}
+
+	var manifest PlaylistManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse sync manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func SavePlaylistManifest(dir string, manifest *PlaylistManifest) error {
+	manifest.UpdatedAt = time.Now().Unix()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, PlaylistManifestName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sync manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write sync manifest: %w", err)
+	}
+	return nil
+}
+
+// PlaylistRelPath returns path relative to the sync folder dir, or false
+// when it lies outside of it.
+func PlaylistRelPath(dir, path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+// ImportPlaylistFile puts a file found elsewhere in the library into the
+// sync folder, hardlinking it where possible and copying it otherwise, so
+// that the folder never refers to files it doesn't own.
+func ImportPlaylistFile(dir, src string) (string, error) {
+	dest := filepath.Join(dir, filepath.Base(src))
+	if info, err := os.Stat(dest); err == nil {
+		if srcInfo, err := os.Stat(src); err == nil && os.SameFile(info, srcInfo) {
+			return dest, nil
+		}
+		ext := filepath.Ext(dest)
+		dest = fmt.Sprintf("%s (%d)%s", dest[:len(dest)-len(ext)], time.Now().Unix(), ext)
+	}
+
+	if err := os.Link(src, dest); err == nil {
+		return dest, nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	tmpPath := dest + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, dest)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to copy %s into sync folder: %w", src, err)
+	}
+	return dest, nil
+}
+
+// RemovePlaylistFile deletes a synced file, refusing anything outside dir.
+func RemovePlaylistFile(dir, relPath string) error {
+	rel, ok := PlaylistRelPath(dir, relPath)
+	if !ok {
+		return fmt.Errorf("%s is outside the sync folder", relPath)
+	}
+	return os.Remove(filepath.Join(dir, rel))
+}
+
+func ArchivePlaylistFile(dir, relPath, archiveDir string) (string, error) {
+	rel, ok := PlaylistRelPath(dir, relPath)
+	if !ok {
+		return "", fmt.Errorf("%s is outside the sync folder", relPath)
+	}
+	relPath = rel
+	src := filepath.Join(dir, relPath)
+	dest := filepath.Join(dir, archiveDir, relPath)
+	if filepath.IsAbs(archiveDir) {
+		dest = filepath.Join(archiveDir, relPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		ext := filepath.Ext(dest)
+		dest = fmt.Sprintf("%s (%d)%s", dest[:len(dest)-len(ext)], time.Now().Unix(), ext)
+	}
+	if err := os.Rename(src, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
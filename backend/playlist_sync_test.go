@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlaylistRelPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Sync")
+	tests := []struct {
+		path   string
+		want   string
+		inside bool
+	}{
+		{path: "track.flac", want: "track.flac", inside: true},
+		{path: filepath.Join("sub", "track.flac"), want: filepath.Join("sub", "track.flac"), inside: true},
+		{path: filepath.Join(dir, "track.flac"), want: "track.flac", inside: true},
+		{path: filepath.Join("..", "Artist", "Album", "track.flac")},
+		{path: filepath.Join(filepath.Dir(dir), "Artist", "track.flac")},
+		{path: ".."},
+		{path: "."},
+		{path: filepath.Join("sub", "..", "..", "track.flac")},
+	}
+	for _, tt := range tests {
+		got, inside := PlaylistRelPath(dir, tt.path)
+		if got != tt.want || inside != tt.inside {
+			t.Errorf("PlaylistRelPath(%q) = %q, %v; want %q, %v", tt.path, got, inside, tt.want, tt.inside)
+		}
+	}
+}
+
+func TestPlaylistRemovalStaysInsideSyncFolder(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "Sync")
+	library := filepath.Join(root, "Artist", "Album", "track.flac")
+	for _, path := range []string{library, filepath.Join(dir, "own.flac"), filepath.Join(dir, "archived.flac")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	escaping := filepath.Join("..", "Artist", "Album", "track.flac")
+
+	if err := RemovePlaylistFile(dir, escaping); err == nil {
+		t.Error("RemovePlaylistFile accepted a path outside the sync folder")
+	}
+	if err := RemovePlaylistFile(dir, library); err == nil {
+		t.Error("RemovePlaylistFile accepted an absolute path outside the sync folder")
+	}
+	if _, err := ArchivePlaylistFile(dir, escaping, "Removed"); err == nil {
+		t.Error("ArchivePlaylistFile accepted a path outside the sync folder")
+	}
+	if _, err := os.Stat(library); err != nil {
+		t.Fatalf("library file was touched: %v", err)
+	}
+
+	if err := RemovePlaylistFile(dir, "own.flac"); err != nil {
+		t.Errorf("RemovePlaylistFile: %v", err)
+	}
+	archived, err := ArchivePlaylistFile(dir, "archived.flac", "Removed")
+	if err != nil {
+		t.Fatalf("ArchivePlaylistFile: %v", err)
+	}
+	if archived != filepath.Join(dir, "Removed", "archived.flac") {
+		t.Errorf("archived to %s", archived)
+	}
+}
+
+func TestImportPlaylistFile(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "Sync")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	library := filepath.Join(root, "Artist", "track.flac")
+	if err := os.MkdirAll(filepath.Dir(library), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(library, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := ImportPlaylistFile(dir, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest != filepath.Join(dir, "track.flac") {
+		t.Errorf("imported to %s", dest)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "audio" {
+		t.Errorf("imported file = %q, %v", data, err)
+	}
+
+	again, err := ImportPlaylistFile(dir, library)
+	if err != nil || again != dest {
+		t.Errorf("second import = %s, %v; want %s", again, err, dest)
+	}
+
+	if err := RemovePlaylistFile(dir, "track.flac"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(library); err != nil {
+		t.Errorf("removing the synced copy removed the library file: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sync"
@@ -15,28 +16,46 @@ const (
 	StatusCompleted   DownloadStatus = "completed"
 	StatusFailed      DownloadStatus = "failed"
 	StatusSkipped     DownloadStatus = "skipped"
+	StatusCancelled   DownloadStatus = "cancelled"
+
+	StatusVerificationFailed DownloadStatus = "verification_failed"
+	StatusRetrying           DownloadStatus = "retrying"
 )
 
 type DownloadItem struct {
-	ID           string         `json:"id"`
-	TrackName    string         `json:"track_name"`
-	ArtistName   string         `json:"artist_name"`
-	AlbumName    string         `json:"album_name"`
-	SpotifyID    string         `json:"spotify_id"`
-	Status       DownloadStatus `json:"status"`
-	Progress     float64        `json:"progress"`
-	TotalSize    float64        `json:"total_size"`
-	Speed        float64        `json:"speed"`
-	StartTime    int64          `json:"start_time"`
-	EndTime      int64          `json:"end_time"`
-	ErrorMessage string         `json:"error_message"`
-	FilePath     string         `json:"file_path"`
+	ID           string            `json:"id"`
+	TrackName    string            `json:"track_name"`
+	ArtistName   string            `json:"artist_name"`
+	AlbumName    string            `json:"album_name"`
+	SpotifyID    string            `json:"spotify_id"`
+	Status       DownloadStatus    `json:"status"`
+	Progress     float64           `json:"progress"`
+	TotalSize    float64           `json:"total_size"`
+	Speed        float64           `json:"speed"`
+	Percent      float64           `json:"percent"`
+	ETA          float64           `json:"eta_seconds"`
+	StartTime    int64             `json:"start_time"`
+	EndTime      int64             `json:"end_time"`
+	ErrorMessage string            `json:"error_message"`
+	FilePath     string            `json:"file_path"`
+	Service      string            `json:"service,omitempty"`
+	Attempts     []DownloadAttempt `json:"attempts,omitempty"`
+	RetryCount   int               `json:"retry_count,omitempty"`
+	NextRetryAt  int64             `json:"next_retry_at,omitempty"`
+}
+
+type DownloadAttempt struct {
+	Service   string `json:"service"`
+	Error     string `json:"error,omitempty"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
 }
 
 var (
 	currentProgress     float64
 	currentProgressLock sync.RWMutex
 	isDownloading       bool
+	activeDownloads     int
 	downloadingLock     sync.RWMutex
 	currentSpeed        float64
 	speedLock           sync.RWMutex
@@ -48,6 +67,9 @@ var (
 	totalDownloaded     float64
 	totalDownloadedLock sync.RWMutex
 	sessionStartTime    int64
+
+	downloadCancels     = make(map[string]context.CancelFunc)
+	downloadCancelsLock sync.Mutex
 	sessionStartLock    sync.RWMutex
 )
 
@@ -67,6 +89,8 @@ type DownloadQueueInfo struct {
 	CompletedCount   int            `json:"completed_count"`
 	FailedCount      int            `json:"failed_count"`
 	SkippedCount     int            `json:"skipped_count"`
+	CancelledCount   int            `json:"cancelled_count"`
+	SessionETA       float64        `json:"session_eta_seconds"`
 }
 
 func GetDownloadProgress() ProgressInfo {
@@ -82,6 +106,10 @@ func GetDownloadProgress() ProgressInfo {
 	speed := currentSpeed
 	speedLock.RUnlock()
 
+	itemProgress, itemSpeed := activeItemProgress()
+	progress += itemProgress
+	speed += itemSpeed
+
 	return ProgressInfo{
 		IsDownloading: downloading,
 		MBDownloaded:  progress,
@@ -99,23 +127,33 @@ func SetDownloadProgress(mbDownloaded float64) {
 	currentProgressLock.Lock()
 	currentProgress = mbDownloaded
 	currentProgressLock.Unlock()
+
+	notifyQueueSummary()
 }
 
 func SetDownloading(downloading bool) {
 	downloadingLock.Lock()
-	isDownloading = downloading
+	if downloading {
+		activeDownloads++
+	} else if activeDownloads > 0 {
+		activeDownloads--
+	}
+	isDownloading = activeDownloads > 0
+	stillDownloading := isDownloading
 	downloadingLock.Unlock()
 
-	if !downloading {
+	if !stillDownloading {
 
 		SetDownloadProgress(0)
 		SetDownloadSpeed(0)
 	}
+	notifyQueueSummary()
 }
 
 type ProgressWriter struct {
 	writer      io.Writer
 	total       int64
+	expected    int64
 	lastPrinted int64
 	startTime   int64
 	lastTime    int64
@@ -142,6 +180,11 @@ func NewProgressWriterWithID(writer io.Writer, itemID string) *ProgressWriter {
 	return pw
 }
 
+func (pw *ProgressWriter) SetExpectedSize(size int64) {
+	pw.expected = size
+	SetItemExpectedSize(pw.itemID, size)
+}
+
 func getCurrentTimeMillis() int64 {
 	return time.Now().UnixMilli()
 }
@@ -160,17 +203,16 @@ func (pw *ProgressWriter) Write(p []byte) (int, error) {
 		var speedMBps float64
 		if timeDiff > 0 {
 			speedMBps = (bytesDiff / (1024 * 1024)) / timeDiff
-			SetDownloadSpeed(speedMBps)
+		}
+		if pw.expected > 0 {
+			fmt.Printf("\rDownloaded: %.2f / %.2f MB (%.0f%%, %.2f MB/s)", mbDownloaded, float64(pw.expected)/(1024*1024), float64(pw.total)*100/float64(pw.expected), speedMBps)
+		} else if timeDiff > 0 {
 			fmt.Printf("\rDownloaded: %.2f MB (%.2f MB/s)", mbDownloaded, speedMBps)
 		} else {
 			fmt.Printf("\rDownloaded: %.2f MB", mbDownloaded)
 		}
 
-		SetDownloadProgress(mbDownloaded)
-
-		if pw.itemID != "" {
-			UpdateItemProgress(pw.itemID, mbDownloaded, speedMBps)
-		}
+		reportDownloadProgress(pw.itemID, mbDownloaded, speedMBps)
 
 		pw.lastPrinted = pw.total
 		pw.lastTime = now
@@ -203,6 +245,7 @@ func AddToQueue(id, trackName, artistName, albumName, spotifyID string) {
 	}
 
 	downloadQueue = append(downloadQueue, item)
+	notifyQueueItem(EventQueueItemsAdded, item)
 
 	sessionStartLock.Lock()
 	if sessionStartTime == 0 {
@@ -211,24 +254,75 @@ func AddToQueue(id, trackName, artistName, albumName, spotifyID string) {
 	sessionStartLock.Unlock()
 }
 
-func StartDownloadItem(id string) {
+func RestoreDownloadItem(item DownloadItem) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
 
+	item.Status = StatusQueued
+	item.Progress = 0
+	item.Speed = 0
+	item.StartTime = 0
+	item.EndTime = 0
+	item.ErrorMessage = ""
+	notifyQueueItem(EventQueueItemsAdded, item)
+
 	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusDownloading
-			downloadQueue[i].StartTime = time.Now().Unix()
-			downloadQueue[i].Progress = 0
-			break
+		if downloadQueue[i].ID == item.ID {
+			downloadQueue[i] = item
+			return
 		}
 	}
+	downloadQueue = append(downloadQueue, item)
+
+	sessionStartLock.Lock()
+	if sessionStartTime == 0 {
+		sessionStartTime = time.Now().Unix()
+	}
+	sessionStartLock.Unlock()
+}
+
+func StartDownloadItem(id string) {
+	item, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusDownloading
+		item.StartTime = time.Now().Unix()
+		item.Progress = 0
+		item.TotalSize = 0
+		item.Percent = 0
+		item.ETA = 0
+		notifyQueueItem(EventQueueItemStarted, *item)
+		return true
+	})
+	if ok {
+		updatePersistedDownloadItem(item)
+		albumItemStarted(id)
+	}
 
 	currentItemLock.Lock()
 	currentItemID = id
 	currentItemLock.Unlock()
 }
 
+// updateQueueItem applies update to the queue item with the given ID under
+// downloadQueueLock and returns a copy of the result. update returns false to
+// leave the item untouched. Callers persist the copy after the lock is
+// released so queue readers never wait on a bbolt write.
+func updateQueueItem(id string, update func(item *DownloadItem) bool) (DownloadItem, bool) {
+	downloadQueueLock.Lock()
+	defer downloadQueueLock.Unlock()
+
+	for i := range downloadQueue {
+		if downloadQueue[i].ID == id {
+			item := downloadQueue[i]
+			if !update(&item) {
+				return DownloadItem{}, false
+			}
+			downloadQueue[i] = item
+			return item, true
+		}
+	}
+	return DownloadItem{}, false
+}
+
 func UpdateItemProgress(id string, progress, speed float64) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
@@ -237,65 +331,241 @@ func UpdateItemProgress(id string, progress, speed float64) {
 		if downloadQueue[i].ID == id {
 			downloadQueue[i].Progress = progress
 			downloadQueue[i].Speed = speed
+			updateItemEstimates(&downloadQueue[i])
+			notifyQueueProgress(downloadQueue[i])
 			break
 		}
 	}
 }
 
-func GetCurrentItemID() string {
-	currentItemLock.RLock()
-	defer currentItemLock.RUnlock()
-	return currentItemID
-}
+func SetItemExpectedSize(id string, size int64) {
+	if id == "" || size <= 0 {
+		return
+	}
 
-func CompleteDownloadItem(id, filePath string, finalSize float64) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
 
 	for i := range downloadQueue {
 		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusCompleted
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
-			downloadQueue[i].Progress = finalSize
-			downloadQueue[i].TotalSize = finalSize
-
-			totalDownloadedLock.Lock()
-			totalDownloaded += finalSize
-			totalDownloadedLock.Unlock()
+			downloadQueue[i].TotalSize = float64(size) / (1024 * 1024)
+			updateItemEstimates(&downloadQueue[i])
+			notifyQueueProgress(downloadQueue[i])
 			break
 		}
 	}
 }
 
+func updateItemEstimates(item *DownloadItem) {
+	item.Percent = 0
+	item.ETA = 0
+	if item.TotalSize <= 0 {
+		return
+	}
+
+	item.Percent = item.Progress / item.TotalSize * 100
+	if item.Percent > 100 {
+		item.Percent = 100
+	}
+	if item.Speed > 0 && item.TotalSize > item.Progress {
+		item.ETA = (item.TotalSize - item.Progress) / item.Speed
+	}
+}
+
+// sessionETA estimates the time left for everything still queued or
+// downloading. Items whose size isn't known yet are assumed to be the
+// average of those that are. Callers must hold downloadQueueLock.
+func sessionETA(speed float64) float64 {
+	if speed <= 0 {
+		return 0
+	}
+
+	var remaining, sizedTotal float64
+	var sized, unsized int
+	for _, item := range downloadQueue {
+		switch item.Status {
+		case StatusCompleted:
+			if item.TotalSize > 0 {
+				sizedTotal += item.TotalSize
+				sized++
+			}
+		case StatusDownloading:
+			if item.TotalSize > 0 {
+				sizedTotal += item.TotalSize
+				sized++
+				if item.TotalSize > item.Progress {
+					remaining += item.TotalSize - item.Progress
+				}
+			} else {
+				unsized++
+			}
+		case StatusQueued, StatusRetrying:
+			unsized++
+		}
+	}
+
+	if unsized > 0 {
+		if sized == 0 {
+			return 0
+		}
+		remaining += float64(unsized) * sizedTotal / float64(sized)
+	}
+	return remaining / speed
+}
+
+func reportDownloadProgress(itemID string, mbDownloaded, speedMBps float64) {
+	if itemID == "" {
+		SetDownloadProgress(mbDownloaded)
+		SetDownloadSpeed(speedMBps)
+		return
+	}
+	UpdateItemProgress(itemID, mbDownloaded, speedMBps)
+}
+
+func activeItemProgress() (float64, float64) {
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
+
+	var progress, speed float64
+	for _, item := range downloadQueue {
+		if item.Status == StatusDownloading {
+			progress += item.Progress
+			speed += item.Speed
+		}
+	}
+	return progress, speed
+}
+
+func GetDownloadItem(id string) (DownloadItem, bool) {
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
+
+	for _, item := range downloadQueue {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return DownloadItem{}, false
+}
+
+func GetCurrentItemID() string {
+	currentItemLock.RLock()
+	defer currentItemLock.RUnlock()
+	return currentItemID
+}
+
+func CompleteDownloadItem(id, filePath string, finalSize float64) {
+	_, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusCompleted
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+		item.Progress = finalSize
+		item.TotalSize = finalSize
+		item.Percent = 100
+		item.ETA = 0
+		notifyQueueItem(EventQueueItemCompleted, *item)
+		return true
+	})
+	if !ok {
+		return
+	}
+	DeletePersistedDownload(id)
+
+	totalDownloadedLock.Lock()
+	totalDownloaded += finalSize
+	totalDownloadedLock.Unlock()
+}
+
 func FailDownloadItem(id, errorMsg string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+	item, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusFailed
+		item.EndTime = time.Now().Unix()
+		item.ErrorMessage = errorMsg
+		notifyQueueItem(EventQueueItemFailed, *item)
+		return true
+	})
+	if ok {
+		updatePersistedDownloadItem(item)
+	}
+}
 
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusFailed
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].ErrorMessage = errorMsg
-			break
+func FailDownloadItemVerification(id, errorMsg string) {
+	item, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusVerificationFailed
+		item.EndTime = time.Now().Unix()
+		item.ErrorMessage = errorMsg
+		notifyQueueItem(EventQueueItemFailed, *item)
+		return true
+	})
+	if ok {
+		updatePersistedDownloadItem(item)
+	}
+}
+
+func ScheduleDownloadRetry(id string, nextRetryAt int64) (DownloadItem, bool) {
+	item, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		if item.Status != StatusFailed {
+			return false
 		}
+		item.Status = StatusRetrying
+		item.RetryCount++
+		item.NextRetryAt = nextRetryAt
+		item.Speed = 0
+		notifyQueueItem(EventQueueItemRetrying, *item)
+		return true
+	})
+	if ok {
+		updatePersistedDownloadItem(item)
 	}
+	return item, ok
 }
 
-func SkipDownloadItem(id, filePath string) {
+func RequeueRetryingItem(id string) bool {
+	item, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		if item.Status != StatusRetrying {
+			return false
+		}
+		item.Status = StatusQueued
+		item.NextRetryAt = 0
+		item.Progress = 0
+		item.EndTime = 0
+		notifyQueueItem(EventQueueItemsAdded, *item)
+		return true
+	})
+	if ok {
+		updatePersistedDownloadItem(item)
+	}
+	return ok
+}
+
+func AddDownloadAttempt(id string, attempt DownloadAttempt) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
 
 	for i := range downloadQueue {
 		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusSkipped
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
+			downloadQueue[i].Attempts = append(downloadQueue[i].Attempts, attempt)
+			if attempt.Error == "" {
+				downloadQueue[i].Service = attempt.Service
+			}
 			break
 		}
 	}
 }
 
+func SkipDownloadItem(id, filePath string) {
+	_, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusSkipped
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+		notifyQueueItem(EventQueueItemSkipped, *item)
+		return true
+	})
+	if ok {
+		DeletePersistedDownload(id)
+	}
+}
+
 func GetDownloadQueue() DownloadQueueInfo {
 
 	ResetSessionIfComplete()
@@ -319,17 +589,21 @@ func GetDownloadQueue() DownloadQueueInfo {
 	sessionStart := sessionStartTime
 	sessionStartLock.RUnlock()
 
-	var queued, completed, failed, skipped int
+	var queued, completed, failed, skipped, cancelled int
 	for _, item := range downloadQueue {
 		switch item.Status {
-		case StatusQueued:
+		case StatusDownloading:
+			speed += item.Speed
+		case StatusQueued, StatusRetrying:
 			queued++
 		case StatusCompleted:
 			completed++
-		case StatusFailed:
+		case StatusFailed, StatusVerificationFailed:
 			failed++
 		case StatusSkipped:
 			skipped++
+		case StatusCancelled:
+			cancelled++
 		}
 	}
 
@@ -346,20 +620,29 @@ func GetDownloadQueue() DownloadQueueInfo {
 		CompletedCount:   completed,
 		FailedCount:      failed,
 		SkippedCount:     skipped,
+		CancelledCount:   cancelled,
+		SessionETA:       sessionETA(speed),
 	}
 }
 
 func ClearDownloadQueue() {
+	var removed []string
 	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
 	newQueue := make([]DownloadItem, 0)
 	for _, item := range downloadQueue {
-		if item.Status == StatusQueued || item.Status == StatusDownloading {
+		if item.Status == StatusQueued || item.Status == StatusDownloading || item.Status == StatusRetrying {
 			newQueue = append(newQueue, item)
+		} else {
+			removed = append(removed, item.ID)
 		}
 	}
 	downloadQueue = newQueue
+	downloadQueueLock.Unlock()
+
+	for _, id := range removed {
+		DeletePersistedDownload(id)
+	}
+	notifyQueueRefresh()
 }
 
 func ClearAllDownloads() {
@@ -367,6 +650,8 @@ func ClearAllDownloads() {
 	downloadQueue = []DownloadItem{}
 	downloadQueueLock.Unlock()
 
+	ClearPersistedDownloads()
+
 	totalDownloadedLock.Lock()
 	totalDownloaded = 0
 	totalDownloadedLock.Unlock()
@@ -381,26 +666,68 @@ func ClearAllDownloads() {
 
 	SetDownloadProgress(0)
 	SetDownloadSpeed(0)
+	notifyQueueRefresh()
 }
 
 func CancelAllQueuedItems() {
+	var cancelled []string
 	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
 	for i := range downloadQueue {
-		if downloadQueue[i].Status == StatusQueued {
-			downloadQueue[i].Status = StatusSkipped
+		if downloadQueue[i].Status == StatusQueued || downloadQueue[i].Status == StatusRetrying {
+			downloadQueue[i].Status = StatusCancelled
 			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].ErrorMessage = "Cancelled"
+			cancelled = append(cancelled, downloadQueue[i].ID)
 		}
 	}
+	downloadQueueLock.Unlock()
+
+	for _, id := range cancelled {
+		DeletePersistedDownload(id)
+	}
+	notifyQueueRefresh()
+}
+
+func CancelDownloadItem(id string) {
+	_, ok := updateQueueItem(id, func(item *DownloadItem) bool {
+		item.Status = StatusCancelled
+		item.EndTime = time.Now().Unix()
+		item.Speed = 0
+		notifyQueueItem(EventQueueItemCancelled, *item)
+		return true
+	})
+	if ok {
+		DeletePersistedDownload(id)
+	}
+}
+
+func RegisterDownloadCancel(id string, cancel context.CancelFunc) {
+	downloadCancelsLock.Lock()
+	defer downloadCancelsLock.Unlock()
+	downloadCancels[id] = cancel
+}
+
+func UnregisterDownloadCancel(id string) {
+	downloadCancelsLock.Lock()
+	defer downloadCancelsLock.Unlock()
+	delete(downloadCancels, id)
+}
+
+func CancelActiveDownload(id string) bool {
+	downloadCancelsLock.Lock()
+	cancel, ok := downloadCancels[id]
+	downloadCancelsLock.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
 }
 
 func ResetSessionIfComplete() {
 	downloadQueueLock.RLock()
 	hasActiveOrQueued := false
 	for _, item := range downloadQueue {
-		if item.Status == StatusQueued || item.Status == StatusDownloading {
+		if item.Status == StatusQueued || item.Status == StatusDownloading || item.Status == StatusRetrying {
 			hasActiveOrQueued = true
 			break
 		}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+type TrackRequest struct {
+	ItemID               string `json:"item_id,omitempty"`
+	SpotifyID            string `json:"spotify_id,omitempty"`
+	ServiceURL           string `json:"service_url,omitempty"`
+	ISRC                 string `json:"isrc,omitempty"`
+	APIURL               string `json:"api_url,omitempty"`
+	OutputDir            string `json:"output_dir"`
+	Quality              string `json:"quality,omitempty"`
+	FilenameFormat       string `json:"filename_format,omitempty"`
+	PlaylistName         string `json:"playlist_name,omitempty"`
+	PlaylistOwner        string `json:"playlist_owner,omitempty"`
+	IncludeTrackNumber   bool   `json:"include_track_number,omitempty"`
+	Position             int    `json:"position,omitempty"`
+	UseAlbumTrackNumber  bool   `json:"use_album_track_number,omitempty"`
+	TrackName            string `json:"track_name,omitempty"`
+	ArtistName           string `json:"artist_name,omitempty"`
+	AlbumName            string `json:"album_name,omitempty"`
+	AlbumArtist          string `json:"album_artist,omitempty"`
+	ReleaseDate          string `json:"release_date,omitempty"`
+	CoverURL             string `json:"cover_url,omitempty"`
+	TrackNumber          int    `json:"track_number,omitempty"`
+	DiscNumber           int    `json:"disc_number,omitempty"`
+	TotalTracks          int    `json:"total_tracks,omitempty"`
+	TotalDiscs           int    `json:"total_discs,omitempty"`
+	Copyright            string `json:"copyright,omitempty"`
+	Publisher            string `json:"publisher,omitempty"`
+	EmbedMaxQualityCover bool   `json:"embed_max_quality_cover,omitempty"`
+	AllowFallback        bool   `json:"allow_fallback,omitempty"`
+	UseFirstArtistOnly   bool   `json:"use_first_artist_only,omitempty"`
+	UseSingleGenre       bool   `json:"use_single_genre,omitempty"`
+	EmbedGenre           bool   `json:"embed_genre,omitempty"`
+	EmbedSourceTags      bool   `json:"embed_source_tags,omitempty"`
+}
+
+func (r TrackRequest) SpotifyURL() string {
+	if r.SpotifyID == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://open.spotify.com/track/%s", r.SpotifyID)
+}
+
+// TrackResult describes where a file came from. API is the mirror that
+// served it, SourceID the track ID on the provider, and RequestedQuality the
+// quality asked for when the provider fell back to a lower Quality.
+type TrackResult struct {
+	Path             string `json:"path"`
+	AlreadyExists    bool   `json:"already_exists,omitempty"`
+	Service          string `json:"service"`
+	API              string `json:"api,omitempty"`
+	Quality          string `json:"quality,omitempty"`
+	RequestedQuality string `json:"requested_quality,omitempty"`
+	SourceID         string `json:"source_id,omitempty"`
+	ISRC             string `json:"isrc,omitempty"`
+}
+
+// QualityFallback reports whether the provider delivered a different quality
+// than requested.
+func (r TrackResult) QualityFallback() bool {
+	return r.RequestedQuality != "" && r.Quality != "" && r.RequestedQuality != r.Quality
+}
+
+// withSourceTags adds the provenance tags to metadata when the request asks
+// for them.
+func withSourceTags(metadata Metadata, req TrackRequest, result *TrackResult) Metadata {
+	if req.EmbedSourceTags && result != nil {
+		metadata.Source = result.Service
+		metadata.SourceID = result.SourceID
+		metadata.SourceQuality = result.Quality
+	}
+	return metadata
+}
+
+// apiHost reduces a mirror base URL to its host for display and history.
+func apiHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+type Provider interface {
+	Name() string
+	Download(ctx context.Context, req TrackRequest) (*TrackResult, error)
+}
+
+var (
+	providerRegistry = map[string]Provider{
+		"tidal":  tidalProvider{},
+		"qobuz":  qobuzProvider{},
+		"amazon": amazonProvider{},
+		"deezer": deezerProvider{},
+	}
+	providerRegistryLock sync.RWMutex
+)
+
+func RegisterProvider(p Provider) {
+	providerRegistryLock.Lock()
+	defer providerRegistryLock.Unlock()
+	providerRegistry[p.Name()] = p
+}
+
+func GetProvider(name string) (Provider, error) {
+	providerRegistryLock.RLock()
+	defer providerRegistryLock.RUnlock()
+
+	p, ok := providerRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown service: %s", name)
+	}
+	return p, nil
+}
+
+func ProviderNames() []string {
+	providerRegistryLock.RLock()
+	defer providerRegistryLock.RUnlock()
+
+	names := make([]string, 0, len(providerRegistry))
+	for name := range providerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -156,7 +157,9 @@ func (q *QobuzDownloader) DownloadFromStandard(apiBase string, trackID int64, qu
 	return "", fmt.Errorf("invalid response")
 }
 
-func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string, allowFallback bool) (string, error) {
+// GetDownloadURL returns the stream URL, the quality actually granted and the
+// mirror that served it.
+func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string, allowFallback bool) (string, string, string, error) {
 	qualityCode := quality
 	if qualityCode == "" || qualityCode == "5" {
 		qualityCode = "6"
@@ -169,9 +172,10 @@ func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string, allowFal
 		"https://dabmusic.xyz/api/stream?trackId=",
 	}
 
-	downloadFunc := func(qual string) (string, error) {
+	downloadFunc := func(qual string) (string, string, error) {
 		type Provider struct {
 			Name string
+			API  string
 			Func func() (string, error)
 		}
 
@@ -181,6 +185,7 @@ func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string, allowFal
 			currentAPI := api
 			providers = append(providers, Provider{
 				Name: "Standard(" + currentAPI + ")",
+				API:  currentAPI,
 				Func: func() (string, error) {
 					return q.DownloadFromStandard(currentAPI, trackID, qual)
 				},
@@ -198,28 +203,28 @@ func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string, allowFal
 			url, err := p.Func()
 			if err == nil {
 				fmt.Printf("✓ Success\n")
-				return url, nil
+				return url, p.API, nil
 			}
 
 			fmt.Printf("Provider failed: %v\n", err)
 			lastErr = err
 		}
-		return "", lastErr
+		return "", "", lastErr
 	}
 
-	url, err := downloadFunc(qualityCode)
+	url, api, err := downloadFunc(qualityCode)
 	if err == nil {
-		return url, nil
+		return url, qualityCode, api, nil
 	}
 
 	currentQuality := qualityCode
 
 	if currentQuality == "27" && allowFallback {
 		fmt.Printf("⚠ Download with quality 27 failed, trying fallback to 7 (24-bit Standard)...\n")
-		url, err := downloadFunc("7")
+		url, api, err := downloadFunc("7")
 		if err == nil {
 			fmt.Println("✓ Success with fallback quality 7")
-			return url, nil
+			return url, "7", api, nil
 		}
 
 		currentQuality = "7"
@@ -227,50 +232,28 @@ func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string, allowFal
 
 	if currentQuality == "7" && allowFallback {
 		fmt.Printf("⚠ Download with quality 7 failed, trying fallback to 6 (16-bit Lossless)...\n")
-		url, err := downloadFunc("6")
+		url, api, err := downloadFunc("6")
 		if err == nil {
 			fmt.Println("✓ Success with fallback quality 6")
-			return url, nil
+			return url, "6", api, nil
 		}
 	}
 
-	return "", fmt.Errorf("all APIs and fallbacks failed. Last error: %v", err)
+	return "", "", "", fmt.Errorf("all APIs and fallbacks failed. Last error: %v", err)
 }
 
-func (q *QobuzDownloader) DownloadFile(url, filepath string) error {
+func (q *QobuzDownloader) DownloadFile(ctx context.Context, url, filepath, itemID string) error {
 	fmt.Println("Starting file download...")
 
 	downloadClient := &http.Client{
-		Timeout: 5 * time.Minute,
-	}
-
-	resp, err := downloadClient.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+		Transport: DownloadTransport,
 	}
 
 	fmt.Printf("Creating file: %s\n", filepath)
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer out.Close()
-
 	fmt.Println("Downloading...")
 
-	pw := NewProgressWriter(out)
-	_, err = io.Copy(pw, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
-	return nil
+	_, err := DownloadToFile(ctx, downloadClient, url, filepath, itemID)
+	return err
 }
 
 func (q *QobuzDownloader) DownloadCoverArt(coverURL, filepath string) error {
@@ -353,30 +336,37 @@ func buildQobuzFilename(title, artist, album, albumArtist, releaseDate string, t
 	return filename + ".flac"
 }
 
-func (q *QobuzDownloader) DownloadTrack(spotifyID, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string, allowFallback bool, useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool) (string, error) {
-	var deezerISRC string
-	if spotifyID != "" {
+func (q *QobuzDownloader) DownloadTrack(ctx context.Context, req TrackRequest) (*TrackResult, error) {
+	if req.SpotifyID != "" {
 		songlinkClient := NewSongLinkClient()
-		isrc, err := songlinkClient.GetISRC(spotifyID)
+		isrc, err := songlinkClient.GetISRC(req.SpotifyID)
 		if err != nil {
-			return "", fmt.Errorf("failed to get ISRC: %v", err)
+			return nil, fmt.Errorf("failed to get ISRC: %v", err)
 		}
-		deezerISRC = isrc
+		req.ISRC = isrc
 	} else {
-		return "", fmt.Errorf("spotify ID is required for Qobuz download")
+		return nil, fmt.Errorf("spotify ID is required for Qobuz download")
 	}
 
-	return q.DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir, quality, filenameFormat, includeTrackNumber, position, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, useAlbumTrackNumber, spotifyCoverURL, embedMaxQualityCover, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks, spotifyTotalDiscs, spotifyCopyright, spotifyPublisher, spotifyURL, allowFallback, useFirstArtistOnly, useSingleGenre, embedGenre)
+	return q.DownloadTrackWithISRC(ctx, req)
 }
 
-func (q *QobuzDownloader) DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string, allowFallback bool, useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool) (string, error) {
+func (q *QobuzDownloader) DownloadTrackWithISRC(ctx context.Context, req TrackRequest) (*TrackResult, error) {
+	deezerISRC := req.ISRC
+	spotifyTrackName := req.TrackName
+	spotifyArtistName := req.ArtistName
+	spotifyAlbumName := req.AlbumName
+	spotifyAlbumArtist := req.AlbumArtist
+	spotifyReleaseDate := req.ReleaseDate
+	spotifyURL := req.SpotifyURL()
+
 	fmt.Printf("Fetching track info for ISRC: %s\n", deezerISRC)
 
 	metaChan := make(chan Metadata, 1)
-	if embedGenre && deezerISRC != "" {
+	if req.EmbedGenre && deezerISRC != "" {
 		go func() {
 			fmt.Println("Fetching MusicBrainz metadata...")
-			if fetchedMeta, err := FetchMusicBrainzMetadata(deezerISRC, spotifyTrackName, spotifyArtistName, spotifyAlbumName, useSingleGenre, embedGenre); err == nil {
+			if fetchedMeta, err := FetchMusicBrainzMetadata(deezerISRC, spotifyTrackName, spotifyArtistName, spotifyAlbumName, req.UseSingleGenre, req.EmbedGenre); err == nil {
 				fmt.Println("✓ MusicBrainz metadata fetched")
 				metaChan <- fetchedMeta
 			} else {
@@ -388,15 +378,15 @@ func (q *QobuzDownloader) DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir
 		close(metaChan)
 	}
 
-	if outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create output directory: %w", err)
+	if req.OutputDir != "." {
+		if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
 
 	track, err := q.searchByISRC(deezerISRC)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	artists := spotifyArtistName
@@ -413,13 +403,13 @@ func (q *QobuzDownloader) DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir
 	fmt.Printf("Quality: %s\n", qualityInfo)
 
 	fmt.Println("Getting download URL...")
-	downloadURL, err := q.GetDownloadURL(track.ID, quality, allowFallback)
+	downloadURL, deliveredQuality, api, err := q.GetDownloadURL(track.ID, req.Quality, req.AllowFallback)
 	if err != nil {
-		return "", fmt.Errorf("failed to get download URL: %w", err)
+		return nil, fmt.Errorf("failed to get download URL: %w", err)
 	}
 
 	if downloadURL == "" {
-		return "", fmt.Errorf("received empty download URL")
+		return nil, fmt.Errorf("received empty download URL")
 	}
 
 	urlPreview := downloadURL
@@ -431,7 +421,7 @@ func (q *QobuzDownloader) DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir
 	safeArtist := sanitizeFilename(artists)
 	safeAlbumArtist := sanitizeFilename(spotifyAlbumArtist)
 
-	if useFirstArtistOnly {
+	if req.UseFirstArtistOnly {
 		safeArtist = sanitizeFilename(GetFirstArtist(artists))
 		safeAlbumArtist = sanitizeFilename(GetFirstArtist(spotifyAlbumArtist))
 	}
@@ -439,27 +429,38 @@ func (q *QobuzDownloader) DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir
 	safeTitle := sanitizeFilename(trackTitle)
 	safeAlbum := sanitizeFilename(albumTitle)
 
-	filename := buildQobuzFilename(safeTitle, safeArtist, safeAlbum, safeAlbumArtist, spotifyReleaseDate, spotifyTrackNumber, spotifyDiscNumber, filenameFormat, includeTrackNumber, position, useAlbumTrackNumber)
-	filepath := filepath.Join(outputDir, filename)
+	filename := buildQobuzFilename(safeTitle, safeArtist, safeAlbum, safeAlbumArtist, spotifyReleaseDate, req.TrackNumber, req.DiscNumber, req.FilenameFormat, req.IncludeTrackNumber, req.Position, req.UseAlbumTrackNumber)
+	filepath := filepath.Join(req.OutputDir, filename)
+
+	result := &TrackResult{
+		Path:             filepath,
+		Service:          "qobuz",
+		API:              apiHost(api),
+		Quality:          deliveredQuality,
+		RequestedQuality: req.Quality,
+		SourceID:         fmt.Sprintf("%d", track.ID),
+		ISRC:             deezerISRC,
+	}
 
 	if fileInfo, err := os.Stat(filepath); err == nil && fileInfo.Size() > 0 {
 		fmt.Printf("File already exists: %s (%.2f MB)\n", filepath, float64(fileInfo.Size())/(1024*1024))
-		return "EXISTS:" + filepath, nil
+		result.AlreadyExists = true
+		return result, nil
 	}
 
 	fmt.Printf("Downloading FLAC file to: %s\n", filepath)
-	if err := q.DownloadFile(downloadURL, filepath); err != nil {
-		return "", fmt.Errorf("failed to download file: %w", err)
+	if err := q.DownloadFile(ctx, downloadURL, filepath, req.ItemID); err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
 	}
 
 	fmt.Printf("Downloaded: %s\n", filepath)
 
 	coverPath := ""
 
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = filepath + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -475,7 +476,7 @@ func (q *QobuzDownloader) DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir
 
 	fmt.Println("Embedding metadata and cover art...")
 
-	trackNumberToEmbed := spotifyTrackNumber
+	trackNumberToEmbed := req.TrackNumber
 	if trackNumberToEmbed == 0 {
 		trackNumberToEmbed = 1
 	}
@@ -487,21 +488,68 @@ func (q *QobuzDownloader) DownloadTrackWithISRC(deezerISRC, spotifyID, outputDir
 		AlbumArtist: spotifyAlbumArtist,
 		Date:        spotifyReleaseDate,
 		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
-		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
+		TotalTracks: req.TotalTracks,
+		DiscNumber:  req.DiscNumber,
+		TotalDiscs:  req.TotalDiscs,
 		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
+		Copyright:   req.Copyright,
+		Publisher:   req.Publisher,
 		Description: "https://github.com/afkarxyz/SpotiFLAC",
 		ISRC:        deezerISRC,
 		Genre:       mbMeta.Genre,
 	}
+	metadata = withSourceTags(metadata, req, result)
 
 	if err := EmbedMetadata(filepath, metadata, coverPath); err != nil {
-		return "", fmt.Errorf("failed to embed metadata: %w", err)
+		return nil, fmt.Errorf("failed to embed metadata: %w", err)
 	}
 
 	fmt.Println("Metadata embedded successfully!")
-	return filepath, nil
+	return result, nil
+}
+
+type qobuzProvider struct{}
+
+func (qobuzProvider) Name() string {
+	return "qobuz"
+}
+
+func (qobuzProvider) Download(ctx context.Context, req TrackRequest) (*TrackResult, error) {
+	if req.Quality == "" {
+		req.Quality = "6"
+	}
+
+	downloader := NewQobuzDownloader()
+	if req.ISRC != "" {
+		return downloader.DownloadTrackWithISRC(ctx, req)
+	}
+	return downloader.DownloadTrack(ctx, req)
+}
+
+func (qobuzProvider) ProbeQuality(ctx context.Context, req TrackRequest) (*QualityProbe, error) {
+	if req.ISRC == "" {
+		return nil, fmt.Errorf("ISRC is required for Qobuz lookup")
+	}
+
+	track, err := NewQobuzDownloader().searchByISRC(req.ISRC)
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &QualityProbe{
+		Quality:    "6",
+		BitDepth:   16,
+		SampleRate: 44100,
+	}
+	if track.HiresStreamable && track.MaximumBitDepth > 0 && track.MaximumSamplingRate > 0 {
+		probe.BitDepth = track.MaximumBitDepth
+		probe.SampleRate = int(track.MaximumSamplingRate * 1000)
+		if probe.BitDepth > 16 {
+			probe.Quality = "7"
+			if probe.SampleRate > 96000 {
+				probe.Quality = "27"
+			}
+		}
+	}
+	return probe, nil
 }
@@ -0,0 +1,125 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type QualityProbe struct {
+	Service    string `json:"service"`
+	Quality    string `json:"quality,omitempty"`
+	BitDepth   int    `json:"bit_depth,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	ServiceURL string `json:"service_url,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (p QualityProbe) Label() string {
+	if p.BitDepth == 0 || p.SampleRate == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d-bit/%.1fkHz", p.BitDepth, float64(p.SampleRate)/1000.0)
+}
+
+type QualityProber interface {
+	ProbeQuality(ctx context.Context, req TrackRequest) (*QualityProbe, error)
+}
+
+type ProviderSelection struct {
+	Service    string         `json:"service"`
+	Quality    string         `json:"quality,omitempty"`
+	ServiceURL string         `json:"service_url,omitempty"`
+	BitDepth   int            `json:"bit_depth"`
+	SampleRate int            `json:"sample_rate"`
+	Reason     string         `json:"reason"`
+	Probes     []QualityProbe `json:"probes"`
+}
+
+func SelectBestProvider(ctx context.Context, candidates []string, req TrackRequest) (*ProviderSelection, error) {
+	probes := make([]QualityProbe, len(candidates))
+	var wg sync.WaitGroup
+	for i, service := range candidates {
+		probes[i] = QualityProbe{Service: service}
+
+		provider, err := GetProvider(service)
+		if err != nil {
+			probes[i].Error = err.Error()
+			continue
+		}
+		prober, ok := provider.(QualityProber)
+		if !ok {
+			probes[i].Error = "quality lookup not supported"
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, prober QualityProber) {
+			defer wg.Done()
+			probe, err := prober.ProbeQuality(ctx, req)
+			if err != nil {
+				probes[i].Error = err.Error()
+				return
+			}
+			probe.Service = probes[i].Service
+			probes[i] = *probe
+		}(i, prober)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	best := -1
+	for i, probe := range probes {
+		if probe.Error != "" {
+			fmt.Printf("  %s: %s\n", probe.Service, probe.Error)
+			continue
+		}
+		fmt.Printf("  %s: %s\n", probe.Service, probe.Label())
+		if best < 0 || betterQuality(probe, probes[best]) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return nil, fmt.Errorf("no provider returned quality information for this track")
+	}
+
+	chosen := probes[best]
+	var others []string
+	for i, probe := range probes {
+		if i == best {
+			continue
+		}
+		if probe.Error != "" {
+			others = append(others, fmt.Sprintf("%s: unavailable", probe.Service))
+		} else {
+			others = append(others, fmt.Sprintf("%s: %s", probe.Service, probe.Label()))
+		}
+	}
+
+	reason := fmt.Sprintf("%s offers %s", chosen.Service, chosen.Label())
+	if len(others) > 0 {
+		reason += fmt.Sprintf(" (%s)", strings.Join(others, ", "))
+	}
+
+	return &ProviderSelection{
+		Service:    chosen.Service,
+		Quality:    chosen.Quality,
+		ServiceURL: chosen.ServiceURL,
+		BitDepth:   chosen.BitDepth,
+		SampleRate: chosen.SampleRate,
+		Reason:     reason,
+		Probes:     probes,
+	}, nil
+}
+
+func betterQuality(a, b QualityProbe) bool {
+	if a.BitDepth != b.BitDepth {
+		return a.BitDepth > b.BitDepth
+	}
+	return a.SampleRate > b.SampleRate
+}
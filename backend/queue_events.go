@@ -0,0 +1,234 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventQueueItemsAdded    = "queue:items-added"
+	EventQueueItemStarted   = "queue:item-started"
+	EventQueueProgress      = "queue:progress"
+	EventQueueItemCompleted = "queue:item-completed"
+	EventQueueItemFailed    = "queue:item-failed"
+	EventQueueItemSkipped   = "queue:item-skipped"
+	EventQueueItemCancelled = "queue:item-cancelled"
+	EventQueueItemRetrying  = "queue:item-retrying"
+	EventQueueSummary       = "queue:summary"
+	EventQueueRefresh       = "queue:refresh"
+
+	queueEventInterval = 250 * time.Millisecond
+)
+
+type QueueEmitter func(name string, data interface{})
+
+type QueueItemProgress struct {
+	ID        string  `json:"id"`
+	Progress  float64 `json:"progress"`
+	Speed     float64 `json:"speed"`
+	TotalSize float64 `json:"total_size"`
+	Percent   float64 `json:"percent"`
+	ETA       float64 `json:"eta_seconds"`
+}
+
+type QueueSummary struct {
+	IsDownloading    bool    `json:"is_downloading"`
+	MBDownloaded     float64 `json:"mb_downloaded"`
+	CurrentSpeed     float64 `json:"current_speed"`
+	TotalDownloaded  float64 `json:"total_downloaded"`
+	SessionStartTime int64   `json:"session_start_time"`
+	QueuedCount      int     `json:"queued_count"`
+	CompletedCount   int     `json:"completed_count"`
+	FailedCount      int     `json:"failed_count"`
+	SkippedCount     int     `json:"skipped_count"`
+	CancelledCount   int     `json:"cancelled_count"`
+	SessionETA       float64 `json:"session_eta_seconds"`
+}
+
+type queueEvent struct {
+	name string
+	item DownloadItem
+}
+
+// Queue changes are buffered and flushed at most every queueEventInterval so
+// large queues and chatty progress writers don't flood the frontend.
+var (
+	queueEmitter     QueueEmitter
+	queueEventsLock  sync.Mutex
+	pendingEvents    []queueEvent
+	pendingProgress  = make(map[string]QueueItemProgress)
+	pendingRefresh   bool
+	queueFlushQueued bool
+)
+
+func SetQueueEmitter(emitter QueueEmitter) {
+	queueEventsLock.Lock()
+	defer queueEventsLock.Unlock()
+	queueEmitter = emitter
+}
+
+func notifyQueueItem(name string, item DownloadItem) {
+	queueEventsLock.Lock()
+	defer queueEventsLock.Unlock()
+
+	if queueEmitter == nil {
+		return
+	}
+	pendingEvents = append(pendingEvents, queueEvent{name: name, item: item})
+	scheduleQueueFlush()
+}
+
+func notifyQueueProgress(item DownloadItem) {
+	queueEventsLock.Lock()
+	defer queueEventsLock.Unlock()
+
+	if queueEmitter == nil {
+		return
+	}
+	pendingProgress[item.ID] = QueueItemProgress{
+		ID:        item.ID,
+		Progress:  item.Progress,
+		Speed:     item.Speed,
+		TotalSize: item.TotalSize,
+		Percent:   item.Percent,
+		ETA:       item.ETA,
+	}
+	scheduleQueueFlush()
+}
+
+func notifyQueueSummary() {
+	queueEventsLock.Lock()
+	defer queueEventsLock.Unlock()
+
+	if queueEmitter == nil {
+		return
+	}
+	scheduleQueueFlush()
+}
+
+func notifyQueueRefresh() {
+	queueEventsLock.Lock()
+	defer queueEventsLock.Unlock()
+
+	if queueEmitter == nil {
+		return
+	}
+	pendingEvents = nil
+	pendingProgress = make(map[string]QueueItemProgress)
+	pendingRefresh = true
+	scheduleQueueFlush()
+}
+
+func scheduleQueueFlush() {
+	if queueFlushQueued {
+		return
+	}
+	queueFlushQueued = true
+	time.AfterFunc(queueEventInterval, flushQueueEvents)
+}
+
+func flushQueueEvents() {
+	queueEventsLock.Lock()
+	emit := queueEmitter
+	events := pendingEvents
+	progress := pendingProgress
+	refresh := pendingRefresh
+	pendingEvents = nil
+	pendingProgress = make(map[string]QueueItemProgress)
+	pendingRefresh = false
+	queueFlushQueued = false
+	queueEventsLock.Unlock()
+
+	if emit == nil {
+		return
+	}
+
+	if refresh {
+		emit(EventQueueRefresh, nil)
+	}
+
+	finished := make(map[string]bool)
+	var added []DownloadItem
+	for _, ev := range events {
+		if ev.name == EventQueueItemsAdded {
+			added = append(added, ev.item)
+			continue
+		}
+		if len(added) > 0 {
+			emit(EventQueueItemsAdded, added)
+			added = nil
+		}
+		emit(ev.name, ev.item)
+
+		switch ev.name {
+		case EventQueueItemStarted:
+			delete(finished, ev.item.ID)
+		default:
+			finished[ev.item.ID] = true
+		}
+	}
+	if len(added) > 0 {
+		emit(EventQueueItemsAdded, added)
+	}
+
+	if len(progress) > 0 {
+		updates := make([]QueueItemProgress, 0, len(progress))
+		for id, p := range progress {
+			if !finished[id] {
+				updates = append(updates, p)
+			}
+		}
+		if len(updates) > 0 {
+			emit(EventQueueProgress, updates)
+		}
+	}
+
+	emit(EventQueueSummary, GetQueueSummary())
+}
+
+func GetQueueSummary() QueueSummary {
+	ResetSessionIfComplete()
+
+	progress := GetDownloadProgress()
+
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
+
+	speedLock.RLock()
+	speed := currentSpeed
+	speedLock.RUnlock()
+
+	totalDownloadedLock.RLock()
+	total := totalDownloaded
+	totalDownloadedLock.RUnlock()
+
+	sessionStartLock.RLock()
+	sessionStart := sessionStartTime
+	sessionStartLock.RUnlock()
+
+	summary := QueueSummary{
+		IsDownloading:    progress.IsDownloading,
+		MBDownloaded:     progress.MBDownloaded,
+		TotalDownloaded:  total,
+		SessionStartTime: sessionStart,
+	}
+	for _, item := range downloadQueue {
+		switch item.Status {
+		case StatusDownloading:
+			speed += item.Speed
+		case StatusQueued, StatusRetrying:
+			summary.QueuedCount++
+		case StatusCompleted:
+			summary.CompletedCount++
+		case StatusFailed, StatusVerificationFailed:
+			summary.FailedCount++
+		case StatusSkipped:
+			summary.SkippedCount++
+		case StatusCancelled:
+			summary.CancelledCount++
+		}
+	}
+	summary.CurrentSpeed = speed
+	summary.SessionETA = sessionETA(speed)
+	return summary
+}
@@ -0,0 +1,225 @@
+package backend
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const downloadQueueBucket = "DownloadQueue"
+
+type PersistedDownload struct {
+	Item     DownloadItem    `json:"item"`
+	Provider string          `json:"provider,omitempty"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	QueuedAt int64           `json:"queued_at"`
+
+	// PartFiles are the .part files the item's downloads may have left
+	// behind. They are removed with the entry.
+	PartFiles []string `json:"part_files,omitempty"`
+}
+
+func SavePersistedDownload(item DownloadItem, provider string, request json.RawMessage) error {
+	if historyDB == nil {
+		return nil
+	}
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(downloadQueueBucket))
+		if err != nil {
+			return err
+		}
+
+		entry := PersistedDownload{
+			Item:     item,
+			Provider: provider,
+			Request:  request,
+			QueuedAt: time.Now().UnixNano(),
+		}
+		if v := b.Get([]byte(item.ID)); v != nil {
+			var existing PersistedDownload
+			if json.Unmarshal(v, &existing) == nil {
+				if existing.QueuedAt > 0 {
+					entry.QueuedAt = existing.QueuedAt
+				}
+				entry.PartFiles = existing.PartFiles
+			}
+		}
+
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(item.ID), buf)
+	})
+}
+
+func updatePersistedDownloadItem(item DownloadItem) {
+	if historyDB == nil {
+		return
+	}
+	historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(downloadQueueBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(item.ID))
+		if v == nil {
+			return nil
+		}
+
+		var entry PersistedDownload
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		entry.Item = item
+
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(item.ID), buf)
+	})
+}
+
+func DeletePersistedDownload(id string) error {
+	if historyDB == nil {
+		return nil
+	}
+	var parts []string
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(downloadQueueBucket))
+		if b == nil {
+			return nil
+		}
+		var entry PersistedDownload
+		if v := b.Get([]byte(id)); v != nil && json.Unmarshal(v, &entry) == nil {
+			parts = entry.PartFiles
+		}
+		return b.Delete([]byte(id))
+	})
+	for _, part := range parts {
+		removePart(part)
+	}
+	return err
+}
+
+// trackPartFile records a .part file on the item's persisted entry.
+func trackPartFile(id, partPath string) {
+	if historyDB == nil || id == "" {
+		return
+	}
+	historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(downloadQueueBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+
+		var entry PersistedDownload
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		for _, part := range entry.PartFiles {
+			if part == partPath {
+				return nil
+			}
+		}
+		entry.PartFiles = append(entry.PartFiles, partPath)
+
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), buf)
+	})
+}
+
+// RemovePartFiles deletes the .part files left by an item that will not be
+// retried, keeping its entry so it can still be retried by hand.
+func RemovePartFiles(id string) {
+	if historyDB == nil {
+		return
+	}
+	var parts []string
+	historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(downloadQueueBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+
+		var entry PersistedDownload
+		if err := json.Unmarshal(v, &entry); err != nil || len(entry.PartFiles) == 0 {
+			return err
+		}
+		parts = entry.PartFiles
+		entry.PartFiles = nil
+
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), buf)
+	})
+	for _, part := range parts {
+		removePart(part)
+	}
+}
+
+func GetPersistedDownloads() ([]PersistedDownload, error) {
+	if historyDB == nil {
+		return nil, nil
+	}
+	var entries []PersistedDownload
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(downloadQueueBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var entry PersistedDownload
+			if err := json.Unmarshal(v, &entry); err == nil {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].QueuedAt < entries[j].QueuedAt
+	})
+	return entries, err
+}
+
+func ClearPersistedDownloads() error {
+	if historyDB == nil {
+		return nil
+	}
+	var parts []string
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(downloadQueueBucket))
+		if b == nil {
+			return nil
+		}
+		b.ForEach(func(k, v []byte) error {
+			var entry PersistedDownload
+			if json.Unmarshal(v, &entry) == nil {
+				parts = append(parts, entry.PartFiles...)
+			}
+			return nil
+		})
+		return tx.DeleteBucket([]byte(downloadQueueBucket))
+	})
+	for _, part := range parts {
+		removePart(part)
+	}
+	return err
+}
@@ -0,0 +1,262 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	resumableMaxAttempts = 5
+	downloadUserAgent    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/145.0.0.0 Safari/537.36"
+)
+
+type downloadStatusError struct {
+	StatusCode int
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("download failed with status %d", e.StatusCode)
+}
+
+func (e *downloadStatusError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+}
+
+// partInfo records what a .part file was downloaded from, so a later call
+// (a queue retry, or a download resumed after a restart) can check that the
+// server still has the same file before appending to it. Stream URLs are
+// usually signed per request, so the URL itself is not compared.
+type partInfo struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Total        int64  `json:"total"`
+}
+
+func readPartInfo(partPath string) (partInfo, bool) {
+	var info partInfo
+	data, err := os.ReadFile(partPath + ".json")
+	if err != nil || json.Unmarshal(data, &info) != nil {
+		return info, false
+	}
+	return info, info.Total > 0 || info.ETag != "" || info.LastModified != ""
+}
+
+func writePartInfo(partPath string, info partInfo) {
+	if data, err := json.Marshal(info); err == nil {
+		os.WriteFile(partPath+".json", data, 0644)
+	}
+}
+
+func removePart(partPath string) {
+	os.Remove(partPath)
+	os.Remove(partPath + ".json")
+}
+
+// ifRangeValidator returns the If-Range value for a resume. Weak ETags are
+// not allowed in If-Range, so those fall back to Last-Modified.
+func (p partInfo) ifRangeValidator() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+// DownloadToFile streams url into destPath via destPath+".part". Interrupted
+// transfers are resumed with Range requests when the server supports them,
+// including across calls: the .part is kept after a failure and only reused
+// if the server confirms it is still the same file (If-Range, and a matching
+// Content-Range total). The finished file is checked against the advertised
+// length before it is renamed into place. Cancelling discards the .part.
+// The .part is recorded on the queue item so it is removed once the item is
+// given up on or cleared.
+func DownloadToFile(ctx context.Context, client *http.Client, url, destPath, itemID string) (int64, error) {
+	partPath := destPath + ".part"
+	if _, err := os.Stat(partPath); err == nil {
+		if _, ok := readPartInfo(partPath); !ok {
+			removePart(partPath)
+		}
+	}
+	trackPartFile(itemID, partPath)
+
+	var lastErr error
+	for attempt := 1; attempt <= resumableMaxAttempts; attempt++ {
+		if attempt > 1 {
+			wait := time.Duration(attempt-1) * 2 * time.Second
+			fmt.Printf("\n⚠ Download interrupted (%v), resuming in %v (attempt %d/%d)...\n", lastErr, wait, attempt, resumableMaxAttempts)
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			removePart(partPath)
+			return 0, err
+		}
+
+		size, err := downloadPart(ctx, client, url, partPath, itemID)
+		if err == nil {
+			if err := os.Rename(partPath, destPath); err != nil {
+				removePart(partPath)
+				return 0, fmt.Errorf("failed to finalize file: %w", err)
+			}
+			os.Remove(partPath + ".json")
+			fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(size)/(1024*1024))
+			return size, nil
+		}
+
+		lastErr = err
+		if ctx.Err() != nil {
+			removePart(partPath)
+			return 0, ctx.Err()
+		}
+		if statusErr, ok := err.(*downloadStatusError); ok && !statusErr.retryable() {
+			break
+		}
+	}
+
+	return 0, lastErr
+}
+
+func downloadPart(ctx context.Context, client *http.Client, url, partPath, itemID string) (int64, error) {
+	var offset int64
+	part, _ := readPartInfo(partPath)
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", downloadUserAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator := part.ifRangeValidator(); validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	expected := int64(-1)
+	flags := os.O_CREATE | os.O_WRONLY
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if offset > 0 {
+			fmt.Println("\nServer does not support resuming, restarting download...")
+		}
+		offset = 0
+		flags |= os.O_TRUNC
+		expected = resp.ContentLength
+		writePartInfo(partPath, partInfo{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Total:        expected,
+		})
+	case http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			removePart(partPath)
+			return 0, fmt.Errorf("unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		if part.Total > 0 && total >= 0 && total != part.Total {
+			removePart(partPath)
+			return 0, fmt.Errorf("file changed on server (%d bytes, had %d), restarting download", total, part.Total)
+		}
+		flags |= os.O_APPEND
+		expected = total
+		if expected < 0 && resp.ContentLength >= 0 {
+			expected = offset + resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		_, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if ok && total == offset {
+			return offset, nil
+		}
+		removePart(partPath)
+		return 0, fmt.Errorf("server rejected resume at offset %d", offset)
+	default:
+		return 0, &downloadStatusError{StatusCode: resp.StatusCode}
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	pw := NewProgressWriterWithID(out, itemID)
+	pw.total = offset
+	pw.lastPrinted = offset
+	pw.lastBytes = offset
+	if expected > 0 {
+		pw.SetExpectedSize(expected)
+	}
+
+	_, copyErr := io.Copy(pw, resp.Body)
+	closeErr := out.Close()
+	if copyErr != nil {
+		return 0, fmt.Errorf("failed to write file: %w", copyErr)
+	}
+	if closeErr != nil {
+		return 0, fmt.Errorf("failed to write file: %w", closeErr)
+	}
+
+	written := pw.GetTotal()
+	if expected >= 0 && written != expected {
+		if written > expected {
+			removePart(partPath)
+		}
+		return 0, fmt.Errorf("incomplete download: got %d of %d bytes", written, expected)
+	}
+
+	return written, nil
+}
+
+func parseContentRange(header string) (start, total int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, -1, false
+	}
+	spec := strings.TrimPrefix(header, "bytes ")
+
+	slash := strings.Index(spec, "/")
+	if slash < 0 {
+		return 0, -1, false
+	}
+	rangePart, totalPart := spec[:slash], spec[slash+1:]
+
+	total = -1
+	if totalPart != "*" {
+		n, err := strconv.ParseInt(totalPart, 10, 64)
+		if err != nil {
+			return 0, -1, false
+		}
+		total = n
+	}
+
+	if rangePart == "*" {
+		return 0, total, true
+	}
+	dash := strings.Index(rangePart, "-")
+	if dash < 0 {
+		return 0, -1, false
+	}
+	start, err := strconv.ParseInt(rangePart[:dash], 10, 64)
+	if err != nil {
+		return 0, -1, false
+	}
+	return start, total, true
+}
@@ -0,0 +1,273 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// rangeServer serves content with Range and If-Range support. When cutAt is
+// set, the first full response is cut off after that many bytes.
+type rangeServer struct {
+	mu      sync.Mutex
+	content []byte
+	etag    string
+	cutAt   int
+	ranges  []string
+}
+
+func (s *rangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	content, etag, cutAt := s.content, s.etag, s.cutAt
+	s.cutAt = 0
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", etag)
+	start := 0
+	if rng := r.Header.Get("Range"); rng != "" && (r.Header.Get("If-Range") == "" || r.Header.Get("If-Range") == etag) {
+		start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)-start))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	body := content[start:]
+	if cutAt > 0 && cutAt < len(body) {
+		// Hijack so the client sees the connection drop mid-body.
+		w.Write(body[:cutAt])
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+		return
+	}
+	w.Write(body)
+}
+
+func (s *rangeServer) requestedRanges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+func testContent(n int) []byte {
+	return bytes.Repeat([]byte("0123456789abcdef"), n/16)
+}
+
+func TestDownloadToFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		part       []byte
+		partInfo   *partInfo
+		etag       string
+		cutAt      int
+		wantRanges []string
+	}{
+		{
+			name:       "fresh download",
+			etag:       `"v1"`,
+			wantRanges: []string{""},
+		},
+		{
+			name:       "resumes within a call after a dropped connection",
+			etag:       `"v1"`,
+			cutAt:      1024,
+			wantRanges: []string{"", "bytes=1024-"},
+		},
+		{
+			name:       "resumes a part left by an earlier call",
+			part:       testContent(4096)[:2048],
+			partInfo:   &partInfo{ETag: `"v1"`, Total: 4096},
+			etag:       `"v1"`,
+			wantRanges: []string{"bytes=2048-"},
+		},
+		{
+			name:       "restarts when the file changed on the server",
+			part:       bytes.Repeat([]byte("x"), 2048),
+			partInfo:   &partInfo{ETag: `"old"`, Total: 4096},
+			etag:       `"v1"`,
+			wantRanges: []string{"bytes=2048-"},
+		},
+		{
+			name:       "discards a part without recorded validators",
+			part:       bytes.Repeat([]byte("x"), 2048),
+			etag:       `"v1"`,
+			wantRanges: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := testContent(4096)
+			srv := &rangeServer{content: content, etag: tt.etag, cutAt: tt.cutAt}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+
+			dest := filepath.Join(t.TempDir(), "track.flac")
+			if tt.part != nil {
+				os.WriteFile(dest+".part", tt.part, 0644)
+			}
+			if tt.partInfo != nil {
+				writePartInfo(dest+".part", *tt.partInfo)
+			}
+
+			size, err := DownloadToFile(context.Background(), ts.Client(), ts.URL, dest, "")
+			if err != nil {
+				t.Fatalf("DownloadToFile: %v", err)
+			}
+			if size != int64(len(content)) {
+				t.Errorf("size = %d, want %d", size, len(content))
+			}
+			got, _ := os.ReadFile(dest)
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloaded content differs from source")
+			}
+			if _, err := os.Stat(dest + ".part"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf(".part left behind")
+			}
+			if _, err := os.Stat(dest + ".part.json"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf(".part.json left behind")
+			}
+			if ranges := srv.requestedRanges(); fmt.Sprint(ranges) != fmt.Sprint(tt.wantRanges) {
+				t.Errorf("ranges = %q, want %q", ranges, tt.wantRanges)
+			}
+		})
+	}
+}
+
+func TestDownloadToFileKeepsPartAfterFailure(t *testing.T) {
+	content := testContent(4096)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			// A non-retryable status ends the call after the first resume.
+			http.Error(w, "gone", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content[:1000])
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer ts.Close()
+
+	dest := filepath.Join(t.TempDir(), "track.flac")
+	if _, err := DownloadToFile(context.Background(), ts.Client(), ts.URL, dest, ""); err == nil {
+		t.Fatal("expected an error")
+	}
+	if info, err := os.Stat(dest + ".part"); err != nil || info.Size() != 1000 {
+		t.Errorf(".part not kept after a failed download: %v", err)
+	}
+	if info, ok := readPartInfo(dest + ".part"); !ok || info.ETag != `"v1"` || info.Total != int64(len(content)) {
+		t.Errorf("part info = %+v, %v", info, ok)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header string
+		start  int64
+		total  int64
+		ok     bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */500", 0, 500, true},
+		{"items 0-1/2", 0, -1, false},
+		{"bytes 5-9", 0, -1, false},
+		{"bytes x-9/10", 0, -1, false},
+	}
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.header)
+		if start != tt.start || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v", tt.header, start, total, ok, tt.start, tt.total, tt.ok)
+		}
+	}
+}
+
+func TestPartFilesRemovedWithQueueItem(t *testing.T) {
+	openTestHistoryDB(t)
+	dir := t.TempDir()
+
+	leavePart := func(id string) string {
+		t.Helper()
+		if err := SavePersistedDownload(DownloadItem{ID: id}, "tidal", nil); err != nil {
+			t.Fatal(err)
+		}
+		var requests atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) > 1 {
+				http.Error(w, "gone", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", "2000")
+			w.Write(make([]byte, 1000))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}))
+		defer ts.Close()
+
+		dest := filepath.Join(dir, id+".flac")
+		if _, err := DownloadToFile(context.Background(), ts.Client(), ts.URL, dest, id); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := os.Stat(dest + ".part"); err != nil {
+			t.Fatal(err)
+		}
+		return dest + ".part"
+	}
+
+	tests := []struct {
+		name   string
+		remove func(id string)
+		kept   bool
+	}{
+		{"deleted entry", func(id string) { DeletePersistedDownload(id) }, false},
+		{"given up", RemovePartFiles, true},
+		{"whole queue cleared", func(string) { ClearPersistedDownloads() }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := strings.ReplaceAll(tt.name, " ", "-")
+			part := leavePart(id)
+			// Saving the item again must not forget its parts.
+			SavePersistedDownload(DownloadItem{ID: id, Status: StatusFailed}, "tidal", nil)
+
+			tt.remove(id)
+			for _, path := range []string{part, part + ".json"} {
+				if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("%s still exists: %v", filepath.Base(path), err)
+				}
+			}
+			entries, _ := GetPersistedDownloads()
+			var found bool
+			for _, entry := range entries {
+				if entry.Item.ID == id {
+					found = true
+					if len(entry.PartFiles) != 0 {
+						t.Errorf("PartFiles = %v after removal", entry.PartFiles)
+					}
+				}
+			}
+			if found != tt.kept {
+				t.Errorf("entry kept = %v, want %v", found, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"syscall"
+)
+
+const (
+	RetryClassRateLimit = "rate_limit"
+	RetryClassServer    = "server"
+	RetryClassTimeout   = "timeout"
+	RetryClassNetwork   = "network"
+)
+
+var (
+	serverStatusPattern = regexp.MustCompile(`(?i)(status|http|code)[^0-9]{0,12}5\d\d\b`)
+
+	rateLimitMarkers = []string{"429", "too many requests", "rate limit", "rate-limit"}
+	timeoutMarkers   = []string{"timeout", "timed out", "deadline exceeded", "status 408"}
+	serverMarkers    = []string{"bad gateway", "service unavailable", "internal server error", "gateway time"}
+	networkMarkers   = []string{"connection reset", "connection refused", "broken pipe", "no such host", "eof", "network is unreachable", "tls handshake", "incomplete download", "download interrupted"}
+)
+
+// ClassifyDownloadError maps a download error to the transient error class
+// it belongs to, or "" when retrying is unlikely to help. Typed errors are
+// checked first; the message is only matched when none of them apply.
+func ClassifyDownloadError(err error) string {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ""
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if class := ClassifyDownloadError(e); class != "" {
+				return class
+			}
+		}
+		return ""
+	}
+
+	var statusErr *downloadStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return RetryClassRateLimit
+		case statusErr.StatusCode == http.StatusRequestTimeout:
+			return RetryClassTimeout
+		case statusErr.StatusCode >= 500:
+			return RetryClassServer
+		}
+		return ""
+	}
+
+	if errors.Is(err, ErrDownloadStalled) || errors.Is(err, context.DeadlineExceeded) {
+		return RetryClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return RetryClassTimeout
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return RetryClassNetwork
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) {
+		return RetryClassNetwork
+	}
+
+	return classifyDownloadMessage(err.Error())
+}
+
+// classifyDownloadMessage is the fallback for errors that only carry a
+// message, such as API errors formatted by the providers.
+func classifyDownloadMessage(message string) string {
+	msg := strings.ToLower(message)
+	if msg == "" {
+		return ""
+	}
+
+	if containsAny(msg, rateLimitMarkers) {
+		return RetryClassRateLimit
+	}
+	if containsAny(msg, timeoutMarkers) {
+		return RetryClassTimeout
+	}
+	if serverStatusPattern.MatchString(msg) || containsAny(msg, serverMarkers) {
+		return RetryClassServer
+	}
+	if containsAny(msg, networkMarkers) {
+		return RetryClassNetwork
+	}
+	return ""
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, m := range markers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyDownloadError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"cancelled", fmt.Errorf("failed to download file: %w", context.Canceled), ""},
+		{"status 429", &downloadStatusError{StatusCode: 429}, RetryClassRateLimit},
+		{"status 408", &downloadStatusError{StatusCode: 408}, RetryClassTimeout},
+		{"status 503 wrapped", fmt.Errorf("failed to download file: %w", &downloadStatusError{StatusCode: 503}), RetryClassServer},
+		{"status 404", &downloadStatusError{StatusCode: 404}, ""},
+		{"stalled", fmt.Errorf("failed to write file: %w", fmt.Errorf("%w: no data for 30s", ErrDownloadStalled)), RetryClassTimeout},
+		{"deadline", context.DeadlineExceeded, RetryClassTimeout},
+		{"url timeout", &url.Error{Op: "Get", URL: "https://example.com", Err: os.ErrDeadlineExceeded}, RetryClassTimeout},
+		{"unexpected eof", fmt.Errorf("failed to write file: %w", io.ErrUnexpectedEOF), RetryClassNetwork},
+		{"connection reset", &url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}, RetryClassNetwork},
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.invalid"}, RetryClassNetwork},
+		{"message rate limit", errors.New("API returned HTTP 429"), RetryClassRateLimit},
+		{"message server", errors.New("API returned status: 502"), RetryClassServer},
+		{"message not found", errors.New("track not found on qobuz"), ""},
+		{"status 404 message mentions eof", fmt.Errorf("%w (eof)", &downloadStatusError{StatusCode: 404}), ""},
+		{"joined uses first transient attempt", errors.Join(errors.New("track not found"), &downloadStatusError{StatusCode: 500}), RetryClassServer},
+		{"joined without transient attempt", errors.Join(errors.New("track not found"), &downloadStatusError{StatusCode: 403}), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyDownloadError(tt.err); got != tt.want {
+				t.Errorf("ClassifyDownloadError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
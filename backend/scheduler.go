@@ -0,0 +1,191 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+type DownloadJob struct {
+	ItemID   string          `json:"item_id"`
+	Provider string          `json:"provider"`
+	Request  json.RawMessage `json:"request"`
+}
+
+type DownloadHandler func(job DownloadJob)
+
+// DownloadScheduler runs jobs up to a global limit. Per-service limits are
+// held by the provider that is actually downloading (see AcquireProvider),
+// so auto, best and fallback attempts count against the service in use.
+// Job.Provider only keeps a job queued while its own service is full.
+type DownloadScheduler struct {
+	mu               sync.Mutex
+	idle             *sync.Cond
+	pending          []DownloadJob
+	running          int
+	runningByService map[string]int
+	maxConcurrent    int
+	serviceLimits    map[string]int
+	handler          DownloadHandler
+}
+
+const DefaultMaxConcurrentDownloads = 3
+
+func DefaultServiceLimits() map[string]int {
+	return map[string]int{
+		"tidal":  2,
+		"qobuz":  2,
+		"amazon": 1,
+		"deezer": 1,
+	}
+}
+
+func NewDownloadScheduler(maxConcurrent int, serviceLimits map[string]int, handler DownloadHandler) *DownloadScheduler {
+	s := &DownloadScheduler{
+		runningByService: make(map[string]int),
+		handler:          handler,
+	}
+	s.idle = sync.NewCond(&s.mu)
+	s.SetLimits(maxConcurrent, serviceLimits)
+	return s
+}
+
+func (s *DownloadScheduler) SetLimits(maxConcurrent int, serviceLimits map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultMaxConcurrentDownloads
+	}
+	s.maxConcurrent = maxConcurrent
+
+	s.serviceLimits = make(map[string]int, len(serviceLimits))
+	for service, limit := range serviceLimits {
+		s.serviceLimits[service] = limit
+	}
+
+	s.dispatch()
+}
+
+func (s *DownloadScheduler) Enqueue(jobs ...DownloadJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, jobs...)
+	s.dispatch()
+}
+
+func (s *DownloadScheduler) Remove(itemID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, job := range s.pending {
+		if job.ItemID == itemID {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.idle.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+func (s *DownloadScheduler) Clear() []DownloadJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := s.pending
+	s.pending = nil
+	s.idle.Broadcast()
+	return removed
+}
+
+func (s *DownloadScheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+func (s *DownloadScheduler) Running() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func (s *DownloadScheduler) Wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.running > 0 || len(s.pending) > 0 {
+		s.idle.Wait()
+	}
+}
+
+func (s *DownloadScheduler) dispatch() {
+	remaining := s.pending[:0]
+	for _, job := range s.pending {
+		if s.running >= s.maxConcurrent || !s.hasCapacity(job.Provider) {
+			remaining = append(remaining, job)
+			continue
+		}
+
+		s.running++
+		go s.run(job)
+	}
+	for i := len(remaining); i < len(s.pending); i++ {
+		s.pending[i] = DownloadJob{}
+	}
+	s.pending = remaining
+}
+
+func (s *DownloadScheduler) hasCapacity(service string) bool {
+	limit, ok := s.serviceLimits[service]
+	if !ok || limit <= 0 {
+		return true
+	}
+	return s.runningByService[service] < limit
+}
+
+func (s *DownloadScheduler) run(job DownloadJob) {
+	defer func() {
+		s.mu.Lock()
+		s.running--
+		s.dispatch()
+		s.idle.Broadcast()
+		s.mu.Unlock()
+	}()
+
+	s.handler(job)
+}
+
+// AcquireProvider waits for a free slot of the service and returns the
+// function that releases it. Services without a limit never wait.
+func (s *DownloadScheduler) AcquireProvider(ctx context.Context, service string) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		s.idle.Broadcast()
+		s.mu.Unlock()
+	})
+	defer stop()
+
+	for !s.hasCapacity(service) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		s.idle.Wait()
+	}
+	s.runningByService[service]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.runningByService[service]--
+			s.dispatch()
+			s.idle.Broadcast()
+			s.mu.Unlock()
+		})
+	}, nil
+}
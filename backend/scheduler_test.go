@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadSchedulerProviderLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		global    int
+		limits    map[string]int
+		jobs      []DownloadJob
+		uses      map[string]string // item -> service that downloads it
+		wantPeaks map[string]int
+	}{
+		{
+			name:   "auto jobs count against the service they download from",
+			global: 6,
+			limits: map[string]int{"tidal": 2},
+			jobs: []DownloadJob{
+				{ItemID: "1", Provider: "auto"}, {ItemID: "2", Provider: "auto"},
+				{ItemID: "3", Provider: "best"}, {ItemID: "4", Provider: "auto"},
+			},
+			uses:      map[string]string{"1": "tidal", "2": "tidal", "3": "tidal", "4": "tidal"},
+			wantPeaks: map[string]int{"tidal": 2},
+		},
+		{
+			name:   "fallback uses the fallback service's slot",
+			global: 6,
+			limits: map[string]int{"qobuz": 2, "tidal": 1},
+			jobs: []DownloadJob{
+				{ItemID: "1", Provider: "qobuz"}, {ItemID: "2", Provider: "qobuz"}, {ItemID: "3", Provider: "qobuz"},
+			},
+			uses:      map[string]string{"1": "tidal", "2": "tidal", "3": "tidal"},
+			wantPeaks: map[string]int{"tidal": 1, "qobuz": 0},
+		},
+		{
+			name:   "global limit",
+			global: 2,
+			limits: map[string]int{},
+			jobs: []DownloadJob{
+				{ItemID: "1", Provider: "amazon"}, {ItemID: "2", Provider: "deezer"}, {ItemID: "3", Provider: "tidal"},
+			},
+			uses:      map[string]string{"1": "amazon", "2": "deezer", "3": "tidal"},
+			wantPeaks: map[string]int{"*": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			active := make(map[string]int)
+			peaks := make(map[string]int)
+			enter := func(key string) {
+				mu.Lock()
+				active[key]++
+				if active[key] > peaks[key] {
+					peaks[key] = active[key]
+				}
+				mu.Unlock()
+			}
+			leave := func(key string) {
+				mu.Lock()
+				active[key]--
+				mu.Unlock()
+			}
+
+			var s *DownloadScheduler
+			s = NewDownloadScheduler(tt.global, tt.limits, func(job DownloadJob) {
+				enter("*")
+				defer leave("*")
+				service := tt.uses[job.ItemID]
+				release, err := s.AcquireProvider(context.Background(), service)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				enter(service)
+				time.Sleep(20 * time.Millisecond)
+				leave(service)
+				release()
+			})
+			s.Enqueue(tt.jobs...)
+			s.Wait()
+
+			for key, want := range tt.wantPeaks {
+				if peaks[key] != want {
+					t.Errorf("peak %s = %d, want %d", key, peaks[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadSchedulerHoldsJobsForFullProvider(t *testing.T) {
+	s := NewDownloadScheduler(3, map[string]int{"qobuz": 1}, func(DownloadJob) {})
+	release, err := s.AcquireProvider(context.Background(), "qobuz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan string, 2)
+	s.handler = func(job DownloadJob) { started <- job.ItemID }
+	s.Enqueue(DownloadJob{ItemID: "q", Provider: "qobuz"}, DownloadJob{ItemID: "a", Provider: "auto"})
+	if id := <-started; id != "a" {
+		t.Fatalf("started %s first, want the auto job", id)
+	}
+	if s.Pending() != 1 {
+		t.Errorf("Pending = %d, want the qobuz job held", s.Pending())
+	}
+
+	release()
+	release()
+	if id := <-started; id != "q" {
+		t.Errorf("started %s, want q", id)
+	}
+	s.Wait()
+}
+
+func TestAcquireProviderCancel(t *testing.T) {
+	s := NewDownloadScheduler(1, map[string]int{"tidal": 1}, func(DownloadJob) {})
+	release, err := s.AcquireProvider(context.Background(), "tidal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	if r, err := s.AcquireProvider(context.Background(), "deezer"); err != nil {
+		t.Errorf("unlimited service: %v", err)
+	} else {
+		r()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.AcquireProvider(ctx, "tidal"); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
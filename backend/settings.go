@@ -0,0 +1,451 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// SettingsVersion is the schema version written to config.json. Files
+// without a version field are treated as version 1.
+const SettingsVersion = 2
+
+type Settings struct {
+	Version                  int            `json:"version"`
+	DownloadPath             string         `json:"downloadPath"`
+	Downloader               string         `json:"downloader"`
+	Theme                    string         `json:"theme"`
+	ThemeMode                string         `json:"themeMode"`
+	FontFamily               string         `json:"fontFamily"`
+	FolderPreset             string         `json:"folderPreset"`
+	FolderTemplate           string         `json:"folderTemplate"`
+	FilenamePreset           string         `json:"filenamePreset"`
+	FilenameTemplate         string         `json:"filenameTemplate"`
+	TrackNumber              bool           `json:"trackNumber"`
+	SfxEnabled               bool           `json:"sfxEnabled"`
+	EmbedLyrics              bool           `json:"embedLyrics"`
+	EmbedMaxQualityCover     bool           `json:"embedMaxQualityCover"`
+	OperatingSystem          string         `json:"operatingSystem"`
+	TidalQuality             string         `json:"tidalQuality"`
+	QobuzQuality             string         `json:"qobuzQuality"`
+	AmazonQuality            string         `json:"amazonQuality"`
+	AutoOrder                string         `json:"autoOrder"`
+	AutoQuality              string         `json:"autoQuality"`
+	FallbackOrder            []string       `json:"fallbackOrder,omitempty"`
+	VerifyDownloads          bool           `json:"verifyDownloads"`
+	PlaylistSyncRemoved      string         `json:"playlistSyncRemoved"`
+	PlaylistSyncArchiveDir   string         `json:"playlistSyncArchiveDir"`
+	ArtistWatchIntervalHours float64        `json:"artistWatchIntervalHours"`
+	BandwidthLimitMBps       float64        `json:"bandwidthLimitMBps"`
+	RetryMaxAttempts         int            `json:"retryMaxAttempts"`
+	RetryBaseDelaySeconds    float64        `json:"retryBaseDelaySeconds"`
+	RetryErrorClasses        []string       `json:"retryErrorClasses"`
+	MaxConcurrentDownloads   int            `json:"maxConcurrentDownloads"`
+	ServiceConcurrency       map[string]int `json:"serviceConcurrency"`
+	AllowFallback            bool           `json:"allowFallback"`
+	UseSpotFetchAPI          bool           `json:"useSpotFetchAPI"`
+	SpotFetchAPIUrl          string         `json:"spotFetchAPIUrl"`
+	CreatePlaylistFolder     bool           `json:"createPlaylistFolder"`
+	CreateM3u8File           bool           `json:"createM3u8File"`
+	UseFirstArtistOnly       bool           `json:"useFirstArtistOnly"`
+	UseSingleGenre           bool           `json:"useSingleGenre"`
+	EmbedGenre               bool           `json:"embedGenre"`
+	EmbedSourceTags          bool           `json:"embedSourceTags"`
+}
+
+var (
+	templatePlaceholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+	templatePlaceholders       = map[string]bool{
+		"title": true, "artist": true, "album": true, "album_artist": true,
+		"track": true, "disc": true, "year": true, "date": true,
+		"playlist": true, "creator": true,
+	}
+
+	themeModes             = []string{"auto", "light", "dark"}
+	tidalQualities         = []string{"LOSSLESS", "HI_RES_LOSSLESS"}
+	qobuzQualities         = []string{"6", "7", "27"}
+	autoQualities          = []string{"16", "24"}
+	playlistSyncModes      = []string{"archive", "delete"}
+	retryErrorClasses      = []string{RetryClassRateLimit, RetryClassServer, RetryClassTimeout, RetryClassNetwork}
+	defaultAutoOrder       = "tidal-qobuz-amazon-deezer"
+	defaultSpotFetchAPIURL = "https://spotify.afkarxyz.fun/api"
+)
+
+func DefaultSettings() Settings {
+	operatingSystem := "linux/MacOS"
+	if runtime.GOOS == "windows" {
+		operatingSystem = "Windows"
+	}
+
+	return Settings{
+		Version:                  SettingsVersion,
+		DownloadPath:             GetDefaultMusicPath(),
+		Downloader:               "auto",
+		Theme:                    "yellow",
+		ThemeMode:                "auto",
+		FontFamily:               "google-sans",
+		FolderPreset:             "none",
+		FilenamePreset:           "title-artist",
+		FilenameTemplate:         "{title} - {artist}",
+		SfxEnabled:               true,
+		OperatingSystem:          operatingSystem,
+		TidalQuality:             "LOSSLESS",
+		QobuzQuality:             "6",
+		AmazonQuality:            "original",
+		AutoOrder:                defaultAutoOrder,
+		AutoQuality:              "16",
+		VerifyDownloads:          true,
+		PlaylistSyncRemoved:      "archive",
+		PlaylistSyncArchiveDir:   "_archive",
+		ArtistWatchIntervalHours: 6,
+		RetryMaxAttempts:         3,
+		RetryBaseDelaySeconds:    30,
+		RetryErrorClasses:        append([]string(nil), retryErrorClasses...),
+		MaxConcurrentDownloads:   DefaultMaxConcurrentDownloads,
+		ServiceConcurrency:       DefaultServiceLimits(),
+		AllowFallback:            true,
+		SpotFetchAPIUrl:          defaultSpotFetchAPIURL,
+		CreatePlaylistFolder:     true,
+		EmbedGenre:               true,
+	}
+}
+
+// ParseSettings migrates a raw settings object to the current schema and
+// decodes it over the defaults, so missing keys keep their default value.
+// The returned version is the schema version the input was written with.
+// A mistyped value is reported as a *json.UnmarshalTypeError; the rest of
+// the settings are still decoded.
+func ParseSettings(raw map[string]interface{}) (Settings, int, error) {
+	settings := DefaultSettings()
+	if raw == nil {
+		return settings, SettingsVersion, nil
+	}
+
+	from := MigrateSettings(raw)
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return settings, from, err
+	}
+	err = json.Unmarshal(data, &settings)
+	settings.Version = SettingsVersion
+	if settings.DownloadPath == "" {
+		settings.DownloadPath = GetDefaultMusicPath()
+	}
+	return settings, from, err
+}
+
+var settingsMigrations = map[int]func(map[string]interface{}){
+	1: migrateSettingsV1,
+}
+
+// MigrateSettings upgrades raw in place to SettingsVersion and returns the
+// version it started from.
+func MigrateSettings(raw map[string]interface{}) int {
+	from := 1
+	if v, ok := raw["version"].(float64); ok && v >= 1 {
+		from = int(v)
+	}
+
+	for version := from; version < SettingsVersion; version++ {
+		if migrate, ok := settingsMigrations[version]; ok {
+			migrate(raw)
+		}
+	}
+	raw["version"] = float64(SettingsVersion)
+	return from
+}
+
+// migrateSettingsV1 converts the legacy keys that the frontend used to
+// rewrite on every load into their current form.
+func migrateSettingsV1(raw map[string]interface{}) {
+	if dark, ok := raw["darkMode"].(bool); ok {
+		if _, exists := raw["themeMode"]; !exists {
+			raw["themeMode"] = "light"
+			if dark {
+				raw["themeMode"] = "dark"
+			}
+		}
+	}
+	delete(raw, "darkMode")
+
+	if _, exists := raw["folderPreset"]; !exists {
+		artist, _ := raw["artistSubfolder"].(bool)
+		album, _ := raw["albumSubfolder"].(bool)
+		switch {
+		case artist && album:
+			raw["folderPreset"], raw["folderTemplate"] = "artist-album", "{artist}/{album}"
+		case artist:
+			raw["folderPreset"], raw["folderTemplate"] = "artist", "{artist}"
+		case album:
+			raw["folderPreset"], raw["folderTemplate"] = "album", "{album}"
+		}
+	}
+	delete(raw, "artistSubfolder")
+	delete(raw, "albumSubfolder")
+
+	if format, ok := raw["filenameFormat"].(string); ok {
+		if _, exists := raw["filenamePreset"]; !exists {
+			switch format {
+			case "artist-title":
+				raw["filenamePreset"], raw["filenameTemplate"] = "artist-title", "{artist} - {title}"
+			case "title":
+				raw["filenamePreset"], raw["filenameTemplate"] = "title", "{title}"
+			default:
+				raw["filenamePreset"], raw["filenameTemplate"] = "title-artist", "{title} - {artist}"
+			}
+		}
+	}
+	delete(raw, "filenameFormat")
+
+	// Deezer was added after the three-service auto order.
+	if order, ok := raw["autoOrder"].(string); ok && order != "" && !strings.Contains(order, "deezer") {
+		raw["autoOrder"] = order + "-deezer"
+	}
+
+	if q, ok := raw["qobuzQuality"].(float64); ok {
+		raw["qobuzQuality"] = fmt.Sprintf("%d", int(q))
+	}
+}
+
+// Normalize replaces out-of-range values with their defaults. It is applied
+// when loading so a hand-edited config cannot break downloads.
+func (s *Settings) Normalize() {
+	defaults := DefaultSettings()
+
+	if !isDownloadService(s.Downloader) {
+		s.Downloader = defaults.Downloader
+	}
+	if !oneOf(s.ThemeMode, themeModes) {
+		s.ThemeMode = defaults.ThemeMode
+	}
+	if !oneOf(s.TidalQuality, tidalQualities) {
+		s.TidalQuality = defaults.TidalQuality
+	}
+	if !oneOf(s.QobuzQuality, qobuzQualities) {
+		s.QobuzQuality = defaults.QobuzQuality
+	}
+	if !oneOf(s.AutoQuality, autoQualities) {
+		s.AutoQuality = defaults.AutoQuality
+	}
+	if s.AutoOrder == "" {
+		s.AutoOrder = defaults.AutoOrder
+	}
+	if !oneOf(s.PlaylistSyncRemoved, playlistSyncModes) {
+		s.PlaylistSyncRemoved = defaults.PlaylistSyncRemoved
+	}
+	if s.PlaylistSyncArchiveDir == "" {
+		s.PlaylistSyncArchiveDir = defaults.PlaylistSyncArchiveDir
+	}
+	if s.ArtistWatchIntervalHours <= 0 {
+		s.ArtistWatchIntervalHours = defaults.ArtistWatchIntervalHours
+	}
+	if s.BandwidthLimitMBps < 0 {
+		s.BandwidthLimitMBps = 0
+	}
+	if s.RetryMaxAttempts < 0 {
+		s.RetryMaxAttempts = defaults.RetryMaxAttempts
+	}
+	if s.RetryBaseDelaySeconds <= 0 {
+		s.RetryBaseDelaySeconds = defaults.RetryBaseDelaySeconds
+	}
+	if s.MaxConcurrentDownloads <= 0 {
+		s.MaxConcurrentDownloads = defaults.MaxConcurrentDownloads
+	}
+	if s.ServiceConcurrency == nil {
+		s.ServiceConcurrency = defaults.ServiceConcurrency
+	}
+	if s.SpotFetchAPIUrl == "" {
+		s.SpotFetchAPIUrl = defaults.SpotFetchAPIUrl
+	}
+
+	var order []string
+	for _, name := range s.FallbackOrder {
+		if _, err := GetProvider(name); err == nil {
+			order = append(order, name)
+		}
+	}
+	s.FallbackOrder = order
+
+	classes := []string{}
+	for _, class := range s.RetryErrorClasses {
+		if oneOf(class, retryErrorClasses) {
+			classes = append(classes, class)
+		}
+	}
+	s.RetryErrorClasses = classes
+}
+
+// Validate reports every problem that would make the settings unusable.
+func (s Settings) Validate() error {
+	var problems []string
+
+	if info, err := os.Stat(s.DownloadPath); err != nil || !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("download folder does not exist: %s", s.DownloadPath))
+	}
+	if !isDownloadService(s.Downloader) {
+		problems = append(problems, fmt.Sprintf("unknown service: %s", s.Downloader))
+	}
+	for _, name := range s.FallbackOrder {
+		if _, err := GetProvider(name); err != nil {
+			problems = append(problems, fmt.Sprintf("unknown service in fallback order: %s", name))
+		}
+	}
+	for _, name := range strings.Split(s.AutoOrder, "-") {
+		if _, err := GetProvider(name); err != nil {
+			problems = append(problems, fmt.Sprintf("unknown service in auto order: %s", name))
+		}
+	}
+	for name, limit := range s.ServiceConcurrency {
+		if _, err := GetProvider(name); err != nil {
+			problems = append(problems, fmt.Sprintf("unknown service in concurrency limits: %s", name))
+		} else if limit < 0 {
+			problems = append(problems, fmt.Sprintf("concurrency limit for %s must not be negative", name))
+		}
+	}
+
+	if err := validateTemplate(s.FolderTemplate); err != nil {
+		problems = append(problems, fmt.Sprintf("folder template: %v", err))
+	}
+	if err := validateTemplate(s.FilenameTemplate); err != nil {
+		problems = append(problems, fmt.Sprintf("filename template: %v", err))
+	} else if strings.ContainsAny(s.FilenameTemplate, `/\`) {
+		problems = append(problems, "filename template must not contain path separators")
+	}
+	if filepath.IsAbs(s.PlaylistSyncArchiveDir) || strings.HasPrefix(filepath.Clean(s.PlaylistSyncArchiveDir), "..") {
+		problems = append(problems, "playlist archive folder must be relative to the playlist folder")
+	}
+
+	if !oneOf(s.ThemeMode, themeModes) {
+		problems = append(problems, fmt.Sprintf("unknown theme mode: %s", s.ThemeMode))
+	}
+	if !oneOf(s.TidalQuality, tidalQualities) {
+		problems = append(problems, fmt.Sprintf("unknown Tidal quality: %s", s.TidalQuality))
+	}
+	if !oneOf(s.QobuzQuality, qobuzQualities) {
+		problems = append(problems, fmt.Sprintf("unknown Qobuz quality: %s", s.QobuzQuality))
+	}
+	if !oneOf(s.AutoQuality, autoQualities) {
+		problems = append(problems, fmt.Sprintf("unknown auto quality: %s", s.AutoQuality))
+	}
+	if !oneOf(s.PlaylistSyncRemoved, playlistSyncModes) {
+		problems = append(problems, fmt.Sprintf("unknown playlist sync mode: %s", s.PlaylistSyncRemoved))
+	}
+	for _, class := range s.RetryErrorClasses {
+		if !oneOf(class, retryErrorClasses) {
+			problems = append(problems, fmt.Sprintf("unknown retry error class: %s", class))
+		}
+	}
+
+	if s.ArtistWatchIntervalHours <= 0 {
+		problems = append(problems, "artist watch interval must be positive")
+	}
+	if s.BandwidthLimitMBps < 0 {
+		problems = append(problems, "bandwidth limit must not be negative")
+	}
+	if s.RetryMaxAttempts < 0 {
+		problems = append(problems, "retry attempts must not be negative")
+	}
+	if s.RetryBaseDelaySeconds <= 0 {
+		problems = append(problems, "retry delay must be positive")
+	}
+	if s.MaxConcurrentDownloads <= 0 {
+		problems = append(problems, "concurrent downloads must be at least 1")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid settings: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validateTemplate(template string) error {
+	for _, m := range templatePlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		if !templatePlaceholders[m[1]] {
+			return fmt.Errorf("unknown placeholder {%s}", m[1])
+		}
+	}
+	rest := templatePlaceholderPattern.ReplaceAllString(template, "")
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("unbalanced braces in %q", template)
+	}
+	return nil
+}
+
+func isDownloadService(name string) bool {
+	if name == "auto" || name == "best" {
+		return true
+	}
+	_, err := GetProvider(name)
+	return err == nil
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, v := range allowed {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadSettingsFile reads config.json, migrating older schema versions and
+// writing the upgraded file back. A missing file yields the defaults and
+// exists == false.
+func LoadSettingsFile(path string) (settings Settings, exists bool, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultSettings(), false, nil
+	}
+	if err != nil {
+		return DefaultSettings(), false, err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return DefaultSettings(), true, fmt.Errorf("invalid config file: %w", err)
+	}
+
+	settings, from, err := ParseSettings(raw)
+	if err != nil {
+		// A single mistyped value should not throw away the whole file.
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return DefaultSettings(), true, fmt.Errorf("invalid config file: %w", err)
+		}
+		fmt.Printf("⚠ Ignoring invalid setting %s: %v\n", typeErr.Field, err)
+	}
+	settings.Normalize()
+
+	if from < SettingsVersion {
+		if err := SaveSettingsFile(path, settings); err != nil {
+			fmt.Printf("⚠ Failed to save migrated settings: %v\n", err)
+		} else {
+			fmt.Printf("✓ Migrated settings from version %d to %d\n", from, SettingsVersion)
+		}
+	}
+	return settings, true, nil
+}
+
+func SaveSettingsFile(path string, settings Settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	settings.Version = SettingsVersion
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,216 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func rawSettings(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		wantFrom    int
+		wantTypeErr bool
+		check       func(t *testing.T, s Settings)
+	}{
+		{
+			name:     "empty object keeps defaults",
+			raw:      `{}`,
+			wantFrom: 1,
+			check: func(t *testing.T, s Settings) {
+				if s.Downloader != "auto" || s.AutoOrder != defaultAutoOrder || !s.AllowFallback || s.DownloadPath == "" {
+					t.Errorf("defaults not applied: %+v", s)
+				}
+			},
+		},
+		{
+			name:     "current version is decoded as is",
+			raw:      `{"version": 2, "downloader": "qobuz", "autoOrder": "tidal-amazon", "allowFallback": false, "retryMaxAttempts": 0}`,
+			wantFrom: 2,
+			check: func(t *testing.T, s Settings) {
+				if s.Downloader != "qobuz" || s.AutoOrder != "tidal-amazon" || s.AllowFallback || s.RetryMaxAttempts != 0 {
+					t.Errorf("got %+v", s)
+				}
+			},
+		},
+		{
+			name:     "dark mode",
+			raw:      `{"darkMode": true}`,
+			wantFrom: 1,
+			check: func(t *testing.T, s Settings) {
+				if s.ThemeMode != "dark" {
+					t.Errorf("ThemeMode = %q, want dark", s.ThemeMode)
+				}
+			},
+		},
+		{
+			name:     "explicit theme mode wins over dark mode",
+			raw:      `{"darkMode": true, "themeMode": "auto"}`,
+			wantFrom: 1,
+			check: func(t *testing.T, s Settings) {
+				if s.ThemeMode != "auto" {
+					t.Errorf("ThemeMode = %q, want auto", s.ThemeMode)
+				}
+			},
+		},
+		{
+			name:     "subfolder flags become a folder template",
+			raw:      `{"artistSubfolder": true, "albumSubfolder": true}`,
+			wantFrom: 1,
+			check: func(t *testing.T, s Settings) {
+				if s.FolderPreset != "artist-album" || s.FolderTemplate != "{artist}/{album}" {
+					t.Errorf("folder = %q %q", s.FolderPreset, s.FolderTemplate)
+				}
+			},
+		},
+		{
+			name:     "filename format becomes a filename template",
+			raw:      `{"filenameFormat": "artist-title"}`,
+			wantFrom: 1,
+			check: func(t *testing.T, s Settings) {
+				if s.FilenamePreset != "artist-title" || s.FilenameTemplate != "{artist} - {title}" {
+					t.Errorf("filename = %q %q", s.FilenamePreset, s.FilenameTemplate)
+				}
+			},
+		},
+		{
+			name:     "deezer is appended to an old auto order",
+			raw:      `{"autoOrder": "qobuz-tidal-amazon"}`,
+			wantFrom: 1,
+			check: func(t *testing.T, s Settings) {
+				if s.AutoOrder != "qobuz-tidal-amazon-deezer" {
+					t.Errorf("AutoOrder = %q", s.AutoOrder)
+				}
+			},
+		},
+		{
+			name:     "numeric qobuz quality",
+			raw:      `{"qobuzQuality": 27}`,
+			wantFrom: 1,
+			check: func(t *testing.T, s Settings) {
+				if s.QobuzQuality != "27" {
+					t.Errorf("QobuzQuality = %q", s.QobuzQuality)
+				}
+			},
+		},
+		{
+			name:        "a mistyped value keeps the rest",
+			raw:         `{"version": 2, "downloader": "tidal", "maxConcurrentDownloads": "four"}`,
+			wantFrom:    2,
+			wantTypeErr: true,
+			check: func(t *testing.T, s Settings) {
+				if s.Downloader != "tidal" || s.MaxConcurrentDownloads != DefaultMaxConcurrentDownloads {
+					t.Errorf("got downloader %q, concurrency %d", s.Downloader, s.MaxConcurrentDownloads)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, from, err := ParseSettings(rawSettings(t, tt.raw))
+			var typeErr *json.UnmarshalTypeError
+			if tt.wantTypeErr != errors.As(err, &typeErr) || (!tt.wantTypeErr && err != nil) {
+				t.Fatalf("err = %v, want type error %v", err, tt.wantTypeErr)
+			}
+			if from != tt.wantFrom {
+				t.Errorf("from = %d, want %d", from, tt.wantFrom)
+			}
+			if s.Version != SettingsVersion {
+				t.Errorf("Version = %d, want %d", s.Version, SettingsVersion)
+			}
+			tt.check(t, s)
+		})
+	}
+}
+
+func TestMigrateSettingsRemovesLegacyKeys(t *testing.T) {
+	raw := rawSettings(t, `{"darkMode": false, "artistSubfolder": true, "albumSubfolder": false, "filenameFormat": "title"}`)
+	if from := MigrateSettings(raw); from != 1 {
+		t.Errorf("from = %d, want 1", from)
+	}
+
+	want := map[string]interface{}{
+		"version":          float64(SettingsVersion),
+		"themeMode":        "light",
+		"folderPreset":     "artist",
+		"folderTemplate":   "{artist}",
+		"filenamePreset":   "title",
+		"filenameTemplate": "{title}",
+	}
+	if !reflect.DeepEqual(raw, want) {
+		t.Errorf("migrated = %v, want %v", raw, want)
+	}
+}
+
+func TestSettingsNormalize(t *testing.T) {
+	s := DefaultSettings()
+	s.Downloader = "napster"
+	s.ThemeMode = "sepia"
+	s.QobuzQuality = "5"
+	s.AutoOrder = ""
+	s.RetryMaxAttempts = -1
+	s.MaxConcurrentDownloads = 0
+	s.BandwidthLimitMBps = -2
+	s.FallbackOrder = []string{"tidal", "napster", "deezer"}
+	s.RetryErrorClasses = []string{"timeout", "disk_full"}
+	s.Normalize()
+
+	defaults := DefaultSettings()
+	if s.Downloader != defaults.Downloader || s.ThemeMode != defaults.ThemeMode || s.QobuzQuality != defaults.QobuzQuality || s.AutoOrder != defaults.AutoOrder {
+		t.Errorf("invalid values not reset: %+v", s)
+	}
+	if s.RetryMaxAttempts != defaults.RetryMaxAttempts || s.MaxConcurrentDownloads != defaults.MaxConcurrentDownloads || s.BandwidthLimitMBps != 0 {
+		t.Errorf("invalid numbers not reset: %+v", s)
+	}
+	if !reflect.DeepEqual(s.FallbackOrder, []string{"tidal", "deezer"}) {
+		t.Errorf("FallbackOrder = %v", s.FallbackOrder)
+	}
+	if !reflect.DeepEqual(s.RetryErrorClasses, []string{"timeout"}) {
+		t.Errorf("RetryErrorClasses = %v", s.RetryErrorClasses)
+	}
+}
+
+func TestLoadSettingsFileMigrates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"downloadPath": "/music", "darkMode": true, "qobuzQuality": 7}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, exists, err := LoadSettingsFile(path)
+	if err != nil || !exists {
+		t.Fatalf("LoadSettingsFile: exists %v, err %v", exists, err)
+	}
+	if s.ThemeMode != "dark" || s.QobuzQuality != "7" || s.DownloadPath != "/music" {
+		t.Errorf("got %+v", s)
+	}
+
+	data, _ := os.ReadFile(path)
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved["version"] != float64(SettingsVersion) {
+		t.Errorf("saved version = %v", saved["version"])
+	}
+	if _, ok := saved["darkMode"]; ok {
+		t.Error("legacy darkMode key was written back")
+	}
+
+	if _, exists, err := LoadSettingsFile(filepath.Join(t.TempDir(), "missing.json")); exists || err != nil {
+		t.Errorf("missing file: exists %v, err %v", exists, err)
+	}
+}
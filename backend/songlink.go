@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -35,6 +36,27 @@ type TrackAvailability struct {
 	DeezerURL string `json:"deezer_url,omitempty"`
 }
 
+var (
+	isrcCache     = make(map[string]string)
+	isrcCacheLock sync.RWMutex
+)
+
+// CachedISRC returns the ISRC found by an earlier lookup of a Spotify track.
+func CachedISRC(spotifyID string) string {
+	isrcCacheLock.RLock()
+	defer isrcCacheLock.RUnlock()
+	return isrcCache[spotifyID]
+}
+
+func cacheISRC(spotifyID, isrc string) {
+	if spotifyID == "" || isrc == "" {
+		return
+	}
+	isrcCacheLock.Lock()
+	isrcCache[spotifyID] = isrc
+	isrcCacheLock.Unlock()
+}
+
 func NewSongLinkClient() *SongLinkClient {
 	return &SongLinkClient{
 		client: &http.Client{
@@ -161,6 +183,7 @@ func (s *SongLinkClient) GetAllURLsFromSpotify(spotifyTrackID string, region str
 	if deezerLink, ok := songLinkResp.LinksByPlatform["deezer"]; ok && deezerLink.URL != "" {
 		if isrc, err := getDeezerISRC(deezerLink.URL); err == nil && isrc != "" {
 			urls.ISRC = isrc
+			cacheISRC(spotifyTrackID, isrc)
 		}
 	}
 
@@ -457,9 +480,16 @@ func getDeezerISRC(deezerURL string) (string, error) {
 }
 
 func (s *SongLinkClient) GetISRC(spotifyID string) (string, error) {
+	if isrc := CachedISRC(spotifyID); isrc != "" {
+		return isrc, nil
+	}
 	deezerURL, err := s.GetDeezerURLFromSpotify(spotifyID)
 	if err != nil {
 		return "", err
 	}
-	return getDeezerISRC(deezerURL)
+	isrc, err := getDeezerISRC(deezerURL)
+	if err == nil {
+		cacheISRC(spotifyID, isrc)
+	}
+	return isrc, err
 }
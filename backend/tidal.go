@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
@@ -216,93 +217,41 @@ func (t *TidalDownloader) GetDownloadURL(trackID int64, quality string) (string,
 	return "", fmt.Errorf("download URL not found in response")
 }
 
-func (t *TidalDownloader) DownloadFile(url, filepath string) error {
+func (t *TidalDownloader) DownloadFile(ctx context.Context, url, filepath, itemID string) error {
 
 	if strings.HasPrefix(url, "MANIFEST:") {
-		return t.DownloadFromManifest(strings.TrimPrefix(url, "MANIFEST:"), filepath)
+		return t.DownloadFromManifest(ctx, strings.TrimPrefix(url, "MANIFEST:"), filepath, itemID)
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/145.0.0.0 Safari/537.36")
-
-	resp, err := t.client.Do(req)
-
-	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	client := &http.Client{
+		Transport: DownloadTransport,
 	}
 
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	if _, err := DownloadToFile(ctx, client, url, filepath, itemID); err != nil {
+		return err
 	}
-	defer out.Close()
-
-	pw := NewProgressWriter(out)
-	_, err = io.Copy(pw, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
 
 	fmt.Println("Download complete")
 	return nil
 }
 
-func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) error {
+func (t *TidalDownloader) DownloadFromManifest(ctx context.Context, manifestB64, outputPath, itemID string) error {
 	directURL, initURL, mediaURLs, mimeType, err := parseManifest(manifestB64)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest: %w", err)
 	}
 
 	client := &http.Client{
-		Timeout: 120 * time.Second,
-	}
-
-	doRequest := func(url string) (*http.Response, error) {
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/145.0.0.0 Safari/537.36")
-		return client.Do(req)
+		Transport: DownloadTransport,
 	}
 
 	if directURL != "" && (strings.Contains(strings.ToLower(mimeType), "flac") || mimeType == "") {
 		fmt.Println("Downloading file...")
 
-		resp, err := doRequest(directURL)
-		if err != nil {
-			return fmt.Errorf("failed to download file: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != 200 {
-			return fmt.Errorf("download failed with status %d", resp.StatusCode)
-		}
-
-		out, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file: %w", err)
+		if _, err := DownloadToFile(ctx, client, directURL, outputPath, itemID); err != nil {
+			return err
 		}
-		defer out.Close()
 
-		pw := NewProgressWriter(out)
-		_, err = io.Copy(pw, resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
-		}
-
-		fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
 		fmt.Println("Download complete")
 		return nil
 	}
@@ -312,32 +261,10 @@ func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) e
 	if directURL != "" {
 		fmt.Printf("Downloading non-FLAC file (%s)...\n", mimeType)
 
-		resp, err := doRequest(directURL)
-		if err != nil {
-			return fmt.Errorf("failed to download file: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != 200 {
-			return fmt.Errorf("download failed with status %d", resp.StatusCode)
-		}
-
-		out, err := os.Create(tempPath)
-		if err != nil {
-			return fmt.Errorf("failed to create temp file: %w", err)
-		}
-
-		pw := NewProgressWriter(out)
-		_, err = io.Copy(pw, resp.Body)
-		out.Close()
-
-		if err != nil {
-			os.Remove(tempPath)
-			return fmt.Errorf("failed to write temp file: %w", err)
+		if _, err := DownloadToFile(ctx, client, directURL, tempPath, itemID); err != nil {
+			return err
 		}
 
-		fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
-
 	} else {
 
 		fmt.Printf("Downloading %d segments...\n", len(mediaURLs)+1)
@@ -347,72 +274,14 @@ func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) e
 			return fmt.Errorf("failed to create temp file: %w", err)
 		}
 
-		fmt.Print("Downloading init segment... ")
-		resp, err := doRequest(initURL)
-		if err != nil {
-			out.Close()
-			os.Remove(tempPath)
-			return fmt.Errorf("failed to download init segment: %w", err)
-		}
-		if resp.StatusCode != 200 {
-			resp.Body.Close()
-			out.Close()
-			os.Remove(tempPath)
-			return fmt.Errorf("init segment download failed with status %d", resp.StatusCode)
-		}
-		_, err = io.Copy(out, resp.Body)
-		resp.Body.Close()
+		segmentURLs := append([]string{initURL}, mediaURLs...)
+		_, err = downloadSegments(ctx, client, segmentURLs, out, itemID)
+		out.Close()
 		if err != nil {
-			out.Close()
 			os.Remove(tempPath)
-			return fmt.Errorf("failed to write init segment: %w", err)
-		}
-		fmt.Println("OK")
-
-		totalSegments := len(mediaURLs)
-		var totalBytes int64
-		lastTime := time.Now()
-		var lastBytes int64
-		for i, mediaURL := range mediaURLs {
-			resp, err := doRequest(mediaURL)
-			if err != nil {
-				out.Close()
-				os.Remove(tempPath)
-				return fmt.Errorf("failed to download segment %d: %w", i+1, err)
-			}
-			if resp.StatusCode != 200 {
-				resp.Body.Close()
-				out.Close()
-				os.Remove(tempPath)
-				return fmt.Errorf("segment %d download failed with status %d", i+1, resp.StatusCode)
-			}
-			n, err := io.Copy(out, resp.Body)
-			totalBytes += n
-			resp.Body.Close()
-			if err != nil {
-				out.Close()
-				os.Remove(tempPath)
-				return fmt.Errorf("failed to write segment %d: %w", i+1, err)
-			}
-
-			mbDownloaded := float64(totalBytes) / (1024 * 1024)
-			now := time.Now()
-			timeDiff := now.Sub(lastTime).Seconds()
-			var speedMBps float64
-			if timeDiff > 0.1 {
-				bytesDiff := float64(totalBytes - lastBytes)
-				speedMBps = (bytesDiff / (1024 * 1024)) / timeDiff
-				SetDownloadSpeed(speedMBps)
-				lastTime = now
-				lastBytes = totalBytes
-			}
-			SetDownloadProgress(mbDownloaded)
-
-			fmt.Printf("\rDownloading: %.2f MB (%d/%d segments)", mbDownloaded, i+1, totalSegments)
+			return err
 		}
 
-		out.Close()
-
 		tempInfo, _ := os.Stat(tempPath)
 		fmt.Printf("\rDownloaded: %.2f MB (Complete)          \n", float64(tempInfo.Size())/(1024*1024))
 	}
@@ -427,11 +296,16 @@ func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) e
 		return fmt.Errorf("invalid ffmpeg executable: %w", err)
 	}
 
-	cmd := exec.Command(ffmpegPath, "-y", "-i", tempPath, "-vn", "-c:a", "flac", outputPath)
+	cmd := exec.CommandContext(ctx, ffmpegPath, "-y", "-i", tempPath, "-vn", "-c:a", "flac", outputPath)
 	setHideWindow(cmd)
 	var stderr strings.Builder
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			os.Remove(tempPath)
+			os.Remove(outputPath)
+			return ctx.Err()
+		}
 
 		m4aPath := strings.TrimSuffix(outputPath, ".flac") + ".m4a"
 		os.Rename(tempPath, m4aPath)
@@ -444,10 +318,27 @@ func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) e
 	return nil
 }
 
-func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string, allowFallback bool, useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool) (string, error) {
-	if outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("directory error: %w", err)
+func (t *TidalDownloader) DownloadByURL(ctx context.Context, tidalURL string, req TrackRequest) (*TrackResult, error) {
+	return t.downloadByURL(ctx, tidalURL, req, false)
+}
+
+func (t *TidalDownloader) DownloadByURLWithFallback(ctx context.Context, tidalURL string, req TrackRequest) (*TrackResult, error) {
+	return t.downloadByURL(ctx, tidalURL, req, true)
+}
+
+func (t *TidalDownloader) downloadByURL(ctx context.Context, tidalURL string, req TrackRequest, rotate bool) (*TrackResult, error) {
+	var apis []string
+	if rotate {
+		var err error
+		apis, err = t.GetAvailableAPIs()
+		if err != nil {
+			return nil, fmt.Errorf("no APIs available for fallback: %w", err)
+		}
+	}
+
+	if req.OutputDir != "." {
+		if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
+			return nil, fmt.Errorf("directory error: %w", err)
 		}
 	}
 
@@ -455,73 +346,94 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 
 	trackID, err := t.GetTrackIDFromURL(tidalURL)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if trackID == 0 {
-		return "", fmt.Errorf("no track ID found")
+		return nil, fmt.Errorf("no track ID found")
 	}
 
-	artistName := spotifyArtistName
-	trackTitle := spotifyTrackName
-	albumTitle := spotifyAlbumName
+	artistName := req.ArtistName
+	trackTitle := req.TrackName
+	albumTitle := req.AlbumName
 
 	artistNameForFile := sanitizeFilename(artistName)
-	albumArtistForFile := sanitizeFilename(spotifyAlbumArtist)
+	albumArtistForFile := sanitizeFilename(req.AlbumArtist)
 
-	if useFirstArtistOnly {
+	if req.UseFirstArtistOnly {
 		artistNameForFile = sanitizeFilename(GetFirstArtist(artistName))
-		albumArtistForFile = sanitizeFilename(GetFirstArtist(spotifyAlbumArtist))
+		albumArtistForFile = sanitizeFilename(GetFirstArtist(req.AlbumArtist))
 	}
 
 	trackTitleForFile := sanitizeFilename(trackTitle)
 	albumTitleForFile := sanitizeFilename(albumTitle)
 
-	filename := buildTidalFilename(trackTitleForFile, artistNameForFile, albumTitleForFile, albumArtistForFile, spotifyReleaseDate, spotifyTrackNumber, spotifyDiscNumber, filenameFormat, includeTrackNumber, position, useAlbumTrackNumber)
-	outputFilename := filepath.Join(outputDir, filename)
+	filename := buildTidalFilename(trackTitleForFile, artistNameForFile, albumTitleForFile, albumArtistForFile, req.ReleaseDate, req.TrackNumber, req.DiscNumber, req.FilenameFormat, req.IncludeTrackNumber, req.Position, req.UseAlbumTrackNumber)
+	outputFilename := filepath.Join(req.OutputDir, filename)
+
+	result := &TrackResult{
+		Path:     outputFilename,
+		Service:  "tidal",
+		SourceID: fmt.Sprintf("%d", trackID),
+	}
 
 	if fileInfo, err := os.Stat(outputFilename); err == nil && fileInfo.Size() > 0 {
 		fmt.Printf("File already exists: %s (%.2f MB)\n", outputFilename, float64(fileInfo.Size())/(1024*1024))
-		return "EXISTS:" + outputFilename, nil
+		result.AlreadyExists = true
+		return result, nil
 	}
 
-	downloadURL, err := t.GetDownloadURL(trackID, quality)
+	quality := req.Quality
+	apiURL := t.apiURL
+	var downloadURL string
+	if rotate {
+		apiURL, downloadURL, err = getDownloadURLRotated(apis, trackID, quality)
+	} else {
+		downloadURL, err = t.GetDownloadURL(trackID, quality)
+	}
 	if err != nil {
-		if quality == "HI_RES" && allowFallback {
+		if quality == "HI_RES" && req.AllowFallback {
 			fmt.Println("⚠ HI_RES unavailable/failed, falling back to LOSSLESS...")
-			downloadURL, err = t.GetDownloadURL(trackID, "LOSSLESS")
+			quality = "LOSSLESS"
+			if rotate {
+				apiURL, downloadURL, err = getDownloadURLRotated(apis, trackID, quality)
+			} else {
+				downloadURL, err = t.GetDownloadURL(trackID, quality)
+			}
 			if err != nil {
-				return "", fmt.Errorf("failed to get download URL (HI_RES & LOSSLESS both failed): %w", err)
+				return nil, fmt.Errorf("failed to get download URL (HI_RES & LOSSLESS both failed): %w", err)
 			}
 		} else {
-			return "", err
+			return nil, err
 		}
 	}
+	result.API = apiHost(apiURL)
+	result.Quality = quality
+	result.RequestedQuality = req.Quality
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	type mbResult struct {
 		ISRC     string
 		Metadata Metadata
 	}
 
+	spotifyURL := req.SpotifyURL()
 	metaChan := make(chan mbResult, 1)
-	if embedGenre && spotifyURL != "" {
+	if req.EmbedGenre && spotifyURL != "" {
 		go func() {
-			res := mbResult{}
-			var isrc string
-			parts := strings.Split(spotifyURL, "/")
-			if len(parts) > 0 {
-				sID := strings.Split(parts[len(parts)-1], "?")[0]
-				if sID != "" {
-					client := NewSongLinkClient()
-					if val, err := client.GetISRC(sID); err == nil {
-						isrc = val
-					}
+			res := mbResult{ISRC: req.ISRC}
+			if res.ISRC == "" {
+				client := NewSongLinkClient()
+				if val, err := client.GetISRC(req.SpotifyID); err == nil {
+					res.ISRC = val
 				}
 			}
-			res.ISRC = isrc
-			if isrc != "" {
+			if res.ISRC != "" {
 				fmt.Println("Fetching MusicBrainz metadata...")
-				if fetchedMeta, err := FetchMusicBrainzMetadata(isrc, trackTitle, artistName, albumTitle, useSingleGenre, embedGenre); err == nil {
+				if fetchedMeta, err := FetchMusicBrainzMetadata(res.ISRC, trackTitle, artistName, albumTitle, req.UseSingleGenre, req.EmbedGenre); err == nil {
 					res.Metadata = fetchedMeta
 					fmt.Println("✓ MusicBrainz metadata fetched")
 				} else {
@@ -535,26 +447,34 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 	}
 
 	fmt.Printf("Downloading to: %s\n", outputFilename)
-	if err := t.DownloadFile(downloadURL, outputFilename); err != nil {
-		return "", err
+	downloader := t
+	if rotate {
+		downloader = NewTidalDownloader(apiURL)
+	}
+	if err := downloader.DownloadFile(ctx, downloadURL, outputFilename, req.ItemID); err != nil {
+		return nil, err
 	}
 
-	var isrc string
+	isrc := req.ISRC
 	var mbMeta Metadata
 	if spotifyURL != "" {
-		result := <-metaChan
-		isrc = result.ISRC
-		mbMeta = result.Metadata
+		if res, ok := <-metaChan; ok {
+			if res.ISRC != "" {
+				isrc = res.ISRC
+			}
+			mbMeta = res.Metadata
+		}
 	}
+	result.ISRC = isrc
 
 	fmt.Println("Adding metadata...")
 
 	coverPath := ""
 
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = outputFilename + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -563,7 +483,7 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 		}
 	}
 
-	trackNumberToEmbed := spotifyTrackNumber
+	trackNumberToEmbed := req.TrackNumber
 	if trackNumberToEmbed == 0 {
 		trackNumberToEmbed = 1
 	}
@@ -572,19 +492,20 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 		Title:       trackTitle,
 		Artist:      artistName,
 		Album:       albumTitle,
-		AlbumArtist: spotifyAlbumArtist,
-		Date:        spotifyReleaseDate,
+		AlbumArtist: req.AlbumArtist,
+		Date:        req.ReleaseDate,
 		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
-		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
+		TotalTracks: req.TotalTracks,
+		DiscNumber:  req.DiscNumber,
+		TotalDiscs:  req.TotalDiscs,
 		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
+		Copyright:   req.Copyright,
+		Publisher:   req.Publisher,
 		Description: "https://github.com/afkarxyz/SpotiFLAC",
 		ISRC:        isrc,
 		Genre:       mbMeta.Genre,
 	}
+	metadata = withSourceTags(metadata, req, result)
 
 	if err := EmbedMetadata(outputFilename, metadata, coverPath); err != nil {
 		fmt.Printf("Tagging failed: %v\n", err)
@@ -594,176 +515,121 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 
 	fmt.Println("Done")
 	fmt.Println("✓ Downloaded successfully from Tidal")
-	return outputFilename, nil
+	return result, nil
 }
 
-func (t *TidalDownloader) DownloadByURLWithFallback(tidalURL, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string, allowFallback bool, useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool) (string, error) {
-	apis, err := t.GetAvailableAPIs()
-	if err != nil {
-		return "", fmt.Errorf("no APIs available for fallback: %w", err)
-	}
-
-	if outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("directory error: %w", err)
-		}
-	}
+func (t *TidalDownloader) Download(ctx context.Context, req TrackRequest) (*TrackResult, error) {
 
-	fmt.Printf("Using Tidal URL: %s\n", tidalURL)
-
-	trackID, err := t.GetTrackIDFromURL(tidalURL)
+	tidalURL, err := t.GetTidalURLFromSpotify(req.SpotifyID)
 	if err != nil {
-		return "", err
-	}
-
-	if trackID == 0 {
-		return "", fmt.Errorf("no track ID found")
-	}
-
-	artistName := spotifyArtistName
-	trackTitle := spotifyTrackName
-	albumTitle := spotifyAlbumName
-
-	artistNameForFile := sanitizeFilename(artistName)
-	albumArtistForFile := sanitizeFilename(spotifyAlbumArtist)
-
-	if useFirstArtistOnly {
-		artistNameForFile = sanitizeFilename(GetFirstArtist(artistName))
-		albumArtistForFile = sanitizeFilename(GetFirstArtist(spotifyAlbumArtist))
+		return nil, fmt.Errorf("songlink couldn't find Tidal URL: %w", err)
 	}
 
-	trackTitleForFile := sanitizeFilename(trackTitle)
-	albumTitleForFile := sanitizeFilename(albumTitle)
+	return t.DownloadByURLWithFallback(ctx, tidalURL, req)
+}
 
-	filename := buildTidalFilename(trackTitleForFile, artistNameForFile, albumTitleForFile, albumArtistForFile, spotifyReleaseDate, spotifyTrackNumber, spotifyDiscNumber, filenameFormat, includeTrackNumber, position, useAlbumTrackNumber)
-	outputFilename := filepath.Join(outputDir, filename)
+type tidalProvider struct{}
 
-	if fileInfo, err := os.Stat(outputFilename); err == nil && fileInfo.Size() > 0 {
-		fmt.Printf("File already exists: %s (%.2f MB)\n", outputFilename, float64(fileInfo.Size())/(1024*1024))
-		return "EXISTS:" + outputFilename, nil
-	}
+func (tidalProvider) Name() string {
+	return "tidal"
+}
 
-	successAPI, downloadURL, err := getDownloadURLRotated(apis, trackID, quality)
-	if err != nil {
-		if quality == "HI_RES" && allowFallback {
-			fmt.Println("⚠ HI_RES unavailable/failed on all APIs, falling back to LOSSLESS...")
-			successAPI, downloadURL, err = getDownloadURLRotated(apis, trackID, "LOSSLESS")
-			if err != nil {
-				return "", fmt.Errorf("failed to get download URL (HI_RES & LOSSLESS both failed): %w", err)
-			}
-		} else {
-			return "", err
+func (tidalProvider) Download(ctx context.Context, req TrackRequest) (*TrackResult, error) {
+	if req.APIURL == "" || req.APIURL == "auto" {
+		downloader := NewTidalDownloader("")
+		if req.ServiceURL != "" {
+			return downloader.DownloadByURLWithFallback(ctx, req.ServiceURL, req)
 		}
+		return downloader.Download(ctx, req)
 	}
 
-	type mbResultFallback struct {
-		ISRC     string
-		Metadata Metadata
+	downloader := NewTidalDownloader(req.APIURL)
+	if req.ServiceURL != "" {
+		return downloader.DownloadByURL(ctx, req.ServiceURL, req)
 	}
+	return downloader.Download(ctx, req)
+}
 
-	metaChan := make(chan mbResultFallback, 1)
-	if embedGenre && spotifyURL != "" {
-		go func() {
-			res := mbResultFallback{}
-			var isrc string
-			parts := strings.Split(spotifyURL, "/")
-			if len(parts) > 0 {
-				sID := strings.Split(parts[len(parts)-1], "?")[0]
-				if sID != "" {
-					client := NewSongLinkClient()
-					if val, err := client.GetISRC(sID); err == nil {
-						isrc = val
-					}
-				}
-			}
-			res.ISRC = isrc
-			if isrc != "" {
-				fmt.Println("Fetching MusicBrainz metadata...")
-				if fetchedMeta, err := FetchMusicBrainzMetadata(isrc, trackTitle, artistName, albumTitle, useSingleGenre, embedGenre); err == nil {
-					res.Metadata = fetchedMeta
-					fmt.Println("✓ MusicBrainz metadata fetched")
-				} else {
-					fmt.Printf("Warning: Failed to fetch MusicBrainz metadata: %v\n", err)
-				}
-			}
-			metaChan <- res
-		}()
-	} else {
-		close(metaChan)
-	}
-
-	fmt.Printf("Downloading to: %s\n", outputFilename)
-	downloader := NewTidalDownloader(successAPI)
-	if err := downloader.DownloadFile(downloadURL, outputFilename); err != nil {
-		return "", err
-	}
-
-	var isrc string
-	var mbMeta Metadata
-	if spotifyURL != "" {
-		result := <-metaChan
-		isrc = result.ISRC
-		mbMeta = result.Metadata
-	}
-
-	fmt.Println("Adding metadata...")
-
-	coverPath := ""
+func (tidalProvider) ProbeQuality(ctx context.Context, req TrackRequest) (*QualityProbe, error) {
+	downloader := NewTidalDownloader("")
 
-	if spotifyCoverURL != "" {
-		coverPath = outputFilename + ".cover.jpg"
-		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
-			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
-			coverPath = ""
-		} else {
-			defer os.Remove(coverPath)
-			fmt.Println("Spotify cover downloaded")
+	tidalURL := req.ServiceURL
+	if tidalURL == "" {
+		var err error
+		tidalURL, err = downloader.GetTidalURLFromSpotify(req.SpotifyID)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	trackNumberToEmbed := spotifyTrackNumber
-	if trackNumberToEmbed == 0 {
-		trackNumberToEmbed = 1
+	trackID, err := downloader.GetTrackIDFromURL(tidalURL)
+	if err != nil {
+		return nil, err
 	}
 
-	metadata := Metadata{
-		Title:       trackTitle,
-		Artist:      artistName,
-		Album:       albumTitle,
-		AlbumArtist: spotifyAlbumArtist,
-		Date:        spotifyReleaseDate,
-		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
-		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
-		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
-		Description: "https://github.com/afkarxyz/SpotiFLAC",
-		ISRC:        isrc,
-		Genre:       mbMeta.Genre,
+	apis, err := downloader.GetAvailableAPIs()
+	if err != nil {
+		return nil, err
 	}
 
-	if err := EmbedMetadata(outputFilename, metadata, coverPath); err != nil {
-		fmt.Printf("Tagging failed: %v\n", err)
-	} else {
-		fmt.Println("Metadata saved")
+	client := &http.Client{
+		Timeout: 15 * time.Second,
 	}
 
-	fmt.Println("Done")
-	fmt.Println("✓ Downloaded successfully from Tidal")
-	return outputFilename, nil
-}
+	var lastErr error
+	for _, apiURL := range apis {
+		url := fmt.Sprintf("%s/track/?id=%d&quality=HI_RES_LOSSLESS", apiURL, trackID)
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("User-Agent", downloadUserAgent)
 
-func (t *TidalDownloader) Download(spotifyTrackID, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string, allowFallback bool, useFirstArtistOnly bool, useSingleGenre bool, embedGenre bool) (string, error) {
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("%s: failed to read response: %w", apiURL, err)
+			continue
+		}
+		if resp.StatusCode != 200 {
+			lastErr = fmt.Errorf("%s: HTTP %d", apiURL, resp.StatusCode)
+			continue
+		}
 
-	tidalURL, err := t.GetTidalURLFromSpotify(spotifyTrackID)
-	if err != nil {
-		return "", fmt.Errorf("songlink couldn't find Tidal URL: %w", err)
+		var v2Response TidalAPIResponseV2
+		if err := json.Unmarshal(body, &v2Response); err != nil {
+			lastErr = fmt.Errorf("%s: failed to decode response: %w", apiURL, err)
+			continue
+		}
+		if v2Response.Data.Manifest == "" {
+			lastErr = fmt.Errorf("%s: no manifest in response", apiURL)
+			continue
+		}
+
+		probe := &QualityProbe{
+			Quality:    "LOSSLESS",
+			BitDepth:   v2Response.Data.BitDepth,
+			SampleRate: v2Response.Data.SampleRate,
+			ServiceURL: tidalURL,
+		}
+		if v2Response.Data.AudioQuality == "HI_RES_LOSSLESS" {
+			probe.Quality = "HI_RES_LOSSLESS"
+		}
+		if probe.BitDepth == 0 {
+			probe.BitDepth = 16
+		}
+		if probe.SampleRate == 0 {
+			probe.SampleRate = 44100
+		}
+		return probe, nil
 	}
 
-	return t.DownloadByURLWithFallback(tidalURL, outputDir, quality, filenameFormat, includeTrackNumber, position, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, useAlbumTrackNumber, spotifyCoverURL, embedMaxQualityCover, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks, spotifyTotalDiscs, spotifyCopyright, spotifyPublisher, spotifyURL, allowFallback, useFirstArtistOnly, useSingleGenre, embedGenre)
+	return nil, fmt.Errorf("tidal quality lookup failed: %w", lastErr)
 }
 
 type SegmentTemplate struct {
@@ -794,6 +660,151 @@ type MPD struct {
 	} `xml:"Period"`
 }
 
+const (
+	dashSegmentWorkers = 4
+	dashSegmentRetries = 3
+)
+
+type segmentResult struct {
+	data []byte
+	err  error
+}
+
+func downloadSegments(ctx context.Context, client *http.Client, segmentURLs []string, out io.Writer, itemID string) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]chan segmentResult, len(segmentURLs))
+	for i := range results {
+		results[i] = make(chan segmentResult, 1)
+	}
+
+	jobs := make(chan int)
+	window := make(chan struct{}, dashSegmentWorkers*2)
+
+	go func() {
+		defer close(jobs)
+		for i := range segmentURLs {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < dashSegmentWorkers; w++ {
+		go func() {
+			for i := range jobs {
+				data, err := fetchSegment(ctx, client, segmentURLs[i])
+				results[i] <- segmentResult{data: data, err: err}
+			}
+		}()
+	}
+
+	totalSegments := len(segmentURLs) - 1
+	var totalBytes, initBytes int64
+	lastTime := time.Now()
+	var lastBytes int64
+	var speedMBps float64
+	for i := range segmentURLs {
+		var res segmentResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return totalBytes, ctx.Err()
+		}
+
+		if res.err != nil {
+			if i == 0 {
+				return totalBytes, fmt.Errorf("failed to download init segment: %w", res.err)
+			}
+			return totalBytes, fmt.Errorf("failed to download segment %d: %w", i, res.err)
+		}
+		n, err := out.Write(res.data)
+		totalBytes += int64(n)
+		<-window
+		if err != nil {
+			return totalBytes, fmt.Errorf("failed to write segment %d: %w", i, err)
+		}
+
+		// Segment sizes aren't in the manifest, so extrapolate the total
+		// from the media segments fetched so far.
+		if i == 0 {
+			initBytes = totalBytes
+		} else if mediaSegments := len(segmentURLs) - 1; mediaSegments > 0 {
+			expected := initBytes + (totalBytes-initBytes)*int64(mediaSegments)/int64(i)
+			SetItemExpectedSize(itemID, expected)
+		}
+
+		mbDownloaded := float64(totalBytes) / (1024 * 1024)
+		now := time.Now()
+		timeDiff := now.Sub(lastTime).Seconds()
+		if timeDiff > 0.1 {
+			bytesDiff := float64(totalBytes - lastBytes)
+			speedMBps = (bytesDiff / (1024 * 1024)) / timeDiff
+			lastTime = now
+			lastBytes = totalBytes
+		}
+		reportDownloadProgress(itemID, mbDownloaded, speedMBps)
+
+		fmt.Printf("\rDownloading: %.2f MB (%d/%d segments)", mbDownloaded, i, totalSegments)
+	}
+
+	return totalBytes, nil
+}
+
+func fetchSegment(ctx context.Context, client *http.Client, segmentURL string) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt <= dashSegmentRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<(attempt-1)) * 500 * time.Millisecond
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		data, err := fetchSegmentOnce(ctx, client, segmentURL)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if statusErr, ok := err.(*downloadStatusError); ok && !statusErr.retryable() {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func fetchSegmentOnce(ctx context.Context, client *http.Client, segmentURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", segmentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", downloadUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, &downloadStatusError{StatusCode: resp.StatusCode}
+	}
+	return io.ReadAll(resp.Body)
+}
+
 func parseManifest(manifestB64 string) (directURL string, initURL string, mediaURLs []string, mimeType string, err error) {
 	manifestBytes, err := base64.StdEncoding.DecodeString(manifestB64)
 	if err != nil {
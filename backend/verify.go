@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/mewkiz/flac"
+)
+
+type VerificationError struct {
+	Path   string
+	Reason string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verification failed: %s", e.Reason)
+}
+
+type FLACVerification struct {
+	Samples    uint64  `json:"samples"`
+	SampleRate uint32  `json:"sample_rate"`
+	Duration   float64 `json:"duration"`
+	MD5Checked bool    `json:"md5_checked"`
+}
+
+func VerifyFLAC(path string, expectedDurationSec int) (*FLACVerification, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	stream, err := flac.New(f)
+	if err != nil {
+		return nil, &VerificationError{Path: path, Reason: fmt.Sprintf("cannot open FLAC stream: %v", err)}
+	}
+
+	info := stream.Info
+	hasher := md5.New()
+
+	var decoded uint64
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &VerificationError{Path: path, Reason: fmt.Sprintf("corrupt frame after %d samples: %v", decoded, err)}
+		}
+		frame.Hash(hasher)
+		decoded += uint64(frame.BlockSize)
+	}
+
+	result := &FLACVerification{
+		Samples:    decoded,
+		SampleRate: info.SampleRate,
+	}
+	if info.SampleRate > 0 {
+		result.Duration = float64(decoded) / float64(info.SampleRate)
+	}
+
+	if info.NSamples > 0 && decoded != info.NSamples {
+		return result, &VerificationError{Path: path, Reason: fmt.Sprintf("decoded %d of %d samples", decoded, info.NSamples)}
+	}
+
+	var zero [md5.Size]byte
+	if info.MD5sum != zero {
+		if sum := hasher.Sum(nil); !bytes.Equal(sum, info.MD5sum[:]) {
+			return result, &VerificationError{Path: path, Reason: fmt.Sprintf("MD5 mismatch (expected %x, got %x)", info.MD5sum, sum)}
+		}
+		result.MD5Checked = true
+	}
+
+	if expectedDurationSec > 0 && result.Duration > 0 {
+		expected := float64(expectedDurationSec)
+		tolerance := math.Max(10, expected*0.05)
+		if math.Abs(result.Duration-expected) > tolerance {
+			return result, &VerificationError{Path: path, Reason: fmt.Sprintf("duration %.1fs does not match expected %ds", result.Duration, expectedDurationSec)}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// writeTestFLAC encodes a two second mono FLAC file in four frames and
+// returns the file offset at the end of each frame.
+func writeTestFLAC(t *testing.T, path string) []int64 {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &meta.StreamInfo{SampleRate: 8000, NChannels: 1, BitsPerSample: 16}
+	enc, err := flac.NewEncoder(f, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ends []int64
+	for i := 0; i < 4; i++ {
+		samples := make([]int32, 4000)
+		for j := range samples {
+			samples[j] = int32((i*4000+j)%200 - 100)
+		}
+		fr := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         4000,
+				SampleRate:        8000,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+			},
+			Subframes: []*frame.Subframe{{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   samples,
+				NSamples:  len(samples),
+			}},
+		}
+		if err := enc.WriteFrame(fr); err != nil {
+			t.Fatal(err)
+		}
+		end, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ends = append(ends, end)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return ends
+}
+
+func TestVerifyFLAC(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.flac")
+	ends := writeTestFLAC(t, valid)
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name string, content []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// The MD5 sum is the last field of the STREAMINFO block, which follows
+	// the "fLaC" marker and a 4 byte block header.
+	badMD5 := append([]byte(nil), data...)
+	badMD5[4+4+18] ^= 0xFF
+
+	tests := []struct {
+		name       string
+		path       string
+		duration   int
+		wantReason string
+	}{
+		{"valid", valid, 0, ""},
+		{"expected duration", valid, 2, ""},
+		{"duration within tolerance", valid, 11, ""},
+		{"wrong duration", valid, 60, "does not match expected 60s"},
+		{"md5 mismatch", write("md5.flac", badMD5), 0, "MD5 mismatch"},
+		{"missing frames", write("short.flac", data[:ends[2]]), 0, "decoded 12000 of 16000 samples"},
+		{"truncated frame", write("cut.flac", data[:ends[2]+100]), 0, "corrupt frame after 12000 samples"},
+		{"not flac", write("text.flac", []byte("not a flac file")), 0, "cannot open FLAC stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := VerifyFLAC(tt.path, tt.duration)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Samples != 16000 || result.SampleRate != 8000 || result.Duration != 2 || !result.MD5Checked {
+					t.Errorf("result = %+v", result)
+				}
+				return
+			}
+			var verr *VerificationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("err = %v, want a VerificationError", err)
+			}
+			if verr.Path != tt.path || !strings.Contains(verr.Reason, tt.wantReason) {
+				t.Errorf("err = %+v, want reason containing %q", verr, tt.wantReason)
+			}
+		})
+	}
+
+	_, err = VerifyFLAC(filepath.Join(dir, "missing.flac"), 0)
+	var verr *VerificationError
+	if err == nil || errors.As(err, &verr) {
+		t.Errorf("missing file: err = %v, want a plain open error", err)
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+const cliUsage = `Usage:
+  spotiflac download <spotify-url> [--service auto|best|tidal|qobuz|amazon|deezer] [--out DIR] [--format TEMPLATE]
+  spotiflac fetch <spotify-url> [--timeout SECONDS]
+  spotiflac analyze <file.flac> [file.flac...]
+
+Run without arguments to start the desktop app.
+`
+
+var cliCommands = map[string]func(a *App, args []string) int{
+	"download": cliDownload,
+	"fetch":    cliFetch,
+	"analyze":  cliAnalyze,
+}
+
+func isCLIInvocation(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "help", "-h", "--help":
+		return true
+	}
+	_, ok := cliCommands[args[0]]
+	return ok
+}
+
+func runCLI(args []string) int {
+	cmd, ok := cliCommands[args[0]]
+	if !ok {
+		fmt.Print(cliUsage)
+		return 0
+	}
+
+	app := NewApp()
+	app.initBackend(context.Background())
+	defer app.shutdown(context.Background())
+
+	return cmd(app, args[1:])
+}
+
+func parseCLIFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newCLIFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func cliDownload(a *App, args []string) int {
+	fs := newCLIFlagSet("download")
+	service := fs.String("service", "tidal", "")
+	outDir := fs.String("out", backend.GetDefaultMusicPath(), "")
+	format := fs.String("format", "{title} - {artist}", "")
+	quality := fs.String("quality", "", "")
+	embedLyrics := fs.Bool("lyrics", false, "")
+	maxCover := fs.Bool("max-cover", false, "")
+	firstArtist := fs.Bool("first-artist", false, "")
+	noFallback := fs.Bool("no-fallback", false, "")
+
+	positional, err := parseCLIFlags(fs, args)
+	if err != nil || len(positional) != 1 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	data, err := backend.GetFilteredSpotifyData(ctx, positional[0], false, 0)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to fetch metadata: %v\n", err)
+		return 1
+	}
+
+	var tracks []backend.AlbumTrackMetadata
+	var playlistName, playlistOwner string
+
+	switch payload := data.(type) {
+	case backend.TrackResponse:
+		t := payload.Track
+		tracks = append(tracks, backend.AlbumTrackMetadata{
+			SpotifyID:   t.SpotifyID,
+			Artists:     t.Artists,
+			Name:        t.Name,
+			AlbumName:   t.AlbumName,
+			AlbumArtist: t.AlbumArtist,
+			DurationMS:  t.DurationMS,
+			Images:      t.Images,
+			ReleaseDate: t.ReleaseDate,
+			TrackNumber: t.TrackNumber,
+			TotalTracks: t.TotalTracks,
+			DiscNumber:  t.DiscNumber,
+			TotalDiscs:  t.TotalDiscs,
+		})
+	case *backend.AlbumResponsePayload:
+		tracks = payload.TrackList
+	case backend.PlaylistResponsePayload:
+		tracks = payload.TrackList
+		playlistName = payload.PlaylistInfo.Owner.Name
+		playlistOwner = payload.PlaylistInfo.Owner.DisplayName
+	case *backend.ArtistDiscographyPayload:
+		tracks = payload.TrackList
+	default:
+		fmt.Fprintln(os.Stderr, "Error: unsupported Spotify URL")
+		return 1
+	}
+
+	if len(tracks) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no tracks found")
+		return 1
+	}
+
+	folderTemplate := ""
+	filenameTemplate := *format
+	if idx := strings.LastIndex(filenameTemplate, "/"); idx != -1 {
+		folderTemplate = filenameTemplate[:idx]
+		filenameTemplate = filenameTemplate[idx+1:]
+	}
+
+	audioFormat := *quality
+	if audioFormat == "" {
+		switch *service {
+		case "qobuz":
+			audioFormat = "6"
+		case "tidal":
+			audioFormat = "LOSSLESS"
+		}
+	}
+
+	var completed, skipped, failed int
+	for i, t := range tracks {
+		fmt.Printf("\n[%d/%d] %s - %s\n", i+1, len(tracks), t.Name, t.Artists)
+
+		artist := t.Artists
+		albumArtist := t.AlbumArtist
+		if *firstArtist {
+			artist = backend.GetFirstArtist(artist)
+			albumArtist = backend.GetFirstArtist(albumArtist)
+		}
+
+		trackDir := *outDir
+		if folderTemplate != "" {
+			trackDir = filepath.Join(trackDir, renderFolderTemplate(folderTemplate, artist, t.AlbumName, albumArtist, t.ReleaseDate, playlistName, t.DiscNumber))
+		}
+
+		req := DownloadRequest{
+			Service:              *service,
+			TrackName:            t.Name,
+			ArtistName:           artist,
+			AlbumName:            t.AlbumName,
+			AlbumArtist:          albumArtist,
+			ReleaseDate:          t.ReleaseDate,
+			CoverURL:             t.Images,
+			OutputDir:            trackDir,
+			AudioFormat:          audioFormat,
+			FilenameFormat:       filenameTemplate,
+			Position:             i + 1,
+			UseAlbumTrackNumber:  folderTemplate != "",
+			SpotifyID:            t.SpotifyID,
+			EmbedLyrics:          *embedLyrics,
+			EmbedMaxQualityCover: *maxCover,
+			Duration:             t.DurationMS / 1000,
+			SpotifyTrackNumber:   t.TrackNumber,
+			SpotifyDiscNumber:    t.DiscNumber,
+			SpotifyTotalTracks:   t.TotalTracks,
+			SpotifyTotalDiscs:    t.TotalDiscs,
+			PlaylistOwner:        playlistOwner,
+			AllowFallback:        !*noFallback,
+			UseFirstArtistOnly:   *firstArtist,
+			EmbedGenre:           true,
+		}
+		if folderTemplate == "" {
+			req.PlaylistName = playlistName
+		}
+
+		resp, err := a.DownloadTrack(req)
+		switch {
+		case err != nil || !resp.Success:
+			failed++
+			msg := resp.Error
+			if msg == "" && err != nil {
+				msg = err.Error()
+			}
+			fmt.Fprintf(os.Stderr, "✗ %s - %s: %s\n", t.Name, t.Artists, msg)
+		case resp.AlreadyExists:
+			skipped++
+			fmt.Printf("File already exists: %s\n", resp.File)
+		default:
+			completed++
+			fmt.Printf("✓ %s\n", resp.File)
+		}
+	}
+
+	fmt.Printf("\nCompleted: %d, Skipped: %d, Failed: %d\n", completed, skipped, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func renderFolderTemplate(template, artist, album, albumArtist, releaseDate, playlist string, discNumber int) string {
+	if albumArtist == "" {
+		albumArtist = artist
+	}
+	year := ""
+	if len(releaseDate) >= 4 {
+		year = releaseDate[:4]
+	}
+	disc := ""
+	if discNumber > 0 {
+		disc = fmt.Sprintf("%d", discNumber)
+	}
+
+	replacer := strings.NewReplacer(
+		"{artist}", strings.ReplaceAll(artist, "/", " "),
+		"{album}", strings.ReplaceAll(album, "/", " "),
+		"{album_artist}", strings.ReplaceAll(albumArtist, "/", " "),
+		"{year}", year,
+		"{date}", releaseDate,
+		"{playlist}", strings.ReplaceAll(playlist, "/", " "),
+		"{disc}", disc,
+	)
+
+	var parts []string
+	for _, part := range strings.Split(template, "/") {
+		part = strings.TrimSpace(replacer.Replace(part))
+		if part == "" {
+			continue
+		}
+		parts = append(parts, backend.SanitizeFilename(part))
+	}
+	return filepath.Join(parts...)
+}
+
+func cliFetch(a *App, args []string) int {
+	fs := newCLIFlagSet("fetch")
+	timeout := fs.Float64("timeout", 300, "")
+
+	positional, err := parseCLIFlags(fs, args)
+	if err != nil || len(positional) != 1 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	jsonData, err := a.GetSpotifyMetadata(SpotifyMetadataRequest{
+		URL:     positional[0],
+		Timeout: *timeout,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(jsonData)
+	return 0
+}
+
+func cliAnalyze(a *App, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	exitCode := 0
+	results := make([]*backend.AnalysisResult, 0, len(args))
+	for _, filePath := range args {
+		result, err := backend.AnalyzeTrack(filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to analyze %s: %v\n", filePath, err)
+			exitCode = 1
+			continue
+		}
+		result.Spectrum = nil
+		results = append(results, result)
+	}
+
+	jsonData, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to encode response: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(string(jsonData))
+	return exitCode
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const downloadListVersion = 1
+
+var downloadListCSVHeader = []string{"track_name", "artist_name", "album_name", "spotify_id", "status", "service", "error", "output_subdir", "request"}
+
+type DownloadListEntry struct {
+	TrackName    string          `json:"track_name"`
+	ArtistName   string          `json:"artist_name"`
+	AlbumName    string          `json:"album_name,omitempty"`
+	SpotifyID    string          `json:"spotify_id,omitempty"`
+	Status       string          `json:"status"`
+	Service      string          `json:"service,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	OutputSubdir string          `json:"output_subdir,omitempty"`
+	Request      DownloadRequest `json:"request"`
+}
+
+type DownloadList struct {
+	Version    int                 `json:"version"`
+	ExportedAt string              `json:"exported_at"`
+	Entries    []DownloadListEntry `json:"entries"`
+}
+
+func (a *App) failedDownloadEntries() []DownloadListEntry {
+	requests := make(map[string]backend.PersistedDownload)
+	if entries, err := backend.GetPersistedDownloads(); err == nil {
+		for _, entry := range entries {
+			requests[entry.Item.ID] = entry
+		}
+	}
+
+	downloadPath := a.settings().DownloadPath
+
+	var list []DownloadListEntry
+	for _, item := range backend.GetDownloadQueue().Queue {
+		if item.Status != backend.StatusFailed && item.Status != backend.StatusVerificationFailed {
+			continue
+		}
+
+		entry := DownloadListEntry{
+			TrackName:  item.TrackName,
+			ArtistName: item.ArtistName,
+			AlbumName:  item.AlbumName,
+			SpotifyID:  item.SpotifyID,
+			Status:     string(item.Status),
+			Error:      item.ErrorMessage,
+		}
+
+		persisted, ok := requests[item.ID]
+		if ok && len(persisted.Request) > 0 && json.Unmarshal(persisted.Request, &entry.Request) == nil {
+			entry.Service = persisted.Provider
+		} else {
+			entry.Request = DownloadRequest{
+				TrackName:  item.TrackName,
+				ArtistName: item.ArtistName,
+				AlbumName:  item.AlbumName,
+				SpotifyID:  item.SpotifyID,
+			}
+		}
+		entry.Request.ItemID = ""
+
+		if downloadPath != "" && entry.Request.OutputDir != "" {
+			if rel, err := filepath.Rel(downloadPath, entry.Request.OutputDir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+				entry.OutputSubdir = filepath.ToSlash(rel)
+			}
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+func writeDownloadListJSON(path string, entries []DownloadListEntry) error {
+	data, err := json.MarshalIndent(DownloadList{
+		Version:    downloadListVersion,
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Entries:    entries,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeDownloadListCSV(path string, entries []DownloadListEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(downloadListCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		request, err := json.Marshal(entry.Request)
+		if err != nil {
+			return err
+		}
+		if err := w.Write([]string{
+			entry.TrackName,
+			entry.ArtistName,
+			entry.AlbumName,
+			entry.SpotifyID,
+			entry.Status,
+			entry.Service,
+			entry.Error,
+			entry.OutputSubdir,
+			string(request),
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func readDownloadList(path string) ([]DownloadListEntry, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var list DownloadList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("invalid download list: %w", err)
+		}
+		if list.Version > downloadListVersion {
+			return nil, fmt.Errorf("download list version %d is newer than supported version %d", list.Version, downloadListVersion)
+		}
+		return list.Entries, nil
+	case ".csv":
+		return readDownloadListCSV(path)
+	default:
+		return nil, fmt.Errorf("unsupported download list format: %s", filepath.Ext(path))
+	}
+}
+
+func readDownloadListCSV(path string) ([]DownloadListEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid download list: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["request"]; !ok {
+		return nil, fmt.Errorf("invalid download list: missing request column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var entries []DownloadListEntry
+	for line, record := range records[1:] {
+		entry := DownloadListEntry{
+			TrackName:    field(record, "track_name"),
+			ArtistName:   field(record, "artist_name"),
+			AlbumName:    field(record, "album_name"),
+			SpotifyID:    field(record, "spotify_id"),
+			Status:       field(record, "status"),
+			Service:      field(record, "service"),
+			Error:        field(record, "error"),
+			OutputSubdir: field(record, "output_subdir"),
+		}
+		if raw := field(record, "request"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &entry.Request); err != nil {
+				return nil, fmt.Errorf("invalid request on line %d: %w", line+2, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (a *App) SelectDownloadListFile() (string, error) {
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import Download List",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "Download Lists (*.json, *.csv)",
+				Pattern:     "*.json;*.csv",
+			},
+		},
+	})
+}
+
+func (a *App) ImportDownloadList(path string) (int, error) {
+	if path == "" {
+		return 0, fmt.Errorf("no file selected")
+	}
+
+	entries, err := readDownloadList(path)
+	if err != nil {
+		return 0, err
+	}
+
+	downloadPath := a.settings().DownloadPath
+
+	requests := make([]DownloadRequest, 0, len(entries))
+	for _, entry := range entries {
+		req := entry.Request
+		if req.SpotifyID == "" && req.TrackName == "" {
+			req.SpotifyID = entry.SpotifyID
+			req.TrackName = entry.TrackName
+			req.ArtistName = entry.ArtistName
+			req.AlbumName = entry.AlbumName
+		}
+		if req.SpotifyID == "" && req.TrackName == "" {
+			continue
+		}
+		req.ItemID = ""
+
+		// Paths in the list belong to the exporting machine, so rebuild
+		// them under the local download folder.
+		req.OutputDir = importedOutputDir(downloadPath, entry.OutputSubdir)
+		if req.Service == "" {
+			req.Service = entry.Service
+		}
+		requests = append(requests, req)
+	}
+
+	if len(requests) == 0 {
+		return 0, nil
+	}
+
+	itemIDs, err := a.EnqueueDownloads(requests)
+	if err != nil {
+		return len(itemIDs), err
+	}
+	fmt.Printf("Imported %d downloads from %s\n", len(itemIDs), path)
+	return len(itemIDs), nil
+}
+
+// importedOutputDir joins a list entry's subfolder onto the download folder,
+// falling back to the download folder itself for subfolders that would
+// leave it.
+func importedOutputDir(downloadPath, subdir string) string {
+	if subdir == "" {
+		return downloadPath
+	}
+	rel := filepath.Clean(filepath.FromSlash(strings.ReplaceAll(subdir, "\\", "/")))
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		fmt.Printf("⚠ Ignoring output folder outside the download folder: %s\n", subdir)
+		return downloadPath
+	}
+	return filepath.Join(downloadPath, rel)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
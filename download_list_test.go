@@ -0,0 +1,29 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestImportedOutputDir(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "Music")
+	tests := []struct {
+		subdir string
+		want   string
+	}{
+		{"", root},
+		{"Artist/Album", filepath.Join(root, "Artist", "Album")},
+		{"Artist\\Album", filepath.Join(root, "Artist", "Album")},
+		{"Artist/../Other", filepath.Join(root, "Other")},
+		{"../..", root},
+		{"Artist/../../Outside", root},
+		{"..", root},
+		{".", root},
+		{"/etc", root},
+	}
+	for _, tt := range tests {
+		if got := importedOutputDir(root, tt.subdir); got != tt.want {
+			t.Errorf("importedOutputDir(%q) = %q, want %q", tt.subdir, got, tt.want)
+		}
+	}
+}
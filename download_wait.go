@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+var (
+	downloadWaiters     = make(map[string][]chan DownloadResponse)
+	downloadWaitersLock sync.Mutex
+)
+
+func watchDownload(itemID string) chan DownloadResponse {
+	ch := make(chan DownloadResponse, 1)
+	downloadWaitersLock.Lock()
+	downloadWaiters[itemID] = append(downloadWaiters[itemID], ch)
+	downloadWaitersLock.Unlock()
+	return ch
+}
+
+func unwatchDownload(itemID string, ch chan DownloadResponse) {
+	downloadWaitersLock.Lock()
+	defer downloadWaitersLock.Unlock()
+
+	waiters := downloadWaiters[itemID]
+	for i, w := range waiters {
+		if w == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(downloadWaiters, itemID)
+	} else {
+		downloadWaiters[itemID] = waiters
+	}
+}
+
+func finishDownload(itemID string, resp DownloadResponse) {
+	downloadWaitersLock.Lock()
+	waiters := downloadWaiters[itemID]
+	delete(downloadWaiters, itemID)
+	downloadWaitersLock.Unlock()
+
+	resp.ItemID = itemID
+	for _, ch := range waiters {
+		ch <- resp
+	}
+}
+
+// releaseFinishedWaiters answers waiters whose item ended without running
+// through runDownloadJob, e.g. because it was cancelled while queued.
+func releaseFinishedWaiters() {
+	downloadWaitersLock.Lock()
+	var ids []string
+	for id := range downloadWaiters {
+		ids = append(ids, id)
+	}
+	downloadWaitersLock.Unlock()
+
+	for _, id := range ids {
+		if resp, ok := finishedDownloadResponse(id); ok {
+			finishDownload(id, resp)
+		}
+	}
+}
+
+// finishedDownloadResponse rebuilds the outcome of an item from the queue,
+// or reports false while it is still queued, downloading or waiting to retry.
+func finishedDownloadResponse(itemID string) (DownloadResponse, bool) {
+	item, ok := backend.GetDownloadItem(itemID)
+	if !ok {
+		return DownloadResponse{Success: false, Error: "Download not found", ItemID: itemID}, true
+	}
+
+	switch item.Status {
+	case backend.StatusCompleted:
+		return DownloadResponse{Success: true, Message: "Download completed successfully", File: item.FilePath, ItemID: itemID, Service: item.Service}, true
+	case backend.StatusCancelled:
+		return DownloadResponse{Success: false, Error: "Download cancelled", ItemID: itemID}, true
+	case backend.StatusSkipped:
+		return DownloadResponse{Success: true, Message: "File already exists", File: item.FilePath, AlreadyExists: true, ItemID: itemID}, true
+	case backend.StatusFailed, backend.StatusVerificationFailed:
+		return DownloadResponse{Success: false, Error: item.ErrorMessage, ItemID: itemID}, true
+	}
+	return DownloadResponse{}, false
+}
+
+// downloadAndWait queues requests on the download scheduler and blocks until
+// all of them have finished, retries included. done is called from the
+// waiting goroutines as each result arrives.
+func (a *App) downloadAndWait(requests []DownloadRequest, done func(i int, resp DownloadResponse)) error {
+	if a.scheduler == nil {
+		return fmt.Errorf("download scheduler is not running")
+	}
+
+	waiters := make([]chan DownloadResponse, len(requests))
+	for i := range requests {
+		queueDownloadItem(&requests[i])
+		waiters[i] = watchDownload(requests[i].ItemID)
+	}
+
+	if _, err := a.EnqueueDownloads(requests); err != nil {
+		for i, req := range requests {
+			unwatchDownload(req.ItemID, waiters[i])
+		}
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			done(i, <-waiters[i])
+		}(i)
+	}
+	wg.Wait()
+	return nil
+}
+
+// WaitForDownload blocks until a download queued with EnqueueDownloads has
+// finished and returns its result.
+func (a *App) WaitForDownload(itemID string) DownloadResponse {
+	ch := watchDownload(itemID)
+	if resp, ok := finishedDownloadResponse(itemID); ok {
+		unwatchDownload(itemID, ch)
+		return resp
+	}
+	return <-ch
+}
+
+func queueDownloadItem(req *DownloadRequest) {
+	if req.ItemID != "" {
+		return
+	}
+	if req.SpotifyID != "" {
+		req.ItemID = fmt.Sprintf("%s-%d", req.SpotifyID, time.Now().UnixNano())
+	} else {
+		req.ItemID = fmt.Sprintf("%s-%s-%d", req.TrackName, req.ArtistName, time.Now().UnixNano())
+	}
+	backend.AddToQueue(req.ItemID, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID)
+}
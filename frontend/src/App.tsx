@@ -5,7 +5,7 @@ import { Search, X, ArrowUp } from "lucide-react";
 import { TooltipProvider } from "@/components/ui/tooltip";
 import { getSettings, getSettingsWithDefaults, loadSettings, saveSettings, applyThemeMode, applyFont, updateSettings } from "@/lib/settings";
 import { applyTheme } from "@/lib/themes";
-import { OpenFolder, CheckFFmpegInstalled, DownloadFFmpeg } from "../wailsjs/go/main/App";
+import { OpenFolder, CheckFFmpegInstalled, DownloadFFmpeg, GetResumableDownloads, ResumeDownloads, DiscardResumableDownloads } from "../wailsjs/go/main/App";
 import { EventsOn, EventsOff, Quit } from "../wailsjs/runtime/runtime";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { TitleBar } from "@/components/TitleBar";
@@ -99,6 +99,28 @@ function App() {
             }
         };
         checkFFmpeg();
+        const checkResumableDownloads = async () => {
+            try {
+                const items = await GetResumableDownloads();
+                if (items && items.length > 0) {
+                    toast.info(`${items.length} unfinished download(s) from last session`, {
+                        duration: Infinity,
+                        action: {
+                            label: "Resume",
+                            onClick: () => ResumeDownloads([]).catch((err) => toast.error(`Failed to resume downloads: ${err}`)),
+                        },
+                        cancel: {
+                            label: "Discard",
+                            onClick: () => DiscardResumableDownloads(),
+                        },
+                    });
+                }
+            }
+            catch (err) {
+                console.error("Failed to check unfinished downloads:", err);
+            }
+        };
+        checkResumableDownloads();
         const mediaQuery = window.matchMedia("(prefers-color-scheme: dark)");
         const handleChange = () => {
             const currentSettings = getSettings();
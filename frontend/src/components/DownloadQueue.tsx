@@ -1,48 +1,27 @@
 import { useEffect, useState } from "react";
-import { X, Download, CheckCircle2, XCircle, Clock, FileCheck, Trash2, HardDrive, Zap, Timer, FileDown } from "lucide-react";
+import { X, Download, CheckCircle2, XCircle, Clock, FileCheck, Trash2, HardDrive, Zap, Timer, FileDown, FileUp, RotateCcw, Ban } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Dialog, DialogContent, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
 import { Badge } from "@/components/ui/badge";
-import { GetDownloadQueue, ClearCompletedDownloads, ClearAllDownloads, ExportFailedDownloads } from "../../wailsjs/go/main/App";
+import { ClearCompletedDownloads, ClearAllDownloads, ExportFailedDownloads, CancelDownload, RetryFailedDownloads, SelectDownloadListFile, ImportDownloadList } from "../../wailsjs/go/main/App";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
-import { backend } from "../../wailsjs/go/models";
+import { useDownloadQueueData } from "@/hooks/useDownloadQueueData";
 interface DownloadQueueProps {
     isOpen: boolean;
     onClose: () => void;
 }
 export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
-    const [queueInfo, setQueueInfo] = useState<backend.DownloadQueueInfo>(new backend.DownloadQueueInfo({
-        is_downloading: false,
-        queue: [],
-        current_speed: 0,
-        total_downloaded: 0,
-        session_start_time: 0,
-        queued_count: 0,
-        completed_count: 0,
-        failed_count: 0,
-        skipped_count: 0,
-    }));
+    const queueInfo = useDownloadQueueData();
+    const [, setTick] = useState(0);
     useEffect(() => {
         if (!isOpen)
             return;
-        const fetchQueue = async () => {
-            try {
-                const info = await GetDownloadQueue();
-                setQueueInfo(info);
-            }
-            catch (error) {
-                console.error("Failed to get download queue:", error);
-            }
-        };
-        fetchQueue();
-        const interval = setInterval(fetchQueue, 500);
+        const interval = setInterval(() => setTick(t => t + 1), 1000);
         return () => clearInterval(interval);
     }, [isOpen]);
     const handleClearHistory = async () => {
         try {
             await ClearCompletedDownloads();
-            const info = await GetDownloadQueue();
-            setQueueInfo(info);
         }
         catch (error) {
             console.error("Failed to clear history:", error);
@@ -51,14 +30,21 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
     const handleReset = async () => {
         try {
             await ClearAllDownloads();
-            const info = await GetDownloadQueue();
-            setQueueInfo(info);
             toast.success("Download queue reset");
         }
         catch (error) {
             console.error("Failed to reset queue:", error);
         }
     };
+    const handleCancel = async (itemID: string) => {
+        try {
+            await CancelDownload(itemID);
+        }
+        catch (error) {
+            console.error("Failed to cancel download:", error);
+            toast.error(`Failed to cancel: ${error}`);
+        }
+    };
     const handleExportFailed = async () => {
         try {
             const message = await ExportFailedDownloads();
@@ -74,6 +60,39 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             toast.error(`Failed to export: ${error}`);
         }
     };
+    const handleRetryFailed = async () => {
+        try {
+            const count = await RetryFailedDownloads();
+            if (count > 0) {
+                toast.success(`Retrying ${count} failed download${count === 1 ? "" : "s"}`);
+            }
+            else {
+                toast.info("No failed downloads to retry");
+            }
+        }
+        catch (error) {
+            console.error("Failed to retry downloads:", error);
+            toast.error(`Failed to retry: ${error}`);
+        }
+    };
+    const handleImportList = async () => {
+        try {
+            const path = await SelectDownloadListFile();
+            if (!path)
+                return;
+            const count = await ImportDownloadList(path);
+            if (count > 0) {
+                toast.success(`Queued ${count} download${count === 1 ? "" : "s"} from list`);
+            }
+            else {
+                toast.info("No downloads found in list");
+            }
+        }
+        catch (error) {
+            console.error("Failed to import download list:", error);
+            toast.error(`Failed to import: ${error}`);
+        }
+    };
     const getStatusIcon = (status: string) => {
         switch (status) {
             case "downloading":
@@ -81,11 +100,16 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             case "completed":
                 return <CheckCircle2 className="h-4 w-4 text-green-500"/>;
             case "failed":
+            case "verification_failed":
                 return <XCircle className="h-4 w-4 text-red-500"/>;
             case "skipped":
                 return <FileCheck className="h-4 w-4 text-yellow-500"/>;
+            case "cancelled":
+                return <Ban className="h-4 w-4 text-muted-foreground"/>;
             case "queued":
                 return <Clock className="h-4 w-4 text-muted-foreground"/>;
+            case "retrying":
+                return <RotateCcw className="h-4 w-4 text-orange-500"/>;
             default:
                 return null;
         }
@@ -95,13 +119,31 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             downloading: "default",
             completed: "outline",
             failed: "destructive",
+            verification_failed: "destructive",
             skipped: "secondary",
+            cancelled: "outline",
             queued: "outline",
+            retrying: "secondary",
         };
         return (<Badge variant={variants[status] || "outline"} className="text-xs">
       {status}
     </Badge>);
     };
+    const formatETA = (seconds: number) => {
+        if (!seconds || seconds <= 0)
+            return "—";
+        const total = Math.ceil(seconds);
+        const hours = Math.floor(total / 3600);
+        const minutes = Math.floor((total % 3600) / 60);
+        const secs = total % 60;
+        if (hours > 0) {
+            return `${hours}h ${minutes}m`;
+        }
+        else if (minutes > 0) {
+            return `${minutes}m ${secs}s`;
+        }
+        return `${secs}s`;
+    };
     const formatDuration = (startTimestamp: number) => {
         if (startTimestamp === 0)
             return "—";
@@ -127,6 +169,10 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
     const filteredQueue = queueInfo.queue.filter((item: any) => {
         if (filterStatus === "all")
             return true;
+        if (filterStatus === "failed")
+            return item.status === "failed" || item.status === "verification_failed";
+        if (filterStatus === "queued")
+            return item.status === "queued" || item.status === "retrying";
         return item.status === filterStatus;
     });
     return (<Dialog open={isOpen} onOpenChange={onClose}>
@@ -135,14 +181,22 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
         <div className="flex items-center justify-between mb-4">
           <DialogTitle className="text-lg font-semibold hover:text-primary transition-colors cursor-pointer" onClick={handleReset}>Download Queue</DialogTitle>
           <div className="flex items-center gap-2">
-            {(queueInfo.completed_count > 0 || queueInfo.failed_count > 0 || queueInfo.skipped_count > 0) && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleClearHistory}>
+            {(queueInfo.completed_count > 0 || queueInfo.failed_count > 0 || queueInfo.skipped_count > 0 || queueInfo.cancelled_count > 0) && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleClearHistory}>
               <Trash2 className="h-3 w-3"/>
               Clear History
             </Button>)}
+            {queueInfo.failed_count > 0 && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleRetryFailed}>
+              <RotateCcw className="h-3 w-3"/>
+              Retry Failed
+            </Button>)}
             {queueInfo.failed_count > 0 && (<Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleExportFailed}>
               <FileDown className="h-3 w-3"/>
               Export Failures
             </Button>)}
+            <Button variant="ghost" size="sm" className="h-7 text-xs gap-1.5" onClick={handleImportList}>
+              <FileUp className="h-3 w-3"/>
+              Import List
+            </Button>
             <Button variant="ghost" size="icon" className="h-7 w-7 rounded-full hover:bg-muted" onClick={onClose}>
               <X className="h-4 w-4"/>
             </Button>
@@ -171,6 +225,11 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
             <span className="text-muted-foreground">Failed:</span>
             <span className="font-semibold">{queueInfo.failed_count}</span>
           </div>
+          {queueInfo.cancelled_count > 0 && (<div className={`flex items-center gap-1.5 cursor-pointer hover:opacity-80 transition-all select-none ${filterStatus === 'cancelled' ? 'bg-secondary px-2 py-0.5 rounded-md ring-1 ring-border' : ''}`} onClick={() => toggleFilter('cancelled')}>
+            <Ban className="h-3.5 w-3.5 text-muted-foreground"/>
+            <span className="text-muted-foreground">Cancelled:</span>
+            <span className="font-semibold">{queueInfo.cancelled_count}</span>
+          </div>)}
         </div>
 
 
@@ -198,6 +257,11 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
               {queueInfo.session_start_time > 0 ? formatDuration(queueInfo.session_start_time) : "—"}
             </span>
           </div>
+          {queueInfo.is_downloading && queueInfo.session_eta_seconds > 0 && (<div className="flex items-center gap-1.5">
+            <Clock className="h-3.5 w-3.5 text-muted-foreground"/>
+            <span className="text-muted-foreground">Remaining:</span>
+            <span className="font-semibold font-mono">{formatETA(queueInfo.session_eta_seconds)}</span>
+          </div>)}
         </div>
 
       </DialogHeader>
@@ -224,13 +288,23 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
                       {item.album_name && ` • ${item.album_name}`}
                     </p>
                   </div>
-                  {getStatusBadge(item.status)}
+                  <div className="flex items-center gap-1">
+                    {getStatusBadge(item.status)}
+                    {(item.status === "queued" || item.status === "downloading" || item.status === "retrying") && (<Button variant="ghost" size="icon" className="h-6 w-6" title="Cancel" onClick={() => handleCancel(item.id)}>
+                      <X className="h-3.5 w-3.5"/>
+                    </Button>)}
+                  </div>
                 </div>
 
 
+                {item.status === "downloading" && item.percent > 0 && (<div className="mt-1.5 h-1 w-full rounded bg-muted overflow-hidden">
+                  <div className="h-full bg-primary transition-all" style={{ width: `${item.percent}%` }}/>
+                </div>)}
                 {item.status === "downloading" && (<div className="flex items-center gap-3 mt-1.5 text-xs text-muted-foreground font-mono">
                   <span>
-                    {item.progress > 0
+                    {item.progress > 0 && item.total_size > 0
+                    ? `${item.progress.toFixed(2)} / ${item.total_size.toFixed(2)} MB (${item.percent.toFixed(0)}%)`
+                    : item.progress > 0
                     ? `${item.progress.toFixed(2)} MB`
                     : queueInfo.is_downloading && queueInfo.current_speed > 0
                         ? "Downloading..."
@@ -243,6 +317,7 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
                         ? `${queueInfo.current_speed.toFixed(2)} MB/s`
                         : "—"}
                   </span>
+                  {item.eta_seconds > 0 && (<span>ETA {formatETA(item.eta_seconds)}</span>)}
                 </div>)}
 
 
@@ -251,12 +326,19 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
                 </div>)}
 
 
+                {item.status === "retrying" && (<div className="mt-1.5 text-xs text-orange-500">
+                  Attempt {item.retry_count + 1}
+                  {item.next_retry_at > 0 && ` at ${new Date(item.next_retry_at * 1000).toLocaleTimeString()}`}
+                  {item.error_message && ` • ${item.error_message}`}
+                </div>)}
+
+
                 {item.status === "skipped" && (<div className="mt-1.5 text-xs text-muted-foreground">
                   File already exists
                 </div>)}
 
 
-                {item.status === "failed" && item.error_message && (<div className="mt-1.5 text-xs text-red-500 bg-red-50 dark:bg-red-950/20 rounded px-2 py-1">
+                {(item.status === "failed" || item.status === "verification_failed") && item.error_message && (<div className="mt-1.5 text-xs text-red-500 bg-red-50 dark:bg-red-950/20 rounded px-2 py-1">
                   {item.error_message}
                 </div>)}
 
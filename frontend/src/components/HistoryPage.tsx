@@ -1,14 +1,15 @@
 import { useEffect, useState, useRef } from "react";
 import { Button } from "@/components/ui/button";
-import { Trash2, ExternalLink, Search, ArrowUpDown, History, Play, Pause, Database, CloudUpload, Music2, Disc3, ListMusic, UserRound } from "lucide-react";
+import { Trash2, ExternalLink, Search, ArrowUpDown, History, Play, Pause, Database, CloudUpload, Music2, Disc3, ListMusic, UserRound, Download, Upload, Link2 } from "lucide-react";
 import { Badge } from "@/components/ui/badge";
 import { Input } from "@/components/ui/input";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select";
 import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle } from "@/components/ui/dialog";
 import { Pagination, PaginationContent, PaginationEllipsis, PaginationItem, PaginationLink, PaginationNext, PaginationPrevious } from "@/components/ui/pagination";
-import { GetDownloadHistory, ClearDownloadHistory, GetPreviewURL, GetFetchHistory, DeleteDownloadHistoryItem, DeleteFetchHistoryItem, ClearFetchHistoryByType } from "../../wailsjs/go/main/App";
+import { QueryDownloadHistory, ExportHistory, ImportHistory, SelectHistoryFile, RelinkHistory, SelectFolder, ClearDownloadHistory, GetPreviewURL, GetFetchHistory, DeleteDownloadHistoryItem, DeleteFetchHistoryItem, ClearFetchHistoryByType } from "../../wailsjs/go/main/App";
 import { Tooltip, TooltipContent, TooltipProvider, TooltipTrigger } from "@/components/ui/tooltip";
 import { openExternal } from "@/lib/utils";
+import { toastWithSound as toast } from "@/lib/toast-with-sound";
 const formatDate = (timestamp: number) => {
     const date = new Date(timestamp * 1000);
     const year = date.getFullYear();
@@ -31,7 +32,22 @@ interface DownloadHistoryItem {
     format: string;
     path: string;
     timestamp: number;
+    missing?: boolean;
 }
+const DOWNLOAD_SORTS: Record<string, {
+    sort_by: string;
+    order: string;
+}> = {
+    default: { sort_by: "timestamp", order: "desc" },
+    date_desc: { sort_by: "timestamp", order: "desc" },
+    date_asc: { sort_by: "timestamp", order: "asc" },
+    title_asc: { sort_by: "title", order: "asc" },
+    title_desc: { sort_by: "title", order: "desc" },
+    artist_asc: { sort_by: "artist", order: "asc" },
+    artist_desc: { sort_by: "artist", order: "desc" },
+    duration_asc: { sort_by: "duration", order: "asc" },
+    duration_desc: { sort_by: "duration", order: "desc" },
+};
 interface FetchHistoryItem {
     id: string;
     url: string;
@@ -48,7 +64,10 @@ interface HistoryPageProps {
 export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
     const [activeTab, setActiveTab] = useState("downloads");
     const [downloadHistory, setDownloadHistory] = useState<DownloadHistoryItem[]>([]);
-    const [filteredDownloadHistory, setFilteredDownloadHistory] = useState<DownloadHistoryItem[]>([]);
+    const [downloadTotal, setDownloadTotal] = useState(0);
+    const [downloadMatched, setDownloadMatched] = useState(0);
+    const [downloadCursors, setDownloadCursors] = useState<string[]>([""]);
+    const [downloadNextCursor, setDownloadNextCursor] = useState("");
     const [showClearDownloadConfirm, setShowClearDownloadConfirm] = useState(false);
     const [downloadSearchQuery, setDownloadSearchQuery] = useState("");
     const [downloadSortBy, setDownloadSortBy] = useState("default");
@@ -64,8 +83,19 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
     const ITEMS_PER_PAGE = 50;
     const fetchDownloadHistory = async () => {
         try {
-            const items = await GetDownloadHistory();
-            setDownloadHistory(items || []);
+            const cursor = downloadCursors[downloadCurrentPage - 1] || "";
+            const page = await QueryDownloadHistory({
+                text: downloadSearchQuery.trim(),
+                ...DOWNLOAD_SORTS[downloadSortBy],
+                cursor,
+                limit: ITEMS_PER_PAGE,
+            } as any);
+            setDownloadHistory(page.items || []);
+            setDownloadTotal(page.total);
+            if (!cursor) {
+                setDownloadMatched(page.matched);
+            }
+            setDownloadNextCursor(page.next_cursor || "");
         }
         catch (err) {
             console.error("Failed to fetch download history:", err);
@@ -82,16 +112,19 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
     };
     useEffect(() => {
         if (activeTab === "downloads") {
-            fetchDownloadHistory();
+            const timeout = setTimeout(fetchDownloadHistory, downloadSearchQuery ? 250 : 0);
             const interval = setInterval(fetchDownloadHistory, 5000);
-            return () => clearInterval(interval);
+            return () => {
+                clearTimeout(timeout);
+                clearInterval(interval);
+            };
         }
         else {
             fetchFetchHistory();
             const interval = setInterval(fetchFetchHistory, 5000);
             return () => clearInterval(interval);
         }
-    }, [activeTab]);
+    }, [activeTab, downloadSearchQuery, downloadSortBy, downloadCurrentPage, downloadCursors]);
     useEffect(() => {
         return () => {
             if (audioRef.current) {
@@ -100,38 +133,7 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
         };
     }, []);
     useEffect(() => {
-        let result = [...downloadHistory];
-        if (downloadSearchQuery) {
-            const query = downloadSearchQuery.toLowerCase();
-            result = result.filter(item => item.title.toLowerCase().includes(query) ||
-                item.artists.toLowerCase().includes(query) ||
-                item.album.toLowerCase().includes(query));
-        }
-        const parseDuration = (str: string) => {
-            const parts = str.split(':').map(Number);
-            if (parts.length === 2)
-                return parts[0] * 60 + parts[1];
-            if (parts.length === 3)
-                return parts[0] * 3600 + parts[1] * 60 + parts[2];
-            return 0;
-        };
-        result.sort((a, b) => {
-            switch (downloadSortBy) {
-                case "default":
-                case "date_desc": return b.timestamp - a.timestamp;
-                case "date_asc": return a.timestamp - b.timestamp;
-                case "title_asc": return a.title.localeCompare(b.title);
-                case "title_desc": return b.title.localeCompare(a.title);
-                case "artist_asc": return a.artists.localeCompare(b.artists);
-                case "artist_desc": return b.artists.localeCompare(a.artists);
-                case "duration_asc": return parseDuration(a.duration_str) - parseDuration(b.duration_str);
-                case "duration_desc": return parseDuration(b.duration_str) - parseDuration(a.duration_str);
-                default: return 0;
-            }
-        });
-        setFilteredDownloadHistory(result);
-    }, [downloadHistory, downloadSearchQuery, downloadSortBy]);
-    useEffect(() => {
+        setDownloadCursors([""]);
         setDownloadCurrentPage(1);
     }, [downloadSearchQuery, downloadSortBy]);
     useEffect(() => {
@@ -176,12 +178,58 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
     };
     const handleClearDownloadHistory = async () => {
         await ClearDownloadHistory();
+        setDownloadCursors([""]);
+        setDownloadCurrentPage(1);
         fetchDownloadHistory();
         setShowClearDownloadConfirm(false);
     };
     const handleDeleteDownloadItem = async (id: string) => {
         await DeleteDownloadHistoryItem(id);
         setDownloadHistory(prev => prev.filter(item => item.id !== id));
+        setDownloadTotal(prev => Math.max(0, prev - 1));
+    };
+    const handleExportHistory = async () => {
+        try {
+            const message = await ExportHistory();
+            toast.success(message);
+        }
+        catch (err) {
+            toast.error(`Failed to export history: ${err}`);
+        }
+    };
+    const handleImportHistory = async () => {
+        try {
+            const path = await SelectHistoryFile();
+            if (!path)
+                return;
+            const result = await ImportHistory(path);
+            toast.success(`Imported ${result.downloads} downloads and ${result.fetches} fetches`);
+            setDownloadCursors([""]);
+            setDownloadCurrentPage(1);
+            fetchDownloadHistory();
+            fetchFetchHistory();
+        }
+        catch (err) {
+            toast.error(`Failed to import history: ${err}`);
+        }
+    };
+    const handleRelinkHistory = async () => {
+        try {
+            const root = await SelectFolder("");
+            if (!root)
+                return;
+            const result = await RelinkHistory(root);
+            if (result.missing > 0) {
+                toast.warning(`Relinked ${result.relinked.length} items, ${result.missing} still missing`);
+            }
+            else {
+                toast.success(`Relinked ${result.relinked.length} items`);
+            }
+            fetchDownloadHistory();
+        }
+        catch (err) {
+            toast.error(`Failed to relink history: ${err}`);
+        }
     };
     const handleClearFetchHistory = async () => {
         await ClearFetchHistoryByType(activeFetchTab);
@@ -220,19 +268,26 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
         return pages;
     };
     const renderDownloadHistory = () => {
-        const totalPages = Math.ceil(filteredDownloadHistory.length / ITEMS_PER_PAGE);
         const startIndex = (downloadCurrentPage - 1) * ITEMS_PER_PAGE;
-        const paginated = filteredDownloadHistory.slice(startIndex, startIndex + ITEMS_PER_PAGE);
+        const paginated = downloadHistory;
+        const goToNextDownloadPage = () => {
+            if (!downloadNextCursor)
+                return;
+            setDownloadCursors(prev => [...prev.slice(0, downloadCurrentPage), downloadNextCursor]);
+            setDownloadCurrentPage(downloadCurrentPage + 1);
+        };
         return (<div className="space-y-6">
                 <div className="flex flex-col gap-4">
                      <div className="flex items-center justify-between">
                         <div className="flex items-center gap-2">
                              <h2 className="text-xl font-bold tracking-tight">Downloads</h2>
-                             {downloadHistory.length > 0 && (<Badge variant="secondary" className="font-mono">
-                                    {downloadHistory.length.toLocaleString('en-US')}
+                             {downloadTotal > 0 && (<Badge variant="secondary" className="font-mono">
+                                    {downloadSearchQuery.trim()
+                    ? `${downloadMatched.toLocaleString('en-US')} / ${downloadTotal.toLocaleString('en-US')}`
+                    : downloadTotal.toLocaleString('en-US')}
                                 </Badge>)}
                         </div>
-                        <Button variant="outline" size="sm" onClick={() => setShowClearDownloadConfirm(true)} disabled={downloadHistory.length === 0} className="cursor-pointer gap-2">
+                        <Button variant="outline" size="sm" onClick={() => setShowClearDownloadConfirm(true)} disabled={downloadTotal === 0} className="cursor-pointer gap-2">
                              <Trash2 className="h-4 w-4"/> Clear All
                         </Button>
                     </div>
@@ -292,7 +347,10 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
                                             <div className="flex items-center gap-3 min-w-0">
                                                 <img src={item.cover_url || "https://placehold.co/300?text=No+Cover"} alt={item.album} className="h-10 w-10 rounded shrink-0 bg-secondary object-cover" onError={(e) => { (e.target as HTMLImageElement).src = "https://placehold.co/300?text=No+Cover"; }}/>
                                                 <div className="flex flex-col min-w-0 flex-1">
-                                                    <span className="font-medium text-sm truncate">{item.title}</span>
+                                                    <span className="flex items-center gap-2 min-w-0">
+                                                        <span className="font-medium text-sm truncate">{item.title}</span>
+                                                        {item.missing && (<Badge variant="destructive" className="shrink-0 text-[10px] px-1.5 py-0">Missing</Badge>)}
+                                                    </span>
                                                     <span className="text-xs text-muted-foreground truncate">{item.artists}</span>
                                                 </div>
                                             </div>
@@ -364,7 +422,7 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
                         </table>)}
                  </div>
 
-                 {totalPages > 1 && (<Pagination>
+                 {(downloadCurrentPage > 1 || downloadNextCursor) && (<Pagination>
                         <PaginationContent>
                             <PaginationItem>
                                 <PaginationPrevious href="#" onClick={(e) => {
@@ -373,24 +431,18 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
                         setDownloadCurrentPage(downloadCurrentPage - 1);
                 }} className={downloadCurrentPage === 1 ? "pointer-events-none opacity-50" : "cursor-pointer"}/>
                             </PaginationItem>
-                            
-                            {getPaginationPages(downloadCurrentPage, totalPages).map((page, index) => (page === 'ellipsis' ? (<PaginationItem key={`ellipsis-${index}`}>
-                                        <PaginationEllipsis />
-                                    </PaginationItem>) : (<PaginationItem key={page}>
-                                        <PaginationLink href="#" onClick={(e) => {
-                        e.preventDefault();
-                        setDownloadCurrentPage(page as number);
-                    }} isActive={downloadCurrentPage === page} className="cursor-pointer">
-                                            {page}
-                                        </PaginationLink>
-                                    </PaginationItem>)))}
+
+                            <PaginationItem>
+                                <PaginationLink href="#" onClick={(e) => e.preventDefault()} isActive className="cursor-default">
+                                    {downloadCurrentPage}
+                                </PaginationLink>
+                            </PaginationItem>
 
                             <PaginationItem>
                                 <PaginationNext href="#" onClick={(e) => {
                     e.preventDefault();
-                    if (downloadCurrentPage < totalPages)
-                        setDownloadCurrentPage(downloadCurrentPage + 1);
-                }} className={downloadCurrentPage === totalPages ? "pointer-events-none opacity-50" : "cursor-pointer"}/>
+                    goToNextDownloadPage();
+                }} className={!downloadNextCursor ? "pointer-events-none opacity-50" : "cursor-pointer"}/>
                             </PaginationItem>
                         </PaginationContent>
                     </Pagination>)}
@@ -554,8 +606,19 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
             </div>);
     };
     return (<div className="space-y-6">
-            <div className="flex items-center gap-4">
+            <div className="flex items-center justify-between gap-4">
                 <h1 className="text-2xl font-bold">History</h1>
+                <div className="flex items-center gap-2">
+                    <Button variant="outline" size="sm" onClick={handleRelinkHistory} className="cursor-pointer gap-2">
+                        <Link2 className="h-4 w-4"/> Relink
+                    </Button>
+                    <Button variant="outline" size="sm" onClick={handleImportHistory} className="cursor-pointer gap-2">
+                        <Upload className="h-4 w-4"/> Import
+                    </Button>
+                    <Button variant="outline" size="sm" onClick={handleExportHistory} className="cursor-pointer gap-2">
+                        <Download className="h-4 w-4"/> Export
+                    </Button>
+                </div>
             </div>
 
             <div className="border-b">
@@ -93,7 +93,13 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
         loadDefaults();
     }, []);
     const handleSave = async () => {
-        await saveSettings(tempSettings);
+        try {
+            await saveSettings(tempSettings);
+        }
+        catch (error) {
+            toast.error(`Failed to save settings: ${error}`);
+            return;
+        }
         setSavedSettings(tempSettings);
         toast.success("Settings saved");
         onUnsavedChangesChange?.(false);
@@ -248,6 +254,7 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
                     </SelectTrigger>
                     <SelectContent>
                       <SelectItem value="auto">Auto</SelectItem>
+                      <SelectItem value="best">Best Available</SelectItem>
                       <SelectItem value="tidal">
                         <span className="flex items-center">
                           <TidalIcon />
@@ -614,6 +621,15 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
                       Use Single Genre
                     </Label>
                   </div>)}
+                <div className="flex items-center gap-3">
+                  <Switch id="embed-source-tags" checked={tempSettings.embedSourceTags ?? false} onCheckedChange={(checked) => setTempSettings((prev) => ({
+                ...prev,
+                embedSourceTags: checked,
+            }))}/>
+                  <Label htmlFor="embed-source-tags" className="cursor-pointer text-sm font-normal">
+                    Embed Source Tags
+                  </Label>
+                </div>
               </div>
             </div>
           </div>)}
@@ -23,7 +23,7 @@ export function TitleBar() {
     const handleSpotFetchAPIToggle = () => {
         const newValue = !useSpotFetchAPI;
         setUseSpotFetchAPI(newValue);
-        updateSettings({ useSpotFetchAPI: newValue });
+        updateSettings({ useSpotFetchAPI: newValue }).catch(() => setUseSpotFetchAPI(!newValue));
     };
     const handleMinimize = () => {
         WindowMinimise();
@@ -1,10 +1,10 @@
 import { useState, useRef } from "react";
-import { downloadTrack, fetchSpotifyMetadata } from "@/lib/api";
+import { downloadTrack, enqueueDownloads, waitForDownload, fetchSpotifyMetadata } from "@/lib/api";
 import { getSettings, parseTemplate, type TemplateData } from "@/lib/settings";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { joinPath, sanitizePath, getFirstArtist } from "@/lib/utils";
 import { logger } from "@/lib/logger";
-import type { TrackMetadata } from "@/types/api";
+import type { DownloadRequest, DownloadResponse, TrackMetadata } from "@/types/api";
 interface CheckFileExistenceRequest {
     spotify_id: string;
     track_name: string;
@@ -20,6 +20,7 @@ interface CheckFileExistenceRequest {
     include_track_number?: boolean;
     audio_format?: string;
     relative_path?: string;
+    isrc?: string;
 }
 interface FileExistenceResult {
     spotify_id: string;
@@ -106,6 +107,18 @@ export function useDownload(region: string) {
                 }
             }
         }
+        let streamingURLs: any = null;
+        if (service === "auto" && spotifyId) {
+            try {
+                const { GetStreamingURLs } = await import("../../wailsjs/go/main/App");
+                const urlsJson = await GetStreamingURLs(spotifyId, region);
+                streamingURLs = JSON.parse(urlsJson);
+            }
+            catch (err) {
+                console.error("Failed to get streaming URLs:", err);
+            }
+        }
+        const isrc: string | undefined = streamingURLs?.isrc || undefined;
         const serviceForCheck = service === "auto" ? "flac" : (service === "tidal" ? "flac" : (service === "qobuz" ? "flac" : "flac"));
         let fileExists = false;
         if (trackName && artistName) {
@@ -124,6 +137,7 @@ export function useDownload(region: string) {
                     filename_format: settings.filenameTemplate || "",
                     include_track_number: settings.trackNumber || false,
                     audio_format: serviceForCheck,
+                    isrc,
                 };
                 const existenceResults = await CheckFilesExistence(outputDir, settings.downloadPath, [checkRequest]);
                 if (existenceResults.length > 0 && existenceResults[0].exists) {
@@ -146,17 +160,6 @@ export function useDownload(region: string) {
             itemID = await AddToDownloadQueue(id, trackName || "", displayArtist || "", albumName || "");
         }
         if (service === "auto") {
-            let streamingURLs: any = null;
-            if (spotifyId) {
-                try {
-                    const { GetStreamingURLs } = await import("../../wailsjs/go/main/App");
-                    const urlsJson = await GetStreamingURLs(spotifyId, region);
-                    streamingURLs = JSON.parse(urlsJson);
-                }
-                catch (err) {
-                    console.error("Failed to get streaming URLs:", err);
-                }
-            }
             const durationSeconds = durationMs ? Math.round(durationMs / 1000) : undefined;
             const order = (settings.autoOrder || "tidal-amazon-qobuz").split("-");
             let lastResponse: any = { success: false, error: "No matching services found" };
@@ -188,6 +191,7 @@ export function useDownload(region: string) {
                             service_url: streamingURLs.tidal_url,
                             duration: durationSeconds,
                             item_id: itemID,
+                            isrc,
                             audio_format: tidalQuality,
                             spotify_track_number: spotifyTrackNumber,
                             spotify_disc_number: spotifyDiscNumber,
@@ -236,6 +240,7 @@ export function useDownload(region: string) {
                             embed_max_quality_cover: settings.embedMaxQualityCover,
                             service_url: streamingURLs.amazon_url,
                             item_id: itemID,
+                            isrc,
                             spotify_track_number: spotifyTrackNumber,
                             spotify_disc_number: spotifyDiscNumber,
                             spotify_total_tracks: spotifyTotalTracks,
@@ -281,6 +286,7 @@ export function useDownload(region: string) {
                             embed_lyrics: settings.embedLyrics,
                             embed_max_quality_cover: settings.embedMaxQualityCover,
                             item_id: itemID,
+                            isrc,
                             audio_format: qobuzQuality,
                             spotify_track_number: spotifyTrackNumber,
                             spotify_disc_number: spotifyDiscNumber,
@@ -328,6 +334,7 @@ export function useDownload(region: string) {
                             embed_max_quality_cover: settings.embedMaxQualityCover,
                             duration: durationSeconds,
                             item_id: itemID,
+                            isrc,
                             audio_format: "flac",
                             spotify_track_number: spotifyTrackNumber,
                             spotify_disc_number: spotifyDiscNumber,
@@ -375,7 +382,7 @@ export function useDownload(region: string) {
         }
         logger.debug(`trying ${service} for: ${trackName} - ${artistName}`);
         const singleServiceResponse = await downloadTrack({
-            service: service as "tidal" | "qobuz" | "amazon" | "deezer",
+            service: service as "best" | "tidal" | "qobuz" | "amazon" | "deezer",
             query,
             track_name: trackName,
             artist_name: displayArtist,
@@ -393,7 +400,9 @@ export function useDownload(region: string) {
             embed_max_quality_cover: settings.embedMaxQualityCover,
             duration: durationSecondsForFallback,
             item_id: itemID,
+            isrc,
             audio_format: audioFormat,
+            allow_fallback: settings.allowFallback,
             spotify_track_number: spotifyTrackNumber,
             spotify_disc_number: spotifyDiscNumber,
             spotify_total_tracks: spotifyTotalTracks,
@@ -409,16 +418,19 @@ export function useDownload(region: string) {
         }
         return singleServiceResponse;
     };
-    const downloadWithItemID = async (settings: any, itemID: string, trackName?: string, artistName?: string, albumName?: string, folderName?: string, position?: number, spotifyId?: string, durationMs?: number, isAlbum?: boolean, releaseYear?: string, albumArtist?: string, releaseDate?: string, coverUrl?: string, spotifyTrackNumber?: number, spotifyDiscNumber?: number, spotifyTotalTracks?: number, spotifyTotalDiscs?: number, copyright?: string, publisher?: string) => {
+    const buildBatchRequest = async (settings: any, itemID: string, track: TrackMetadata, folderName?: string, position?: number, isAlbum?: boolean): Promise<DownloadRequest> => {
         const service = settings.downloader;
+        const trackName = track.name;
+        const artistName = track.artists;
+        const albumName = track.album_name;
+        const spotifyId = track.spotify_id;
         const query = trackName && artistName ? `${trackName} ${artistName}` : undefined;
         const os = settings.operatingSystem;
         let outputDir = settings.downloadPath;
-        let useAlbumTrackNumber = false;
         const placeholder = "__SLASH_PLACEHOLDER__";
-        let finalReleaseDate = releaseDate;
-        let finalTrackNumber = spotifyTrackNumber || 0;
-        if (spotifyId) {
+        let finalReleaseDate = track.release_date;
+        let finalTrackNumber = track.track_number || 0;
+        if (spotifyId && (!finalReleaseDate || finalTrackNumber <= 0)) {
             try {
                 const trackURL = `https://open.spotify.com/track/${spotifyId}`;
                 const trackMetadata = await fetchSpotifyMetadata(trackURL, false, 0, 10);
@@ -434,12 +446,13 @@ export function useDownload(region: string) {
             catch (err) {
             }
         }
-        const yearValue = releaseYear || finalReleaseDate?.substring(0, 4);
+        const yearValue = finalReleaseDate?.substring(0, 4);
         const hasSubfolder = settings.folderTemplate && settings.folderTemplate.trim() !== "";
         const trackNumberForTemplate = (hasSubfolder && finalTrackNumber > 0) ? finalTrackNumber : (position || 0);
         const displayArtist = settings.useFirstArtistOnly && artistName
             ? getFirstArtist(artistName)
             : artistName;
+        const albumArtist = track.album_artist || "";
         const displayAlbumArtist = settings.useFirstArtistOnly && albumArtist
             ? getFirstArtist(albumArtist)
             : albumArtist;
@@ -450,7 +463,7 @@ export function useDownload(region: string) {
             title: trackName?.replace(/\//g, placeholder),
             track: trackNumberForTemplate,
             year: yearValue,
-            date: releaseDate,
+            date: track.release_date,
             playlist: folderName?.replace(/\//g, placeholder),
         };
         const folderTemplate = settings.folderTemplate || "";
@@ -461,234 +474,13 @@ export function useDownload(region: string) {
         if (settings.folderTemplate) {
             const folderPath = parseTemplate(settings.folderTemplate, templateData);
             if (folderPath) {
-                const parts = folderPath.split("/").filter(p => p.trim());
+                const parts = folderPath.split("/").filter((p: string) => p.trim());
                 for (const part of parts) {
                     const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
                     outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
                 }
             }
         }
-        if (service === "auto") {
-            let streamingURLs: any = null;
-            if (spotifyId) {
-                try {
-                    const { GetStreamingURLs } = await import("../../wailsjs/go/main/App");
-                    const urlsJson = await GetStreamingURLs(spotifyId, region);
-                    streamingURLs = JSON.parse(urlsJson);
-                }
-                catch (err) {
-                    console.error("Failed to get streaming URLs:", err);
-                }
-            }
-            const durationSeconds = durationMs ? Math.round(durationMs / 1000) : undefined;
-            const order = (settings.autoOrder || "tidal-amazon-qobuz").split("-");
-            let lastResponse: any = { success: false, error: "No matching services found" };
-            const fallbackErrors: string[] = [];
-            const is24Bit = (settings.autoQuality || "24") === "24";
-            const tidalQuality = is24Bit ? "HI_RES_LOSSLESS" : "LOSSLESS";
-            const qobuzQuality = is24Bit ? "27" : "6";
-            for (const s of order) {
-                if (s === "tidal" && streamingURLs?.tidal_url) {
-                    try {
-                        logger.debug(`trying tidal for: ${trackName} - ${artistName}`);
-                        const response = await downloadTrack({
-                            service: "tidal",
-                            query,
-                            track_name: trackName,
-                            artist_name: displayArtist,
-                            album_name: albumName,
-                            album_artist: displayAlbumArtist,
-                            release_date: finalReleaseDate || releaseDate,
-                            cover_url: coverUrl,
-                            output_dir: outputDir,
-                            filename_format: settings.filenameTemplate,
-                            track_number: settings.trackNumber,
-                            position,
-                            use_album_track_number: useAlbumTrackNumber,
-                            spotify_id: spotifyId,
-                            embed_lyrics: settings.embedLyrics,
-                            embed_max_quality_cover: settings.embedMaxQualityCover,
-                            service_url: streamingURLs.tidal_url,
-                            duration: durationSeconds,
-                            item_id: itemID,
-                            audio_format: tidalQuality,
-                            spotify_track_number: spotifyTrackNumber,
-                            spotify_disc_number: spotifyDiscNumber,
-                            spotify_total_tracks: spotifyTotalTracks,
-                            spotify_total_discs: spotifyTotalDiscs,
-                            copyright: copyright,
-                            publisher: publisher,
-                            use_first_artist_only: settings.useFirstArtistOnly,
-                            use_single_genre: settings.useSingleGenre,
-                            embed_genre: settings.embedGenre,
-                        });
-                        if (response.success) {
-                            logger.success(`tidal: ${trackName} - ${artistName}`);
-                            return response;
-                        }
-                        const errMsg = response.error || response.message || "Failed";
-                        fallbackErrors.push(`[Tidal] ${errMsg}`);
-                        lastResponse = response;
-                        logger.warning(`tidal failed, trying next...`);
-                    }
-                    catch (err) {
-                        logger.error(`tidal error: ${err}`);
-                        fallbackErrors.push(`[Tidal] ${String(err)}`);
-                        lastResponse = { success: false, error: String(err) };
-                    }
-                }
-                else if (s === "amazon" && streamingURLs?.amazon_url) {
-                    try {
-                        logger.debug(`trying amazon for: ${trackName} - ${artistName}`);
-                        const response = await downloadTrack({
-                            service: "amazon",
-                            query,
-                            track_name: trackName,
-                            artist_name: displayArtist,
-                            album_name: albumName,
-                            album_artist: displayAlbumArtist,
-                            release_date: finalReleaseDate || releaseDate,
-                            cover_url: coverUrl,
-                            output_dir: outputDir,
-                            filename_format: settings.filenameTemplate,
-                            track_number: settings.trackNumber,
-                            position,
-                            use_album_track_number: useAlbumTrackNumber,
-                            spotify_id: spotifyId,
-                            embed_lyrics: settings.embedLyrics,
-                            embed_max_quality_cover: settings.embedMaxQualityCover,
-                            service_url: streamingURLs.amazon_url,
-                            item_id: itemID,
-                            spotify_track_number: spotifyTrackNumber,
-                            spotify_disc_number: spotifyDiscNumber,
-                            spotify_total_tracks: spotifyTotalTracks,
-                            spotify_total_discs: spotifyTotalDiscs,
-                            copyright: copyright,
-                            publisher: publisher,
-                            use_first_artist_only: settings.useFirstArtistOnly,
-                            use_single_genre: settings.useSingleGenre,
-                            embed_genre: settings.embedGenre,
-                        });
-                        if (response.success) {
-                            logger.success(`amazon: ${trackName} - ${artistName}`);
-                            return response;
-                        }
-                        const errMsg = response.error || response.message || "Failed";
-                        fallbackErrors.push(`[Amazon] ${errMsg}`);
-                        lastResponse = response;
-                        logger.warning(`amazon failed, trying next...`);
-                    }
-                    catch (err) {
-                        logger.error(`amazon error: ${err}`);
-                        fallbackErrors.push(`[Amazon] ${String(err)}`);
-                        lastResponse = { success: false, error: String(err) };
-                    }
-                }
-                else if (s === "qobuz") {
-                    try {
-                        logger.debug(`trying qobuz for: ${trackName} - ${artistName}`);
-                        const response = await downloadTrack({
-                            service: "qobuz",
-                            query,
-                            track_name: trackName,
-                            artist_name: displayArtist,
-                            album_name: albumName,
-                            album_artist: displayAlbumArtist,
-                            release_date: finalReleaseDate || releaseDate,
-                            cover_url: coverUrl,
-                            output_dir: outputDir,
-                            filename_format: settings.filenameTemplate,
-                            track_number: settings.trackNumber,
-                            position: trackNumberForTemplate,
-                            use_album_track_number: useAlbumTrackNumber,
-                            spotify_id: spotifyId,
-                            embed_lyrics: settings.embedLyrics,
-                            embed_max_quality_cover: settings.embedMaxQualityCover,
-                            duration: durationSeconds,
-                            item_id: itemID,
-                            audio_format: qobuzQuality,
-                            spotify_track_number: spotifyTrackNumber,
-                            spotify_disc_number: spotifyDiscNumber,
-                            spotify_total_tracks: spotifyTotalTracks,
-                            spotify_total_discs: spotifyTotalDiscs,
-                            copyright: copyright,
-                            publisher: publisher,
-                            use_first_artist_only: settings.useFirstArtistOnly,
-                            use_single_genre: settings.useSingleGenre,
-                            embed_genre: settings.embedGenre,
-                        });
-                        if (response.success) {
-                            logger.success(`qobuz: ${trackName} - ${artistName}`);
-                            return response;
-                        }
-                        const errMsg = response.error || response.message || "Failed";
-                        fallbackErrors.push(`[Qobuz] ${errMsg}`);
-                        lastResponse = response;
-                        logger.warning(`qobuz failed, trying next...`);
-                    }
-                    catch (err) {
-                        logger.error(`qobuz error: ${err}`);
-                        fallbackErrors.push(`[Qobuz] ${String(err)}`);
-                        lastResponse = { success: false, error: String(err) };
-                    }
-                }
-                else if (s === "deezer") {
-                    try {
-                        logger.debug(`trying deezer for: ${trackName} - ${artistName}`);
-                        const response = await downloadTrack({
-                            service: "deezer",
-                            query,
-                            track_name: trackName,
-                            artist_name: displayArtist,
-                            album_name: albumName,
-                            album_artist: displayAlbumArtist,
-                            release_date: finalReleaseDate || releaseDate,
-                            cover_url: coverUrl,
-                            output_dir: outputDir,
-                            filename_format: settings.filenameTemplate,
-                            track_number: settings.trackNumber,
-                            position: trackNumberForTemplate,
-                            use_album_track_number: useAlbumTrackNumber,
-                            spotify_id: spotifyId,
-                            embed_lyrics: settings.embedLyrics,
-                            embed_max_quality_cover: settings.embedMaxQualityCover,
-                            duration: durationSeconds,
-                            item_id: itemID,
-                            audio_format: "flac",
-                            spotify_track_number: spotifyTrackNumber,
-                            spotify_disc_number: spotifyDiscNumber,
-                            spotify_total_tracks: spotifyTotalTracks,
-                            spotify_total_discs: spotifyTotalDiscs,
-                            copyright: copyright,
-                            publisher: publisher,
-                            use_first_artist_only: settings.useFirstArtistOnly,
-                            use_single_genre: settings.useSingleGenre,
-                            embed_genre: settings.embedGenre,
-                        });
-                        if (response.success) {
-                            logger.success(`deezer: ${trackName} - ${artistName}`);
-                            return response;
-                        }
-                        const errMsg = response.error || response.message || "Failed";
-                        fallbackErrors.push(`[Deezer] ${errMsg}`);
-                        lastResponse = response;
-                        logger.warning(`deezer failed, trying next...`);
-                    }
-                    catch (err) {
-                        logger.error(`deezer error: ${err}`);
-                        fallbackErrors.push(`[Deezer] ${String(err)}`);
-                        lastResponse = { success: false, error: String(err) };
-                    }
-                }
-            }
-            if (!lastResponse.success && itemID) {
-                const { MarkDownloadItemFailed } = await import("../../wailsjs/go/main/App");
-                const finalError = fallbackErrors.length > 0 ? fallbackErrors.join(" | ") : (lastResponse.error || "All services failed");
-                await MarkDownloadItemFailed(itemID, finalError);
-            }
-            return lastResponse;
-        }
-        const durationSecondsForFallback = durationMs ? Math.round(durationMs / 1000) : undefined;
         let audioFormat: string | undefined;
         if (service === "tidal") {
             audioFormat = settings.tidalQuality || "LOSSLESS";
@@ -699,41 +491,57 @@ export function useDownload(region: string) {
         else if (service === "deezer") {
             audioFormat = "flac";
         }
-        const singleServiceResponse = await downloadTrack({
-            service: service as "tidal" | "qobuz" | "amazon" | "deezer",
+        return {
+            service,
             query,
             track_name: trackName,
             artist_name: displayArtist,
             album_name: albumName,
             album_artist: displayAlbumArtist,
-            release_date: finalReleaseDate || releaseDate,
-            cover_url: coverUrl,
+            release_date: finalReleaseDate || track.release_date,
+            cover_url: track.images,
             output_dir: outputDir,
             filename_format: settings.filenameTemplate,
             track_number: settings.trackNumber,
             position: trackNumberForTemplate,
-            use_album_track_number: useAlbumTrackNumber,
+            use_album_track_number: false,
             spotify_id: spotifyId,
             embed_lyrics: settings.embedLyrics,
             embed_max_quality_cover: settings.embedMaxQualityCover,
-            duration: durationSecondsForFallback,
+            duration: track.duration_ms ? Math.round(track.duration_ms / 1000) : undefined,
             item_id: itemID,
             audio_format: audioFormat,
-            spotify_track_number: spotifyTrackNumber,
-            spotify_disc_number: spotifyDiscNumber,
-            spotify_total_tracks: spotifyTotalTracks,
-            spotify_total_discs: spotifyTotalDiscs,
-            copyright: copyright,
-            publisher: publisher,
+            spotify_track_number: track.track_number,
+            spotify_disc_number: track.disc_number,
+            spotify_total_tracks: track.total_tracks,
+            spotify_total_discs: track.total_discs,
+            copyright: track.copyright,
+            publisher: track.publisher,
+            allow_fallback: settings.allowFallback,
             use_first_artist_only: settings.useFirstArtistOnly,
             use_single_genre: settings.useSingleGenre,
             embed_genre: settings.embedGenre,
-        });
-        if (!singleServiceResponse.success && itemID) {
-            const { MarkDownloadItemFailed } = await import("../../wailsjs/go/main/App");
-            await MarkDownloadItemFailed(itemID, singleServiceResponse.error || "Download failed");
-        }
-        return singleServiceResponse;
+        };
+    };
+    // Batches go through the backend download scheduler, which runs them
+    // with the configured concurrency and retry policy. onResult is called
+    // as each track finishes, in completion order.
+    const downloadBatch = async (settings: any, tracks: TrackMetadata[], itemIDs: string[], positions: number[], onResult: (track: TrackMetadata, response: DownloadResponse) => void, folderName?: string, isAlbum?: boolean) => {
+        const requests: DownloadRequest[] = [];
+        for (let i = 0; i < tracks.length; i++) {
+            requests.push(await buildBatchRequest(settings, itemIDs[i], tracks[i], folderName, positions[i], isAlbum));
+        }
+        const queuedIDs = await enqueueDownloads(requests);
+        await Promise.all(queuedIDs.map(async (itemID, i) => {
+            let response: DownloadResponse;
+            try {
+                response = await waitForDownload(itemID);
+            }
+            catch (err) {
+                response = { success: false, message: "", error: err instanceof Error ? err.message : String(err) };
+            }
+            onResult(tracks[i], response);
+        }));
     };
     const handleDownloadTrack = async (id: string, trackName?: string, artistName?: string, albumName?: string, spotifyId?: string, playlistName?: string, durationMs?: number, position?: number, albumArtist?: string, releaseDate?: string, coverUrl?: string, spotifyTrackNumber?: number, spotifyDiscNumber?: number, spotifyTotalTracks?: number, spotifyTotalDiscs?: number, copyright?: string, publisher?: string) => {
         if (!id) {
@@ -854,21 +662,12 @@ export function useDownload(region: string) {
         let skippedCount = existingSpotifyIDs.size;
         const total = selectedTracks.length;
         setDownloadProgress(Math.round((skippedCount / total) * 100));
-        for (let i = 0; i < tracksToDownload.length; i++) {
-            if (shouldStopDownloadRef.current) {
-                toast.info(`Download stopped. ${successCount} tracks downloaded, ${tracksToDownload.length - i} remaining.`);
-                break;
-            }
-            const track = tracksToDownload[i];
-            const id = track.spotify_id || "";
-            const originalIndex = selectedTracks.indexOf(id);
-            const itemID = itemIDs[originalIndex];
-            setDownloadingTrack(id);
-            const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
-            setCurrentDownloadInfo({ name: track.name, artists: displayArtist || "" });
-            try {
-                const releaseYear = track.release_date?.substring(0, 4);
-                const response = await downloadWithItemID(settings, itemID, track.name, track.artists, track.album_name, folderName, originalIndex + 1, track.spotify_id, track.duration_ms, isAlbum, releaseYear, track.album_artist || "", track.release_date, track.images, track.track_number, track.disc_number, track.total_tracks, track.total_discs, track.copyright, track.publisher);
+        const positions = tracksToDownload.map((track) => selectedTracks.indexOf(track.spotify_id || "") + 1);
+        const pendingIDs = positions.map((position) => itemIDs[position - 1]);
+        try {
+            await downloadBatch(settings, tracksToDownload, pendingIDs, positions, (track, response) => {
+                const id = track.spotify_id || "";
+                const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
                 if (response.success) {
                     if (response.already_exists) {
                         skippedCount++;
@@ -881,7 +680,6 @@ export function useDownload(region: string) {
                     }
                     if (response.file) {
                         finalFilePaths.set(id, response.file);
-                        finalFilePaths.set(track.spotify_id || id, response.file);
                     }
                     setDownloadedTracks((prev) => new Set(prev).add(id));
                     setFailedTracks((prev) => {
@@ -895,26 +693,22 @@ export function useDownload(region: string) {
                     logger.error(`failed: ${track.name} - ${displayArtist}`);
                     setFailedTracks((prev) => new Set(prev).add(id));
                 }
-            }
-            catch (err) {
-                errorCount++;
-                logger.error(`error: ${track.name} - ${err}`);
-                setFailedTracks((prev) => new Set(prev).add(id));
-                if (itemID) {
-                    const { MarkDownloadItemFailed } = await import("../../wailsjs/go/main/App");
-                    await MarkDownloadItemFailed(itemID, err instanceof Error ? err.message : String(err));
-                }
-            }
-            const completedCount = skippedCount + successCount + errorCount;
-            setDownloadProgress(Math.min(100, Math.round((completedCount / total) * 100)));
+                const completedCount = skippedCount + successCount + errorCount;
+                setDownloadProgress(Math.min(100, Math.round((completedCount / total) * 100)));
+            }, folderName, isAlbum);
+        }
+        catch (err) {
+            logger.error(`batch download failed: ${err}`);
+            toast.error(err instanceof Error ? err.message : "Download failed");
+        }
+        if (shouldStopDownloadRef.current) {
+            toast.info(`Download stopped. ${successCount} tracks downloaded.`);
         }
         setDownloadingTrack(null);
         setCurrentDownloadInfo(null);
         setIsDownloading(false);
         setBulkDownloadType(null);
         shouldStopDownloadRef.current = false;
-        const { CancelAllQueuedItems } = await import("../../wailsjs/go/main/App");
-        await CancelAllQueuedItems();
         if (settings.createM3u8File && folderName) {
             const paths = selectedTrackObjects.map((t) => finalFilePaths.get(t.spotify_id || "") || "").filter((p) => p !== "");
             if (paths.length > 0) {
@@ -1024,21 +818,13 @@ export function useDownload(region: string) {
         let skippedCount = existingSpotifyIDs.size;
         const total = tracksWithId.length;
         setDownloadProgress(Math.round((skippedCount / total) * 100));
-        for (let i = 0; i < tracksToDownload.length; i++) {
-            if (shouldStopDownloadRef.current) {
-                toast.info(`Download stopped. ${successCount} tracks downloaded, ${tracksToDownload.length - i} remaining.`);
-                break;
-            }
-            const track = tracksToDownload[i];
-            const originalIndex = tracksWithId.findIndex((t) => t.spotify_id === track.spotify_id);
-            const itemID = itemIDs[originalIndex];
-            const trackId = track.spotify_id || "";
-            setDownloadingTrack(trackId);
-            const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
-            setCurrentDownloadInfo({ name: track.name || "", artists: displayArtist || "" });
-            try {
-                const releaseYear = track.release_date?.substring(0, 4);
-                const response = await downloadWithItemID(settings, itemID, track.name, track.artists, track.album_name, folderName, originalIndex + 1, track.spotify_id, track.duration_ms, isAlbum, releaseYear, track.album_artist || "", track.release_date, track.images, track.track_number, track.disc_number, track.total_tracks, track.total_discs, track.copyright, track.publisher);
+        const positions = tracksToDownload.map((track) => tracksWithId.findIndex((t) => t.spotify_id === track.spotify_id) + 1);
+        const pendingIDs = positions.map((position) => itemIDs[position - 1]);
+        try {
+            await downloadBatch(settings, tracksToDownload, pendingIDs, positions, (track, response) => {
+                const trackId = track.spotify_id || "";
+                const originalIndex = tracksWithId.findIndex((t) => t.spotify_id === track.spotify_id);
+                const displayArtist = settings.useFirstArtistOnly && track.artists ? getFirstArtist(track.artists) : track.artists;
                 if (response.success) {
                     if (response.already_exists) {
                         skippedCount++;
@@ -1064,24 +850,22 @@ export function useDownload(region: string) {
                     logger.error(`failed: ${track.name} - ${displayArtist}`);
                     setFailedTracks((prev) => new Set(prev).add(trackId));
                 }
-            }
-            catch (err) {
-                errorCount++;
-                logger.error(`error: ${track.name} - ${err}`);
-                setFailedTracks((prev) => new Set(prev).add(trackId));
-                const { MarkDownloadItemFailed } = await import("../../wailsjs/go/main/App");
-                await MarkDownloadItemFailed(itemID, err instanceof Error ? err.message : String(err));
-            }
-            const completedCount = skippedCount + successCount + errorCount;
-            setDownloadProgress(Math.min(100, Math.round((completedCount / total) * 100)));
+                const completedCount = skippedCount + successCount + errorCount;
+                setDownloadProgress(Math.min(100, Math.round((completedCount / total) * 100)));
+            }, folderName, isAlbum);
+        }
+        catch (err) {
+            logger.error(`batch download failed: ${err}`);
+            toast.error(err instanceof Error ? err.message : "Download failed");
+        }
+        if (shouldStopDownloadRef.current) {
+            toast.info(`Download stopped. ${successCount} tracks downloaded.`);
         }
         setDownloadingTrack(null);
         setCurrentDownloadInfo(null);
         setIsDownloading(false);
         setBulkDownloadType(null);
         shouldStopDownloadRef.current = false;
-        const { CancelAllQueuedItems: CancelQueued } = await import("../../wailsjs/go/main/App");
-        await CancelQueued();
         if (settings.createM3u8File && folderName) {
             try {
                 logger.info(`creating m3u8 playlist: ${folderName}`);
@@ -1113,10 +897,12 @@ export function useDownload(region: string) {
             toast.warning(parts.join(", "));
         }
     };
-    const handleStopDownload = () => {
+    const handleStopDownload = async () => {
         logger.info("download stopped by user");
         shouldStopDownloadRef.current = true;
         toast.info("Stopping download...");
+        const { CancelAllQueuedItems } = await import("../../wailsjs/go/main/App");
+        await CancelAllQueuedItems();
     };
     const resetDownloadedTracks = () => {
         setDownloadedTracks(new Set());
@@ -1,5 +1,6 @@
-import { useState, useEffect, useRef } from "react";
+import { useState, useEffect } from "react";
 import { GetDownloadProgress } from "../../wailsjs/go/main/App";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
 export interface DownloadProgressInfo {
     is_downloading: boolean;
     mb_downloaded: number;
@@ -11,24 +12,21 @@ export function useDownloadProgress() {
         mb_downloaded: 0,
         speed_mbps: 0,
     });
-    const intervalRef = useRef<number | null>(null);
     useEffect(() => {
-        const pollProgress = async () => {
-            try {
-                const progressInfo = await GetDownloadProgress();
-                setProgress(progressInfo);
-            }
-            catch (error) {
-                console.error("Failed to get download progress:", error);
-            }
-        };
-        intervalRef.current = window.setInterval(pollProgress, 200);
-        pollProgress();
-        return () => {
-            if (intervalRef.current) {
-                clearInterval(intervalRef.current);
-            }
-        };
+        GetDownloadProgress()
+            .then(setProgress)
+            .catch(error => console.error("Failed to get download progress:", error));
+        return EventsOn("queue:summary", (summary: {
+            is_downloading: boolean;
+            mb_downloaded: number;
+            current_speed: number;
+        }) => {
+            setProgress({
+                is_downloading: summary.is_downloading,
+                mb_downloaded: summary.mb_downloaded,
+                speed_mbps: summary.current_speed,
+            });
+        });
     }, []);
     return progress;
 }
@@ -1,6 +1,44 @@
 import { useEffect, useState } from "react";
 import { GetDownloadQueue } from "../../wailsjs/go/main/App";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
 import { backend } from "../../wailsjs/go/models";
+interface QueueItemProgress {
+    id: string;
+    progress: number;
+    speed: number;
+    total_size: number;
+    percent: number;
+    eta_seconds: number;
+}
+interface QueueSummary {
+    is_downloading: boolean;
+    mb_downloaded: number;
+    current_speed: number;
+    total_downloaded: number;
+    session_start_time: number;
+    queued_count: number;
+    completed_count: number;
+    failed_count: number;
+    skipped_count: number;
+    cancelled_count: number;
+    session_eta_seconds: number;
+}
+function upsertItems(queue: backend.DownloadItem[], items: backend.DownloadItem[]): backend.DownloadItem[] {
+    const next = queue.slice();
+    const index = new Map<string, number>();
+    next.forEach((item, i) => index.set(item.id, i));
+    for (const item of items) {
+        const i = index.get(item.id);
+        if (i === undefined) {
+            index.set(item.id, next.length);
+            next.push(item);
+        }
+        else {
+            next[i] = item;
+        }
+    }
+    return next;
+}
 export function useDownloadQueueData() {
     const [queueInfo, setQueueInfo] = useState<backend.DownloadQueueInfo>(new backend.DownloadQueueInfo({
         is_downloading: false,
@@ -12,6 +50,8 @@ export function useDownloadQueueData() {
         completed_count: 0,
         failed_count: 0,
         skipped_count: 0,
+        cancelled_count: 0,
+        session_eta_seconds: 0,
     }));
     useEffect(() => {
         const fetchQueue = async () => {
@@ -23,9 +63,47 @@ export function useDownloadQueueData() {
                 console.error("Failed to get download queue:", error);
             }
         };
+        const applyItems = (items: backend.DownloadItem[]) => {
+            setQueueInfo(prev => new backend.DownloadQueueInfo({ ...prev, queue: upsertItems(prev.queue, items) }));
+        };
+        const applyItem = (item: backend.DownloadItem) => applyItems([item]);
         fetchQueue();
-        const interval = setInterval(fetchQueue, 200);
-        return () => clearInterval(interval);
+        const unsubscribers = [
+            EventsOn("queue:items-added", applyItems),
+            EventsOn("queue:item-started", applyItem),
+            EventsOn("queue:item-completed", applyItem),
+            EventsOn("queue:item-failed", applyItem),
+            EventsOn("queue:item-skipped", applyItem),
+            EventsOn("queue:item-cancelled", applyItem),
+            EventsOn("queue:item-retrying", applyItem),
+            EventsOn("queue:progress", (updates: QueueItemProgress[]) => {
+                setQueueInfo(prev => {
+                    const byID = new Map(updates.map(u => [u.id, u]));
+                    const queue = prev.queue.map(item => {
+                        const update = byID.get(item.id);
+                        return update ? { ...item, progress: update.progress, speed: update.speed, total_size: update.total_size, percent: update.percent, eta_seconds: update.eta_seconds } as backend.DownloadItem : item;
+                    });
+                    return new backend.DownloadQueueInfo({ ...prev, queue });
+                });
+            }),
+            EventsOn("queue:summary", (summary: QueueSummary) => {
+                setQueueInfo(prev => new backend.DownloadQueueInfo({
+                    ...prev,
+                    is_downloading: summary.is_downloading,
+                    current_speed: summary.current_speed,
+                    total_downloaded: summary.total_downloaded,
+                    session_start_time: summary.session_start_time,
+                    queued_count: summary.queued_count,
+                    completed_count: summary.completed_count,
+                    failed_count: summary.failed_count,
+                    skipped_count: summary.skipped_count,
+                    cancelled_count: summary.cancelled_count,
+                    session_eta_seconds: summary.session_eta_seconds,
+                }));
+            }),
+            EventsOn("queue:refresh", fetchQueue),
+        ];
+        return () => unsubscribers.forEach(off => off());
     }, []);
     return queueInfo;
 }
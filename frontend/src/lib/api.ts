@@ -1,5 +1,5 @@
 import type { SpotifyMetadataResponse, DownloadRequest, DownloadResponse, HealthResponse, LyricsDownloadRequest, LyricsDownloadResponse, CoverDownloadRequest, CoverDownloadResponse, HeaderDownloadRequest, HeaderDownloadResponse, GalleryImageDownloadRequest, GalleryImageDownloadResponse, AvatarDownloadRequest, AvatarDownloadResponse, } from "@/types/api";
-import { GetSpotifyMetadata, DownloadTrack, DownloadLyrics, DownloadCover, DownloadHeader, DownloadGalleryImage, DownloadAvatar } from "../../wailsjs/go/main/App";
+import { GetSpotifyMetadata, DownloadTrack, EnqueueDownloads, WaitForDownload, DownloadLyrics, DownloadCover, DownloadHeader, DownloadGalleryImage, DownloadAvatar } from "../../wailsjs/go/main/App";
 import { main } from "../../wailsjs/go/models";
 export async function fetchSpotifyMetadata(url: string, batch: boolean = true, delay: number = 1.0, timeout: number = 300.0): Promise<SpotifyMetadataResponse> {
     const req = new main.SpotifyMetadataRequest({
@@ -11,12 +11,21 @@ export async function fetchSpotifyMetadata(url: string, batch: boolean = true, d
     const jsonString = await GetSpotifyMetadata(req);
     return JSON.parse(jsonString);
 }
-export async function downloadTrack(request: DownloadRequest): Promise<DownloadResponse> {
+function toBackendRequest(request: DownloadRequest): main.DownloadRequest {
     const req = new main.DownloadRequest(request);
     if (request.use_single_genre !== undefined) {
         (req as any).use_single_genre = request.use_single_genre;
     }
-    return await DownloadTrack(req);
+    return req;
+}
+export async function downloadTrack(request: DownloadRequest): Promise<DownloadResponse> {
+    return await DownloadTrack(toBackendRequest(request));
+}
+export async function enqueueDownloads(requests: DownloadRequest[]): Promise<string[]> {
+    return await EnqueueDownloads(requests.map(toBackendRequest));
+}
+export async function waitForDownload(itemID: string): Promise<DownloadResponse> {
+    return await WaitForDownload(itemID);
 }
 export async function checkHealth(): Promise<HealthResponse> {
     return {
@@ -3,8 +3,9 @@ export type FontFamily = "google-sans" | "inter" | "poppins" | "roboto" | "dm-sa
 export type FolderPreset = "none" | "artist" | "album" | "year-album" | "year-artist-album" | "artist-album" | "artist-year-album" | "artist-year-nested-album" | "album-artist" | "album-artist-album" | "album-artist-year-album" | "album-artist-year-nested-album" | "year" | "year-artist" | "custom";
 export type FilenamePreset = "title" | "title-artist" | "artist-title" | "track-title" | "track-title-artist" | "track-artist-title" | "title-album-artist" | "track-title-album-artist" | "artist-album-title" | "track-dash-title" | "disc-track-title" | "disc-track-title-artist" | "custom";
 export interface Settings {
+    version?: number;
     downloadPath: string;
-    downloader: "auto" | "tidal" | "qobuz" | "amazon" | "deezer";
+    downloader: "auto" | "best" | "tidal" | "qobuz" | "amazon" | "deezer";
     theme: string;
     themeMode: "auto" | "light" | "dark";
     fontFamily: FontFamily;
@@ -25,6 +26,17 @@ export interface Settings {
     amazonQuality: "original";
     autoOrder: "tidal-qobuz-amazon-deezer" | "tidal-qobuz-deezer-amazon" | "tidal-amazon-qobuz-deezer" | "tidal-amazon-deezer-qobuz" | "tidal-deezer-qobuz-amazon" | "tidal-deezer-amazon-qobuz" | "qobuz-tidal-amazon-deezer" | "qobuz-tidal-deezer-amazon" | "qobuz-amazon-tidal-deezer" | "qobuz-amazon-deezer-tidal" | "qobuz-deezer-tidal-amazon" | "qobuz-deezer-amazon-tidal" | "amazon-tidal-qobuz-deezer" | "amazon-tidal-deezer-qobuz" | "amazon-qobuz-tidal-deezer" | "amazon-qobuz-deezer-tidal" | "amazon-deezer-tidal-qobuz" | "amazon-deezer-qobuz-tidal" | "deezer-tidal-qobuz-amazon" | "deezer-tidal-amazon-qobuz" | "deezer-qobuz-tidal-amazon" | "deezer-qobuz-amazon-tidal" | "deezer-amazon-tidal-qobuz" | "deezer-amazon-qobuz-tidal" | string;
     autoQuality: "16" | "24";
+    fallbackOrder?: string[];
+    verifyDownloads?: boolean;
+    playlistSyncRemoved?: "archive" | "delete";
+    playlistSyncArchiveDir?: string;
+    artistWatchIntervalHours?: number;
+    bandwidthLimitMBps?: number;
+    retryMaxAttempts?: number;
+    retryBaseDelaySeconds?: number;
+    retryErrorClasses?: ("rate_limit" | "server" | "timeout" | "network")[];
+    maxConcurrentDownloads?: number;
+    serviceConcurrency?: Record<string, number>;
     allowFallback: boolean;
     useSpotFetchAPI: boolean;
     spotFetchAPIUrl: string;
@@ -33,6 +45,7 @@ export interface Settings {
     useFirstArtistOnly: boolean;
     useSingleGenre: boolean;
     embedGenre: boolean;
+    embedSourceTags?: boolean;
 }
 export const FOLDER_PRESETS: Record<FolderPreset, {
     label: string;
@@ -116,7 +129,8 @@ export const DEFAULT_SETTINGS: Settings = {
     createM3u8File: false,
     useFirstArtistOnly: false,
     useSingleGenre: false,
-    embedGenre: true
+    embedGenre: true,
+    embedSourceTags: false
 };
 export const FONT_OPTIONS: {
     value: FontFamily;
@@ -240,80 +254,9 @@ export async function loadSettings(): Promise<Settings> {
     try {
         const backendSettings = await LoadSettings();
         if (backendSettings) {
+            // The backend migrates older config files and fills in defaults.
             const parsed = backendSettings as any;
-            if ('darkMode' in parsed && !('themeMode' in parsed)) {
-                parsed.themeMode = parsed.darkMode ? 'dark' : 'light';
-                delete parsed.darkMode;
-            }
-            if (!('folderPreset' in parsed) && ('artistSubfolder' in parsed || 'albumSubfolder' in parsed)) {
-                const hasArtist = parsed.artistSubfolder;
-                const hasAlbum = parsed.albumSubfolder;
-                if (hasArtist && hasAlbum) {
-                    parsed.folderPreset = "artist-album";
-                    parsed.folderTemplate = "{artist}/{album}";
-                }
-                else if (hasArtist) {
-                    parsed.folderPreset = "artist";
-                    parsed.folderTemplate = "{artist}";
-                }
-                else if (hasAlbum) {
-                    parsed.folderPreset = "album";
-                    parsed.folderTemplate = "{album}";
-                }
-                else {
-                    parsed.folderPreset = "none";
-                    parsed.folderTemplate = "";
-                }
-            }
-            if (!('filenamePreset' in parsed) && 'filenameFormat' in parsed) {
-                const format = parsed.filenameFormat;
-                if (format === "title-artist") {
-                    parsed.filenamePreset = "artist-title";
-                    parsed.filenameTemplate = "{artist} - {title}";
-                }
-                else if (format === "artist-title") {
-                    parsed.filenamePreset = "artist-title";
-                    parsed.filenameTemplate = "{artist} - {title}";
-                }
-                else {
-                    parsed.filenamePreset = "title";
-                    parsed.filenameTemplate = "{title}";
-                }
-            }
             parsed.operatingSystem = detectOS();
-            if (!('tidalQuality' in parsed)) {
-                parsed.tidalQuality = "LOSSLESS";
-            }
-            if (!('qobuzQuality' in parsed)) {
-                parsed.qobuzQuality = "6";
-            }
-            if (!('amazonQuality' in parsed)) {
-                parsed.amazonQuality = "original";
-            }
-            if (!('autoOrder' in parsed)) {
-                parsed.autoOrder = "tidal-qobuz-amazon";
-            }
-            if (!('autoQuality' in parsed)) {
-                parsed.autoQuality = "16";
-            }
-            if (!('allowFallback' in parsed)) {
-                parsed.allowFallback = true;
-            }
-            if (!('createPlaylistFolder' in parsed)) {
-                parsed.createPlaylistFolder = true;
-            }
-            if (!('createM3u8File' in parsed)) {
-                parsed.createM3u8File = false;
-            }
-            if (!('useFirstArtistOnly' in parsed)) {
-                parsed.useFirstArtistOnly = false;
-            }
-            if (!('useSingleGenre' in parsed)) {
-                parsed.useSingleGenre = false;
-            }
-            if (!('embedGenre' in parsed)) {
-                parsed.embedGenre = true;
-            }
             cachedSettings = { ...DEFAULT_SETTINGS, ...parsed };
             return cachedSettings!;
         }
@@ -367,14 +310,15 @@ export async function getSettingsWithDefaults(): Promise<Settings> {
 }
 export async function saveSettings(settings: Settings): Promise<void> {
     try {
-        cachedSettings = settings;
-        localStorage.setItem(SETTINGS_KEY, JSON.stringify(settings));
         await SaveToBackend(settings as any);
-        window.dispatchEvent(new CustomEvent('settingsUpdated', { detail: settings }));
     }
     catch (error) {
         console.error("Failed to save settings:", error);
+        throw error;
     }
+    cachedSettings = settings;
+    localStorage.setItem(SETTINGS_KEY, JSON.stringify(settings));
+    window.dispatchEvent(new CustomEvent('settingsUpdated', { detail: settings }));
 }
 export async function updateSettings(partial: Partial<Settings>): Promise<Settings> {
     const current = getSettings();
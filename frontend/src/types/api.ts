@@ -108,7 +108,7 @@ export interface ArtistResponse {
 }
 export type SpotifyMetadataResponse = TrackResponse | AlbumResponse | PlaylistResponse | ArtistDiscographyResponse | ArtistResponse;
 export interface DownloadRequest {
-    service: "tidal" | "qobuz" | "amazon" | "deezer";
+    service: "auto" | "best" | "tidal" | "qobuz" | "amazon" | "deezer";
     query?: string;
     track_name?: string;
     artist_name?: string;
@@ -137,6 +137,8 @@ export interface DownloadRequest {
     copyright?: string;
     publisher?: string;
     spotify_url?: string;
+    allow_fallback?: boolean;
+    isrc?: string;
     use_first_artist_only?: boolean;
     use_single_genre?: boolean;
     embed_genre?: boolean;
@@ -148,6 +150,8 @@ export interface DownloadResponse {
     error?: string;
     already_exists?: boolean;
     item_id?: string;
+    service?: string;
+    selection_reason?: string;
 }
 export interface HealthResponse {
     status: string;
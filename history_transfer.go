@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+func (a *App) ExportHistory() (string, error) {
+	export, err := backend.ExportHistory(a.settings().DownloadPath, "SpotiFLAC")
+	if err != nil {
+		return "", fmt.Errorf("failed to read history: %w", err)
+	}
+	if len(export.Downloads) == 0 && len(export.Fetches) == 0 {
+		return "No history to export.", nil
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: fmt.Sprintf("SpotiFLAC_%s_History.json", time.Now().Format("20060102_150405")),
+		Title:           "Export History",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "JSON History (*.json)",
+				Pattern:     "*.json",
+			},
+			{
+				DisplayName: "CSV History (*.csv)",
+				Pattern:     "*.csv",
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open save dialog: %v", err)
+	}
+	if path == "" {
+		return "Export cancelled", nil
+	}
+
+	if err := backend.WriteHistoryExport(path, export); err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+	return fmt.Sprintf("Exported %d downloads and %d fetches to %s", len(export.Downloads), len(export.Fetches), path), nil
+}
+
+func (a *App) SelectHistoryFile() (string, error) {
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import History",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "History Files (*.json, *.csv)",
+				Pattern:     "*.json;*.csv",
+			},
+		},
+	})
+}
+
+func (a *App) ImportHistory(path string) (backend.HistoryImportResult, error) {
+	if path == "" {
+		return backend.HistoryImportResult{}, fmt.Errorf("no file selected")
+	}
+
+	export, err := backend.ReadHistoryExport(path)
+	if err != nil {
+		return backend.HistoryImportResult{}, err
+	}
+
+	result, err := backend.ImportHistory(export, a.settings().DownloadPath, "SpotiFLAC")
+	if err != nil {
+		return result, err
+	}
+	fmt.Printf("Imported %d downloads and %d fetches from %s (%d already present)\n", result.Downloads, result.Fetches, path, result.Skipped)
+	return result, nil
+}
+
+func (a *App) RelinkHistory(libraryRoot string) (backend.HistoryRelinkResult, error) {
+	if libraryRoot == "" {
+		libraryRoot = a.settings().DownloadPath
+	}
+	return backend.RelinkHistory(libraryRoot, "SpotiFLAC")
+}
@@ -4,6 +4,7 @@ import (
 	"embed"
 	"encoding/json"
 	"log"
+	"os"
 
 	"github.com/afkarxyz/SpotiFLAC/backend"
 
@@ -31,6 +32,10 @@ func main() {
 		backend.AppVersion = config.Info.ProductVersion
 	}
 
+	if isCLIInvocation(os.Args[1:]) {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	app := NewApp()
 
 	err := wails.Run(&options.App{
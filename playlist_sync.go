@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+type PlaylistSyncResult struct {
+	PlaylistName string   `json:"playlist_name"`
+	Dir          string   `json:"dir"`
+	Total        int      `json:"total"`
+	Added        []string `json:"added"`
+	Unchanged    int      `json:"unchanged"`
+	Removed      []string `json:"removed"`
+	Failed       []string `json:"failed"`
+	RemovedMode  string   `json:"removed_mode"`
+	M3U8Path     string   `json:"m3u8_path,omitempty"`
+}
+
+func (a *App) SyncPlaylist(url string, dir string) (PlaylistSyncResult, error) {
+	if url == "" {
+		return PlaylistSyncResult{}, fmt.Errorf("playlist URL is required")
+	}
+	if dir == "" {
+		return PlaylistSyncResult{}, fmt.Errorf("sync folder is required")
+	}
+	dir = backend.SanitizeFolderPath(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	data, err := backend.GetFilteredSpotifyData(ctx, url, false, 0)
+	cancel()
+	if err != nil {
+		return PlaylistSyncResult{}, fmt.Errorf("failed to fetch playlist: %w", err)
+	}
+
+	playlist, ok := data.(backend.PlaylistResponsePayload)
+	if !ok {
+		return PlaylistSyncResult{}, fmt.Errorf("URL is not a Spotify playlist: %s", url)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return PlaylistSyncResult{}, fmt.Errorf("failed to create sync folder: %w", err)
+	}
+
+	manifest, err := backend.LoadPlaylistManifest(dir)
+	if err != nil {
+		return PlaylistSyncResult{}, err
+	}
+
+	settings := a.settings()
+	removedMode := settings.PlaylistSyncRemoved
+	archiveDir := settings.PlaylistSyncArchiveDir
+
+	options := AlbumDownloadOptions{}
+	applyAlbumDefaults(&options, settings)
+
+	playlistName := playlist.PlaylistInfo.Owner.Name
+	result := PlaylistSyncResult{
+		PlaylistName: playlistName,
+		Dir:          dir,
+		Total:        len(playlist.TrackList),
+		RemovedMode:  removedMode,
+	}
+
+	existing := make(map[string]backend.PlaylistManifestTrack)
+	for _, entry := range manifest.Tracks {
+		existing[entry.SpotifyID] = entry
+	}
+
+	fmt.Printf("[Sync] %s: %d tracks, %d in manifest\n", playlistName, len(playlist.TrackList), len(manifest.Tracks))
+
+	var synced []backend.PlaylistManifestTrack
+	current := make(map[string]string)
+	var order []string
+	var requests []DownloadRequest
+	var pending []backend.AlbumTrackMetadata
+
+	for i, t := range playlist.TrackList {
+		if t.SpotifyID == "" {
+			continue
+		}
+		order = append(order, t.SpotifyID)
+		if _, seen := current[t.SpotifyID]; seen {
+			continue
+		}
+		current[t.SpotifyID] = ""
+
+		if entry, ok := existing[t.SpotifyID]; ok {
+			if rel, inside := backend.PlaylistRelPath(dir, entry.File); inside {
+				if _, err := os.Stat(filepath.Join(dir, rel)); err == nil {
+					entry.File = rel
+					synced = append(synced, entry)
+					current[t.SpotifyID] = filepath.Join(dir, rel)
+					result.Unchanged++
+					continue
+				}
+			}
+		}
+
+		artist := t.Artists
+		albumArtist := t.AlbumArtist
+		if settings.UseFirstArtistOnly {
+			artist = backend.GetFirstArtist(artist)
+			albumArtist = backend.GetFirstArtist(albumArtist)
+		}
+
+		pending = append(pending, t)
+		requests = append(requests, DownloadRequest{
+			Service:              options.Service,
+			TrackName:            t.Name,
+			ArtistName:           artist,
+			AlbumName:            t.AlbumName,
+			AlbumArtist:          albumArtist,
+			ReleaseDate:          t.ReleaseDate,
+			CoverURL:             t.Images,
+			OutputDir:            dir,
+			AudioFormat:          options.AudioFormat,
+			FilenameFormat:       options.FilenameFormat,
+			TrackNumber:          settings.TrackNumber,
+			Position:             i + 1,
+			SpotifyID:            t.SpotifyID,
+			EmbedLyrics:          settings.EmbedLyrics,
+			EmbedMaxQualityCover: settings.EmbedMaxQualityCover,
+			Duration:             t.DurationMS / 1000,
+			SpotifyTrackNumber:   t.TrackNumber,
+			SpotifyDiscNumber:    t.DiscNumber,
+			SpotifyTotalTracks:   t.TotalTracks,
+			SpotifyTotalDiscs:    t.TotalDiscs,
+			PlaylistOwner:        playlist.PlaylistInfo.Owner.DisplayName,
+			AllowFallback:        settings.AllowFallback,
+			UseFirstArtistOnly:   settings.UseFirstArtistOnly,
+			UseSingleGenre:       settings.UseSingleGenre,
+			EmbedGenre:           settings.EmbedGenre,
+		})
+	}
+
+	if len(requests) > 0 {
+		fmt.Printf("[Sync] Queueing %d new tracks\n", len(requests))
+		responses := make([]DownloadResponse, len(requests))
+		err := a.downloadAndWait(requests, func(i int, resp DownloadResponse) {
+			responses[i] = resp
+		})
+		if err != nil {
+			return result, err
+		}
+
+		for i, resp := range responses {
+			t, req := pending[i], requests[i]
+			if !resp.Success || resp.File == "" {
+				fmt.Printf("✗ %s - %s: %s\n", t.Name, req.ArtistName, resp.Error)
+				result.Failed = append(result.Failed, fmt.Sprintf("%s - %s", t.Name, req.ArtistName))
+				continue
+			}
+
+			// The library index may find the track elsewhere in the download
+			// folder; the sync folder gets its own copy so that removing it
+			// later never touches the library.
+			file := resp.File
+			relPath, inside := backend.PlaylistRelPath(dir, file)
+			if !inside {
+				imported, err := backend.ImportPlaylistFile(dir, file)
+				if err != nil {
+					fmt.Printf("✗ %s - %s: %v\n", t.Name, req.ArtistName, err)
+					result.Failed = append(result.Failed, fmt.Sprintf("%s - %s", t.Name, req.ArtistName))
+					continue
+				}
+				file = imported
+				relPath = filepath.Base(imported)
+			}
+			synced = append(synced, backend.PlaylistManifestTrack{
+				SpotifyID: t.SpotifyID,
+				Name:      t.Name,
+				Artists:   req.ArtistName,
+				File:      relPath,
+				AddedAt:   time.Now().Unix(),
+			})
+			current[t.SpotifyID] = file
+			result.Added = append(result.Added, fmt.Sprintf("%s - %s", t.Name, req.ArtistName))
+		}
+	}
+
+	var playlistPaths []string
+	for _, id := range order {
+		if path := current[id]; path != "" {
+			playlistPaths = append(playlistPaths, path)
+		}
+	}
+
+	for _, entry := range manifest.Tracks {
+		if _, ok := current[entry.SpotifyID]; ok {
+			continue
+		}
+
+		rel, inside := backend.PlaylistRelPath(dir, entry.File)
+		if !inside {
+			fmt.Printf("⚠ Not removing %s: it is outside the sync folder\n", entry.File)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, rel)); err != nil {
+			continue
+		}
+
+		if removedMode == "delete" {
+			if err := backend.RemovePlaylistFile(dir, rel); err != nil {
+				fmt.Printf("⚠ Failed to delete %s: %v\n", entry.File, err)
+				synced = append(synced, entry)
+				continue
+			}
+			fmt.Printf("Deleted removed track: %s\n", entry.File)
+		} else {
+			archived, err := backend.ArchivePlaylistFile(dir, rel, archiveDir)
+			if err != nil {
+				fmt.Printf("⚠ Failed to archive %s: %v\n", entry.File, err)
+				synced = append(synced, entry)
+				continue
+			}
+			fmt.Printf("Archived removed track: %s\n", archived)
+		}
+		result.Removed = append(result.Removed, fmt.Sprintf("%s - %s", entry.Name, entry.Artists))
+	}
+
+	manifest.PlaylistURL = url
+	manifest.PlaylistName = playlistName
+	manifest.Tracks = synced
+	if err := backend.SavePlaylistManifest(dir, manifest); err != nil {
+		return result, err
+	}
+
+	m3u8Name := playlistName
+	if m3u8Name == "" {
+		m3u8Name = "playlist"
+	}
+	if err := a.CreateM3U8File(m3u8Name, dir, playlistPaths); err != nil {
+		fmt.Printf("⚠ Failed to write m3u8: %v\n", err)
+	} else if len(playlistPaths) > 0 {
+		result.M3U8Path = filepath.Join(dir, backend.SanitizeFilename(m3u8Name)+".m3u8")
+	}
+
+	fmt.Printf("\n[Sync] Added: %d, Unchanged: %d, Removed: %d, Failed: %d\n", len(result.Added), result.Unchanged, len(result.Removed), len(result.Failed))
+	return result, nil
+}
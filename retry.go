@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/afkarxyz/SpotiFLAC/backend"
+)
+
+const maxRetryDelay = 30 * time.Minute
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	Classes     map[string]bool
+}
+
+func retryPolicyFromSettings(settings backend.Settings) RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts: settings.RetryMaxAttempts,
+		BaseDelay:   time.Duration(settings.RetryBaseDelaySeconds * float64(time.Second)),
+		Classes:     make(map[string]bool, len(settings.RetryErrorClasses)),
+	}
+	for _, class := range settings.RetryErrorClasses {
+		policy.Classes[class] = true
+	}
+	return policy
+}
+
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(retry-1)))
+	if d > maxRetryDelay || d <= 0 {
+		d = maxRetryDelay
+	}
+	return d
+}
+
+// scheduleRetry only retries transient failures. An item that failed
+// verification was downloaded in full and the source is likely to send the
+// same file again, so it waits for RetryFailedDownloads instead.
+func (a *App) scheduleRetry(job backend.DownloadJob, err error) {
+	item, ok := backend.GetDownloadItem(job.ItemID)
+	if !ok || item.Status != backend.StatusFailed {
+		return
+	}
+
+	// Partial files are only worth keeping for an automatic retry.
+	retrying := false
+	defer func() {
+		if !retrying {
+			backend.RemovePartFiles(job.ItemID)
+		}
+	}()
+
+	policy := retryPolicyFromSettings(a.settings())
+	if item.RetryCount >= policy.MaxAttempts {
+		return
+	}
+
+	class := backend.ClassifyDownloadError(err)
+	if class == "" || !policy.Classes[class] {
+		return
+	}
+
+	wait := policy.delay(item.RetryCount + 1)
+	item, ok = backend.ScheduleDownloadRetry(job.ItemID, time.Now().Add(wait).Unix())
+	if !ok {
+		return
+	}
+	retrying = true
+	fmt.Printf("⚠ %s - %s failed (%s), retry %d/%d in %v\n", item.TrackName, item.ArtistName, class, item.RetryCount, policy.MaxAttempts, wait)
+
+	time.AfterFunc(wait, func() {
+		if a.scheduler == nil || !backend.RequeueRetryingItem(job.ItemID) {
+			return
+		}
+		a.scheduler.Enqueue(job)
+	})
+}
+
+// RetryFailedDownloads requeues failed and verification-failed items from the
+// persisted queue. DownloadTrack persists every request, so this covers
+// single tracks as well as album downloads and playlist syncs.
+func (a *App) RetryFailedDownloads() (int, error) {
+	if a.scheduler == nil {
+		return 0, fmt.Errorf("download scheduler is not running")
+	}
+
+	entries, err := backend.GetPersistedDownloads()
+	if err != nil {
+		return 0, err
+	}
+
+	a.scheduler.SetLimits(downloadConcurrencyLimits(a.settings()))
+
+	var jobs []backend.DownloadJob
+	for _, entry := range entries {
+		item, ok := backend.GetDownloadItem(entry.Item.ID)
+		if !ok {
+			item = entry.Item
+		}
+		if item.Status != backend.StatusFailed && item.Status != backend.StatusVerificationFailed {
+			continue
+		}
+		if len(entry.Request) == 0 {
+			continue
+		}
+
+		item.RetryCount = 0
+		item.NextRetryAt = 0
+		backend.RestoreDownloadItem(item)
+		jobs = append(jobs, backend.DownloadJob{
+			ItemID:   item.ID,
+			Provider: entry.Provider,
+			Request:  entry.Request,
+		})
+	}
+
+	a.scheduler.Enqueue(jobs...)
+	fmt.Printf("Retrying %d failed downloads\n", len(jobs))
+	return len(jobs), nil
+}